@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"todo-list-app/internal/database"
+	"todo-list-app/internal/store"
+)
+
+// runInitDB implements "init-db": apply all migrations, optionally seed, and exit
+func runInitDB(args []string) error {
+	fs := flag.NewFlagSet("init-db", flag.ExitOnError)
+	dbURL := fs.String("db", databaseURL(), "database URL or SQLite file path (env DATABASE_URL or DB_PATH)")
+	seed := fs.Bool("seed", false, "insert development test data after migrating")
+	fs.Parse(args)
+
+	db, err := database.InitDB(*dbURL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if *seed {
+		if err := store.NewSQLStore(db).InsertTestData(); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Database ready at %s", displayURL(*dbURL))
+	return nil
+}
+
+// runSeed implements "seed": insert development test data into a migrated database
+func runSeed(args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	dbURL := fs.String("db", databaseURL(), "database URL or SQLite file path (env DATABASE_URL or DB_PATH)")
+	fs.Parse(args)
+
+	db, err := database.InitDB(*dbURL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return store.NewSQLStore(db).InsertTestData()
+}
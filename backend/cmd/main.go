@@ -1,103 +1,110 @@
 package main
 
 import (
+	"fmt"
 	"log"
-	"net/http"
+	"net/url"
 	"os"
-
-	"github.com/gorilla/mux"
-	"todo-list-app/internal/database"
-	"todo-list-app/internal/handlers"
-	"todo-list-app/internal/middleware"
+	"sort"
+	"strings"
+	"time"
 )
 
-func main() {
-	// Initialize session store
-	secretKey := os.Getenv("SESSION_SECRET")
-	if secretKey == "" {
-		secretKey = "your-super-secret-key-change-this-in-production"
-		log.Println("Warning: Using default session secret. Set SESSION_SECRET environment variable in production.")
-	}
-	middleware.InitSessionStore(secretKey)
+// command is a subcommand of the server binary
+type command struct {
+	summary string
+	run     func(args []string) error
+}
 
-	// Initialize database
-	dbPath := os.Getenv("DB_PATH")
-	if dbPath == "" {
-		dbPath = "./data/todo.db"
-	}
-	
-	db, err := database.InitDB(dbPath)
-	if err != nil {
-		log.Fatal("Failed to initialize database:", err)
-	}
-	defer db.Close()
+var commands = map[string]command{
+	"serve":          {"run the HTTP API server (default)", runServe},
+	"init-db":        {"create or upgrade the database schema and exit", runInitDB},
+	"seed":           {"insert development test data", runSeed},
+	"migrate":        {"manage schema migrations: status|up|down|to N", runMigrate},
+	"create-user":    {"create a user account", runCreateUser},
+	"reset-password": {"set a new password for an existing user", runResetPassword},
+}
 
-	// Seed test data in development
-	if os.Getenv("SEED_DATA") == "true" {
-		if err := database.InsertTestData(db); err != nil {
-			log.Printf("Warning: Failed to insert test data: %v", err)
+func main() {
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 {
+		switch {
+		case args[0] == "--init-db" || args[0] == "-init-db":
+			// Flag form kept for older docker-compose files
+			name, args = "init-db", args[1:]
+		case args[0] == "help" || args[0] == "-h" || args[0] == "--help":
+			usage()
+			return
+		case !strings.HasPrefix(args[0], "-"):
+			name, args = args[0], args[1:]
 		}
 	}
 
-	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(db)
-	todoHandler := handlers.NewTodoHandler(db)
-
-	// Setup routes
-	r := mux.NewRouter()
-
-	// Global CORS handler for all OPTIONS requests
-	r.Methods("OPTIONS").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		origin := r.Header.Get("Origin")
-		if origin == "http://localhost:5173" || origin == "http://localhost:5174" || origin == "http://localhost:5175" {
-			w.Header().Set("Access-Control-Allow-Origin", origin)
-		}
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, PATCH, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		w.Header().Set("Access-Control-Allow-Credentials", "true")
-		w.WriteHeader(http.StatusOK)
-	})
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		usage()
+		os.Exit(2)
+	}
 
-	// Apply CORS middleware to all routes
-	r.Use(middleware.CORSMiddleware)
+	if err := cmd.run(args); err != nil {
+		log.Fatalf("%s: %v", name, err)
+	}
+}
 
-	// Health check endpoint
-	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status": "healthy"}`))
-	}).Methods("GET")
+// usage prints the list of available commands
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s <command> [flags]\n\ncommands:\n", os.Args[0])
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-15s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun '%s <command> -h' for command flags.\n", os.Args[0])
+}
 
-	// API routes
-	api := r.PathPrefix("/api").Subrouter()
-	
-	// Auth routes (public)
-	api.HandleFunc("/auth/register", authHandler.Register).Methods("POST")
-	api.HandleFunc("/auth/login", authHandler.Login).Methods("POST")
-	api.HandleFunc("/auth/logout", authHandler.Logout).Methods("POST")
+// databaseURL returns the database to use from DATABASE_URL, falling back
+// to the SQLite file named by DB_PATH
+func databaseURL() string {
+	if databaseURL := os.Getenv("DATABASE_URL"); databaseURL != "" {
+		return databaseURL
+	}
+	dbPath := os.Getenv("DB_PATH")
+	if dbPath == "" {
+		dbPath = "./data/todo.db"
+	}
+	return dbPath
+}
 
-	// Protected Todo routes
-	protected := api.PathPrefix("/todos").Subrouter()
-	protected.Use(middleware.AuthMiddleware)
-	protected.HandleFunc("", todoHandler.GetTodos).Methods("GET")
-	protected.HandleFunc("", todoHandler.CreateTodo).Methods("POST")
-	protected.HandleFunc("/{id}", todoHandler.UpdateTodo).Methods("PUT")
-	protected.HandleFunc("/{id}", todoHandler.DeleteTodo).Methods("DELETE")
-	protected.HandleFunc("/{id}/toggle", todoHandler.ToggleTodo).Methods("PATCH")
+// displayURL hides any password in a database URL so it can be logged
+func displayURL(databaseURL string) string {
+	if u, err := url.Parse(databaseURL); err == nil && u.User != nil {
+		return u.Redacted()
+	}
+	return databaseURL
+}
 
-	// Determine port
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+// attachmentsDir returns the directory for attachment files from
+// ATTACHMENTS_DIR, defaulting to the data directory next to the database
+func attachmentsDir() string {
+	if dir := os.Getenv("ATTACHMENTS_DIR"); dir != "" {
+		return dir
 	}
+	return "./data/attachments"
+}
 
-	log.Printf("Server starting on port %s", port)
-	log.Printf("Database location: %s", dbPath)
-	log.Printf("Health check: http://localhost:%s/health", port)
-	log.Printf("API base URL: http://localhost:%s/api", port)
-	log.Printf("CORS enabled for: http://localhost:5173")
-	
-	if err := http.ListenAndServe(":"+port, r); err != nil {
-		log.Fatal("Server failed to start:", err)
+// trashRetention returns how long deleted todos are kept from
+// TRASH_RETENTION, a Go duration such as "720h", defaulting to 30 days
+func trashRetention() time.Duration {
+	if value := os.Getenv("TRASH_RETENTION"); value != "" {
+		retention, err := time.ParseDuration(value)
+		if err == nil {
+			return retention
+		}
+		log.Printf("Warning: Ignoring invalid TRASH_RETENTION %q: %v", value, err)
 	}
-}
\ No newline at end of file
+	return 30 * 24 * time.Hour
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strconv"
+
+	"todo-list-app/internal/database"
+)
+
+// runMigrate implements "migrate status|up|down|to N"
+func runMigrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dbURL := fs.String("db", databaseURL(), "database URL or SQLite file path (env DATABASE_URL or DB_PATH)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: migrate [-db path] status|up|down|to N")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	args = fs.Args()
+
+	if len(args) == 0 {
+		fs.Usage()
+		return fmt.Errorf("missing migrate command")
+	}
+
+	db, err := database.Open(*dbURL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if status.Modified {
+				state += " (modified since applied)"
+			}
+			if status.Unknown {
+				state += " (unknown to this binary)"
+			}
+			fmt.Printf("%04d  %-30s %s\n", status.Version, status.Name, state)
+		}
+		return nil
+
+	case "up":
+		applied, err := migrator.Up()
+		if err != nil {
+			return err
+		}
+		log.Printf("Applied %d migration(s)", applied)
+		return nil
+
+	case "down":
+		return migrator.Down()
+
+	case "to":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: migrate to N")
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil || version < 0 {
+			return fmt.Errorf("invalid migration version: %s", args[1])
+		}
+		changed, err := migrator.To(version)
+		if err != nil {
+			return err
+		}
+		log.Printf("Migrated to version %d (%d step(s))", version, changed)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown migrate command %q; expected status, up, down or to N", args[0])
+	}
+}
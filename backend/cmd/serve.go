@@ -0,0 +1,226 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gorilla/mux"
+	"todo-list-app/internal/blob"
+	"todo-list-app/internal/database"
+	"todo-list-app/internal/handlers"
+	"todo-list-app/internal/middleware"
+	"todo-list-app/internal/store"
+)
+
+// runServe implements "serve", the default command
+func runServe(args []string) error {
+	defaultPort := os.Getenv("PORT")
+	if defaultPort == "" {
+		defaultPort = "8080"
+	}
+
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	dbURL := fs.String("db", databaseURL(), "database URL or SQLite file path (env DATABASE_URL or DB_PATH)")
+	port := fs.String("port", defaultPort, "HTTP port to listen on (env PORT)")
+	seed := fs.Bool("seed", false, "insert development test data before serving")
+	attachmentsDir := fs.String("attachments", attachmentsDir(), "directory for attachment files (env ATTACHMENTS_DIR)")
+	trashRetention := fs.Duration("trash-retention", trashRetention(), "how long deleted todos stay in the trash, 0 to keep them (env TRASH_RETENTION)")
+	fs.Parse(args)
+
+	// Initialize database
+	db, err := database.InitDB(*dbURL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	s := store.NewSQLStore(db)
+
+	// Attachment content lives next to the database by default
+	blobs, err := blob.NewFileStore(*attachmentsDir)
+	if err != nil {
+		return err
+	}
+	s.UseBlobStore(blobs)
+
+	// Initialize session store
+	secretKey := os.Getenv("SESSION_SECRET")
+	if secretKey == "" {
+		secretKey = "your-super-secret-key-change-this-in-production"
+		log.Println("Warning: Using default session secret. Set SESSION_SECRET environment variable in production.")
+	}
+	middleware.InitSessionStore(secretKey, s)
+
+	// Seed test data in development
+	if *seed {
+		if err := s.InsertTestData(); err != nil {
+			log.Printf("Warning: Failed to insert test data: %v", err)
+		}
+	}
+
+	if *trashRetention > 0 {
+		go purgeTrash(s, *trashRetention)
+	}
+
+	r := newRouter(s)
+
+	log.Printf("Server starting on port %s", *port)
+	log.Printf("Database location: %s", displayURL(*dbURL))
+	log.Printf("Health check: http://localhost:%s/health", *port)
+	log.Printf("API base URL: http://localhost:%s/api", *port)
+	log.Printf("CORS enabled for: http://localhost:5173")
+
+	return http.ListenAndServe(":"+*port, r)
+}
+
+// newRouter wires the HTTP routes to their handlers
+func newRouter(s store.Store) *mux.Router {
+	// Initialize handlers
+	authHandler := handlers.NewAuthHandler(s, s)
+	todoHandler := handlers.NewTodoHandler(s, s, s, s, s, s)
+	tagHandler := handlers.NewTagHandler(s)
+	projectHandler := handlers.NewProjectHandler(s, s)
+	tokenHandler := handlers.NewTokenHandler(s)
+
+	// Setup routes
+	r := mux.NewRouter()
+
+	// Global CORS handler for all OPTIONS requests
+	r.Methods("OPTIONS").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "http://localhost:5173" || origin == "http://localhost:5174" || origin == "http://localhost:5175" {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, PATCH, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, If-Match, If-None-Match, Idempotency-Key")
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.WriteHeader(http.StatusOK)
+	})
+
+	// Apply CORS middleware to all routes
+	r.Use(middleware.CORSMiddleware)
+
+	// Health check endpoint
+	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status": "healthy"}`))
+	}).Methods("GET")
+
+	// API routes
+	api := r.PathPrefix("/api").Subrouter()
+
+	// Auth routes (public)
+	api.HandleFunc("/auth/register", authHandler.Register).Methods("POST")
+	api.HandleFunc("/auth/login", authHandler.Login).Methods("POST")
+	api.HandleFunc("/auth/logout", authHandler.Logout).Methods("POST")
+
+	// Session and password management; these need a session, not a token
+	account := api.PathPrefix("/auth").Subrouter()
+	account.Use(middleware.AuthMiddleware)
+	account.HandleFunc("/sessions", authHandler.GetSessions).Methods("GET")
+	account.HandleFunc("/sessions", authHandler.DeleteSessions).Methods("DELETE")
+	account.HandleFunc("/sessions/{id}", authHandler.DeleteSession).Methods("DELETE")
+	account.HandleFunc("/password", authHandler.ChangePassword).Methods("PUT")
+
+	// Protected Todo routes
+	protected := api.PathPrefix("/todos").Subrouter()
+	protected.Use(middleware.RequireAuth(s, "todos"), middleware.Idempotency(s))
+	protected.HandleFunc("", todoHandler.GetTodos).Methods("GET")
+	protected.HandleFunc("", todoHandler.CreateTodo).Methods("POST")
+	protected.HandleFunc("/search", todoHandler.SearchTodos).Methods("GET")
+	protected.HandleFunc("/bulk", todoHandler.BulkTodos).Methods("POST")
+	protected.HandleFunc("/{id}", todoHandler.GetTodo).Methods("GET")
+	protected.HandleFunc("/{id}", todoHandler.UpdateTodo).Methods("PUT")
+	protected.HandleFunc("/{id}", todoHandler.PatchTodo).Methods("PATCH")
+	protected.HandleFunc("/{id}", todoHandler.DeleteTodo).Methods("DELETE")
+	protected.HandleFunc("/{id}/toggle", todoHandler.ToggleTodo).Methods("PATCH")
+	protected.HandleFunc("/{id}/restore", todoHandler.RestoreTodo).Methods("POST")
+	protected.HandleFunc("/{id}/history", todoHandler.GetHistory).Methods("GET")
+	protected.HandleFunc("/{id}/revert", todoHandler.RevertTodo).Methods("POST")
+	protected.HandleFunc("/{id}/subtasks", todoHandler.GetSubtasks).Methods("GET")
+	protected.HandleFunc("/{id}/occurrences", todoHandler.GetOccurrences).Methods("GET")
+	protected.HandleFunc("/{id}/comments", todoHandler.GetComments).Methods("GET")
+	protected.HandleFunc("/{id}/comments", todoHandler.CreateComment).Methods("POST")
+	protected.HandleFunc("/{id}/comments/{commentID}", todoHandler.UpdateComment).Methods("PUT")
+	protected.HandleFunc("/{id}/comments/{commentID}", todoHandler.DeleteComment).Methods("DELETE")
+	protected.HandleFunc("/{id}/attachments", todoHandler.GetAttachments).Methods("GET")
+	protected.HandleFunc("/{id}/attachments", todoHandler.UploadAttachment).Methods("POST")
+	protected.HandleFunc("/{id}/attachments/{attachmentID}", todoHandler.DownloadAttachment).Methods("GET")
+	protected.HandleFunc("/{id}/attachments/{attachmentID}", todoHandler.DeleteAttachment).Methods("DELETE")
+
+	// Trash routes
+	trash := api.PathPrefix("/trash").Subrouter()
+	trash.Use(middleware.RequireAuth(s, "todos"), middleware.Idempotency(s))
+	trash.HandleFunc("", todoHandler.GetTrash).Methods("GET")
+	trash.HandleFunc("", todoHandler.EmptyTrash).Methods("DELETE")
+
+	// Change feed and push for clients that keep todos offline
+	sync := api.PathPrefix("/sync").Subrouter()
+	sync.Use(middleware.RequireAuth(s, "todos"), middleware.Idempotency(s))
+	sync.HandleFunc("", todoHandler.GetChanges).Methods("GET")
+	sync.HandleFunc("/push", todoHandler.PushChanges).Methods("POST")
+
+	// Protected Tag routes
+	tags := api.PathPrefix("/tags").Subrouter()
+	tags.Use(middleware.RequireAuth(s, "tags"), middleware.Idempotency(s))
+	tags.HandleFunc("", tagHandler.GetTags).Methods("GET")
+	tags.HandleFunc("", tagHandler.CreateTag).Methods("POST")
+	tags.HandleFunc("/{id}", tagHandler.UpdateTag).Methods("PUT")
+	tags.HandleFunc("/{id}", tagHandler.DeleteTag).Methods("DELETE")
+	tags.HandleFunc("/{id}/merge", tagHandler.MergeTag).Methods("POST")
+
+	// A project's todos are todos, so listing them takes the todos scope.
+	// This has to come before the project routes to take precedence.
+	projectTodos := api.PathPrefix("/projects/{id}/todos").Subrouter()
+	projectTodos.Use(middleware.RequireAuth(s, "todos"))
+	projectTodos.HandleFunc("", todoHandler.GetProjectTodos).Methods("GET")
+
+	// Protected Project routes
+	projects := api.PathPrefix("/projects").Subrouter()
+	projects.Use(middleware.RequireAuth(s, "projects"), middleware.Idempotency(s))
+	projects.HandleFunc("", projectHandler.GetProjects).Methods("GET")
+	projects.HandleFunc("", projectHandler.CreateProject).Methods("POST")
+	projects.HandleFunc("/{id}", projectHandler.UpdateProject).Methods("PUT")
+	projects.HandleFunc("/{id}", projectHandler.DeleteProject).Methods("DELETE")
+	projects.HandleFunc("/{id}/members", projectHandler.GetMembers).Methods("GET")
+	projects.HandleFunc("/{id}/members/{userID}", projectHandler.UpdateMember).Methods("PUT")
+	projects.HandleFunc("/{id}/members/{userID}", projectHandler.DeleteMember).Methods("DELETE")
+	projects.HandleFunc("/{id}/invitations", projectHandler.GetProjectInvitations).Methods("GET")
+	projects.HandleFunc("/{id}/invitations", projectHandler.CreateInvitation).Methods("POST")
+	projects.HandleFunc("/{id}/invitations/{invitationID}", projectHandler.DeleteProjectInvitation).Methods("DELETE")
+
+	// Invitations to other users' projects
+	invitations := api.PathPrefix("/invitations").Subrouter()
+	invitations.Use(middleware.RequireAuth(s, "projects"), middleware.Idempotency(s))
+	invitations.HandleFunc("", projectHandler.GetInvitations).Methods("GET")
+	invitations.HandleFunc("/{id}/accept", projectHandler.AcceptInvitation).Methods("POST")
+	invitations.HandleFunc("/{id}", projectHandler.DeclineInvitation).Methods("DELETE")
+
+	// Personal access token routes; these need a session, not a token. Like
+	// the account routes they take no Idempotency-Key, since a stored
+	// response would keep the new token's secret.
+	tokens := api.PathPrefix("/tokens").Subrouter()
+	tokens.Use(middleware.AuthMiddleware)
+	tokens.HandleFunc("", tokenHandler.GetTokens).Methods("GET")
+	tokens.HandleFunc("", tokenHandler.CreateToken).Methods("POST")
+	tokens.HandleFunc("/{id}", tokenHandler.DeleteToken).Methods("DELETE")
+
+	return r
+}
+
+// purgeTrash permanently deletes todos that have been in the trash longer
+// than retention, checking right away and then periodically
+func purgeTrash(todos store.TodoStore, retention time.Duration) {
+	interval := max(min(retention/10, time.Hour), time.Second)
+	for {
+		if purged, err := todos.PurgeTrash(time.Now().Add(-retention)); err != nil {
+			log.Printf("Warning: Failed to purge trash: %v", err)
+		} else if purged > 0 {
+			log.Printf("Purged %d todo(s) from the trash", purged)
+		}
+		time.Sleep(interval)
+	}
+}
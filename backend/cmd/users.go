@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	"todo-list-app/internal/database"
+	"todo-list-app/internal/handlers"
+	"todo-list-app/internal/store"
+)
+
+// runCreateUser implements "create-user"
+func runCreateUser(args []string) error {
+	fs := flag.NewFlagSet("create-user", flag.ExitOnError)
+	dbURL := fs.String("db", databaseURL(), "database URL or SQLite file path (env DATABASE_URL or DB_PATH)")
+	email := fs.String("email", "", "email address of the new user (required)")
+	password := fs.String("password", "", "password; read from stdin when omitted")
+	fs.Parse(args)
+
+	if err := handlers.ValidateEmail(*email); err != nil {
+		return err
+	}
+
+	hash, err := readPasswordHash(*password)
+	if err != nil {
+		return err
+	}
+
+	db, err := database.InitDB(*dbURL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	user, err := store.NewSQLStore(db).CreateUser(*email, hash)
+	if err == store.ErrConflict {
+		return fmt.Errorf("user %s already exists", *email)
+	} else if err != nil {
+		return err
+	}
+
+	log.Printf("Created user %s (id %d)", user.Email, user.ID)
+	return nil
+}
+
+// runResetPassword implements "reset-password"
+func runResetPassword(args []string) error {
+	fs := flag.NewFlagSet("reset-password", flag.ExitOnError)
+	dbURL := fs.String("db", databaseURL(), "database URL or SQLite file path (env DATABASE_URL or DB_PATH)")
+	email := fs.String("email", "", "email address of the user (required)")
+	password := fs.String("password", "", "new password; read from stdin when omitted")
+	fs.Parse(args)
+
+	if *email == "" {
+		return fmt.Errorf("-email is required")
+	}
+
+	hash, err := readPasswordHash(*password)
+	if err != nil {
+		return err
+	}
+
+	db, err := database.InitDB(*dbURL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	users := store.NewSQLStore(db)
+	user, err := users.GetUserByEmail(*email)
+	if err == store.ErrNotFound {
+		return fmt.Errorf("user %s not found", *email)
+	} else if err != nil {
+		return fmt.Errorf("failed to look up user: %w", err)
+	}
+
+	if err := users.UpdatePassword(user.ID, hash); err != nil {
+		return err
+	}
+
+	log.Printf("Password reset for %s; all of their sessions have been signed out", *email)
+	return nil
+}
+
+// readPasswordHash validates and hashes a password, reading it from the
+// first line of stdin when it was not passed as a flag
+func readPasswordHash(password string) (string, error) {
+	if password == "" {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("no password given; pass -password or pipe it on stdin")
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+
+	if err := handlers.ValidatePassword(password); err != nil {
+		return "", err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
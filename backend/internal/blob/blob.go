@@ -0,0 +1,36 @@
+// Package blob stores the content of file attachments. Blobs are content
+// addressed: a blob's key is the hex SHA-256 of its bytes, so storing the
+// same content twice keeps a single copy.
+package blob
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned when no blob has the requested key
+var ErrNotFound = errors.New("blob not found")
+
+// Store is a place to keep blobs
+type Store interface {
+	// Put reads r to the end and stores its content, returning the key and
+	// size. Storing content that is already present is a no-op. If reading
+	// r fails nothing is stored and the read error is returned.
+	Put(r io.Reader) (key string, size int64, err error)
+	// Open returns the content of a blob
+	Open(key string) (io.ReadSeekCloser, error)
+	// Delete removes a blob; deleting a missing blob is not an error
+	Delete(key string) error
+}
+
+// validKey reports whether key looks like a hex SHA-256, which keeps keys
+// from naming anything outside the store
+func validKey(key string) bool {
+	if len(key) != hex.EncodedLen(sha256.Size) {
+		return false
+	}
+	_, err := hex.DecodeString(key)
+	return err == nil
+}
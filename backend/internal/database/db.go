@@ -5,133 +5,68 @@ import (
 	"fmt"
 	"log"
 
+	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 )
 
-// InitDB initializes the SQLite database and creates tables
-func InitDB(dataSourceName string) (*sql.DB, error) {
-	db, err := sql.Open("sqlite3", dataSourceName)
+// Open opens the database named by a database URL (see ParseURL) without
+// changing its schema
+func Open(databaseURL string) (*DB, error) {
+	dialect, dataSourceName, err := ParseURL(databaseURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
-	}
-
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
-	}
-
-	// Create tables
-	if err := createTables(db); err != nil {
-		return nil, fmt.Errorf("failed to create tables: %w", err)
-	}
-
-	log.Println("Database initialized successfully")
-	return db, nil
-}
-
-// createTables creates the necessary database tables
-func createTables(db *sql.DB) error {
-	// Users table
-	usersTable := `
-	CREATE TABLE IF NOT EXISTS users (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		email TEXT UNIQUE NOT NULL,
-		password_hash TEXT NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);`
-
-	if _, err := db.Exec(usersTable); err != nil {
-		return fmt.Errorf("failed to create users table: %w", err)
-	}
-
-	// Todos table
-	todosTable := `
-	CREATE TABLE IF NOT EXISTS todos (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		user_id INTEGER NOT NULL,
-		title TEXT NOT NULL,
-		description TEXT,
-		completed BOOLEAN DEFAULT FALSE,
-		priority INTEGER DEFAULT 1,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
-	);`
-
-	if _, err := db.Exec(todosTable); err != nil {
-		return fmt.Errorf("failed to create todos table: %w", err)
+		return nil, err
 	}
 
-	// Create indices for better performance
-	indices := []string{
-		"CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);",
-		"CREATE INDEX IF NOT EXISTS idx_todos_user_id ON todos(user_id);",
-		"CREATE INDEX IF NOT EXISTS idx_todos_created_at ON todos(created_at);",
+	sqlDB, err := sql.Open(dialect.driverName(), dataSourceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	for _, index := range indices {
-		if _, err := db.Exec(index); err != nil {
-			return fmt.Errorf("failed to create index: %w", err)
-		}
+	if err := sqlDB.Ping(); err != nil {
+		sqlDB.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	log.Println("Database tables and indices created successfully")
-	return nil
+	return &DB{DB: sqlDB, Dialect: dialect}, nil
 }
 
-// InsertTestData inserts initial test data for development
-func InsertTestData(db *sql.DB) error {
-	// Check if test data already exists
-	var count int
-	err := db.QueryRow("SELECT COUNT(*) FROM users WHERE email = 'test@example.com'").Scan(&count)
+// InitDB opens the database and brings its schema up to date
+func InitDB(databaseURL string) (*DB, error) {
+	db, err := Open(databaseURL)
 	if err != nil {
-		return fmt.Errorf("failed to check test data: %w", err)
+		return nil, err
 	}
 
-	if count > 0 {
-		log.Println("Test data already exists, skipping insertion")
-		return nil
-	}
-
-	// Insert test user (password: "password123" hashed with bcrypt)
-	testUserQuery := `
-	INSERT INTO users (email, password_hash) 
-	VALUES ('test@example.com', '$2a$10$570Q1w5Z9hL.WUb3Ch8Xwuycz0R9fFWXBVb9ebw.Os7FDTGmhtj1G');`
-
-	result, err := db.Exec(testUserQuery)
+	migrator, err := NewMigrator(db)
 	if err != nil {
-		return fmt.Errorf("failed to insert test user: %w", err)
+		db.Close()
+		return nil, err
 	}
 
-	userID, err := result.LastInsertId()
+	applied, err := migrator.Up()
 	if err != nil {
-		return fmt.Errorf("failed to get user ID: %w", err)
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
 	}
-
-	// Insert test todos
-	testTodos := []struct {
-		title       string
-		description string
-		priority    int
-		completed   bool
-	}{
-		{"Complete project setup", "Set up the initial project structure and configurations", 3, true},
-		{"Implement authentication", "Create user registration and login functionality", 3, false},
-		{"Build Todo CRUD", "Implement create, read, update, delete operations for todos", 2, false},
-		{"Design UI/UX", "Create responsive and intuitive user interface", 2, false},
-		{"Write tests", "Add unit and integration tests", 1, false},
+	if applied > 0 {
+		log.Printf("Applied %d migration(s)", applied)
 	}
 
-	for _, todo := range testTodos {
-		todoQuery := `
-		INSERT INTO todos (user_id, title, description, priority, completed) 
-		VALUES (?, ?, ?, ?, ?);`
+	log.Println("Database initialized successfully")
+	return db, nil
+}
 
-		_, err := db.Exec(todoQuery, userID, todo.title, todo.description, todo.priority, todo.completed)
-		if err != nil {
-			return fmt.Errorf("failed to insert test todo: %w", err)
-		}
+// HasTable reports whether a table (including virtual tables) exists
+func HasTable(db *DB, name string) (bool, error) {
+	query := "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?"
+	if db.Dialect == Postgres {
+		query = "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = ?"
 	}
 
-	log.Println("Test data inserted successfully")
-	return nil
-}
\ No newline at end of file
+	var count int
+	err := db.QueryRow(query, name).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
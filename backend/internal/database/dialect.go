@@ -0,0 +1,196 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Dialect names a supported database engine and papers over the SQL
+// differences between them
+type Dialect string
+
+const (
+	SQLite   Dialect = "sqlite"
+	Postgres Dialect = "postgres"
+)
+
+// ParseURL picks the dialect from a database URL and returns the data source
+// name to hand to its driver. postgres:// and postgresql:// URLs select
+// Postgres; sqlite: URLs and plain file paths select SQLite.
+func ParseURL(databaseURL string) (Dialect, string, error) {
+	scheme, rest, hasScheme := strings.Cut(databaseURL, ":")
+	if !hasScheme || strings.ContainsAny(scheme, "./\\") {
+		return SQLite, databaseURL, nil
+	}
+
+	switch strings.ToLower(scheme) {
+	case "postgres", "postgresql":
+		return Postgres, databaseURL, nil
+	case "sqlite", "sqlite3":
+		path := strings.TrimPrefix(rest, "//")
+		if path == "" {
+			return "", "", fmt.Errorf("database URL %q has no file path", databaseURL)
+		}
+		return SQLite, path, nil
+	case "file":
+		// go-sqlite3 understands file: URIs directly
+		return SQLite, databaseURL, nil
+	default:
+		return "", "", fmt.Errorf("unsupported database URL scheme %q (expected sqlite: or postgres://)", scheme)
+	}
+}
+
+// driverName returns the database/sql driver registered for the dialect
+func (d Dialect) driverName() string {
+	if d == Postgres {
+		return "postgres"
+	}
+	return "sqlite3"
+}
+
+// Rebind rewrites the ? placeholders used throughout the code base into the
+// dialect's own style. Question marks inside string literals are left alone.
+func (d Dialect) Rebind(query string) string {
+	if d != Postgres || !strings.Contains(query, "?") {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	inString := false
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			inString = !inString
+		case r == '?' && !inString:
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Fold wraps an expression so that comparisons and ordering on it ignore case
+func (d Dialect) Fold(expr string) string {
+	if d == Postgres {
+		return "LOWER(" + expr + ")"
+	}
+	return expr + " COLLATE NOCASE"
+}
+
+// Like returns the case-insensitive LIKE operator
+func (d Dialect) Like() string {
+	if d == Postgres {
+		return "ILIKE"
+	}
+	return "LIKE"
+}
+
+// Now returns the current UTC time as a timestamp without time zone
+func (d Dialect) Now() string {
+	if d == Postgres {
+		return "(CURRENT_TIMESTAMP AT TIME ZONE 'UTC')"
+	}
+	return "CURRENT_TIMESTAMP"
+}
+
+// ForUpdate returns the clause that locks the rows a SELECT reads until the
+// transaction ends. SQLite locks the whole database for a writing
+// transaction instead, so it has none.
+func (d Dialect) ForUpdate() string {
+	if d == Postgres {
+		return " FOR UPDATE"
+	}
+	return ""
+}
+
+// DB is a database handle that knows its dialect. Queries are written with
+// ? placeholders and rebound for the dialect before they are run.
+type DB struct {
+	*sql.DB
+	Dialect Dialect
+}
+
+// Exec runs a statement after rebinding its placeholders
+func (db *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return db.DB.Exec(db.Dialect.Rebind(query), args...)
+}
+
+// Query runs a query after rebinding its placeholders
+func (db *DB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return db.DB.Query(db.Dialect.Rebind(query), args...)
+}
+
+// QueryRow runs a single-row query after rebinding its placeholders
+func (db *DB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return db.DB.QueryRow(db.Dialect.Rebind(query), args...)
+}
+
+// Insert runs an INSERT statement and returns the id of the new row
+func (db *DB) Insert(query string, args ...interface{}) (int, error) {
+	return insert(db.Dialect, db.DB, query, args)
+}
+
+// Begin starts a transaction that rebinds placeholders like DB does
+func (db *DB) Begin() (*Tx, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx, Dialect: db.Dialect}, nil
+}
+
+// Tx is a transaction that knows its dialect
+type Tx struct {
+	*sql.Tx
+	Dialect Dialect
+}
+
+// Exec runs a statement after rebinding its placeholders
+func (tx *Tx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return tx.Tx.Exec(tx.Dialect.Rebind(query), args...)
+}
+
+// Query runs a query after rebinding its placeholders
+func (tx *Tx) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return tx.Tx.Query(tx.Dialect.Rebind(query), args...)
+}
+
+// QueryRow runs a single-row query after rebinding its placeholders
+func (tx *Tx) QueryRow(query string, args ...interface{}) *sql.Row {
+	return tx.Tx.QueryRow(tx.Dialect.Rebind(query), args...)
+}
+
+// Insert runs an INSERT statement and returns the id of the new row
+func (tx *Tx) Insert(query string, args ...interface{}) (int, error) {
+	return insert(tx.Dialect, tx.Tx, query, args)
+}
+
+// execQueryer is satisfied by both *sql.DB and *sql.Tx
+type execQueryer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// insert returns the new row's id with RETURNING on Postgres, whose driver
+// does not support LastInsertId, and with LastInsertId elsewhere
+func insert(d Dialect, q execQueryer, query string, args []interface{}) (int, error) {
+	query = d.Rebind(query)
+
+	if d == Postgres {
+		var id int
+		err := q.QueryRow(strings.TrimRight(strings.TrimSpace(query), ";")+" RETURNING id", args...).Scan(&id)
+		return id, err
+	}
+
+	result, err := q.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	return int(id), err
+}
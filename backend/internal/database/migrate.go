@@ -0,0 +1,380 @@
+package database
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Each dialect has its own migrations directory. Versions are kept in step
+// across dialects so a version number means the same schema everywhere.
+//
+//go:embed migrations/*/*.sql
+var migrationFiles embed.FS
+
+// migrationFileName matches files like 0002_todo_due_dates.up.sql
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is a single versioned schema change
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// MigrationStatus describes a migration and whether it has been applied
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+	// Modified is set when the applied checksum no longer matches the file
+	Modified bool
+	// Unknown is set for versions recorded in the database that this
+	// binary has no migration file for
+	Unknown bool
+}
+
+// Migrator applies and rolls back the embedded migrations
+type Migrator struct {
+	db         *DB
+	migrations []Migration
+}
+
+// appliedMigration is a row of the schema_migrations table
+type appliedMigration struct {
+	version   int
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// NewMigrator creates a migrator for the embedded migrations of the
+// database's dialect, creating the schema_migrations bookkeeping table if
+// needed
+func NewMigrator(db *DB) (*Migrator, error) {
+	migrations, err := loadMigrations(db.Dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	appliedAt := "DATETIME DEFAULT CURRENT_TIMESTAMP"
+	if db.Dialect == Postgres {
+		appliedAt = "TIMESTAMP(0) DEFAULT " + db.Dialect.Now()
+	}
+
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at ` + appliedAt + `
+	);`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// loadMigrations reads and pairs a dialect's embedded up/down files, ordered
+// by version
+func loadMigrations(dialect Dialect) ([]Migration, error) {
+	dir := "migrations/" + string(dialect)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file name: %s", entry.Name())
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		contents, err := migrationFiles.ReadFile(dir + "/" + entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(contents)
+			sum := sha256.Sum256(contents)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(contents)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d (%s) has no up step", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// applied returns the migrations recorded in schema_migrations, by version
+func (m *Migrator) applied() (map[int]appliedMigration, error) {
+	rows, err := m.db.Query("SELECT version, name, checksum, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]appliedMigration)
+	for rows.Next() {
+		var a appliedMigration
+		if err := rows.Scan(&a.version, &a.name, &a.checksum, &a.appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_migrations: %w", err)
+		}
+		applied[a.version] = a
+	}
+	return applied, rows.Err()
+}
+
+// verify checks that every applied migration still matches its embedded
+// file and that the database is not ahead of this binary
+func (m *Migrator) verify(applied map[int]appliedMigration) error {
+	known := make(map[int]Migration, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = migration
+	}
+
+	for version, a := range applied {
+		migration, ok := known[version]
+		if !ok {
+			return fmt.Errorf("database has migration %d (%s) applied, which this binary does not know about", version, a.name)
+		}
+		if migration.Checksum != a.checksum {
+			return fmt.Errorf("migration %d (%s) was modified after it was applied", version, migration.Name)
+		}
+	}
+	return nil
+}
+
+// Status lists every known migration and any unknown applied ones
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []MigrationStatus
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if a, ok := applied[migration.Version]; ok {
+			appliedAt := a.appliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+			status.Modified = a.checksum != migration.Checksum
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+
+	for _, a := range applied {
+		appliedAt := a.appliedAt
+		statuses = append(statuses, MigrationStatus{
+			Version:   a.version,
+			Name:      a.name,
+			Applied:   true,
+			AppliedAt: &appliedAt,
+			Unknown:   true,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses, nil
+}
+
+// Version returns the highest applied migration version, or 0 for none
+func (m *Migrator) Version() (int, error) {
+	var version int
+	err := m.db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
+
+// Latest returns the highest known migration version
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up applies all pending migrations and returns how many were applied
+func (m *Migrator) Up() (int, error) {
+	return m.migrate(m.Latest())
+}
+
+// Down rolls back the most recently applied migration
+func (m *Migrator) Down() error {
+	current, err := m.Version()
+	if err != nil {
+		return err
+	}
+	if current == 0 {
+		return fmt.Errorf("no migrations to roll back")
+	}
+
+	target := 0
+	for _, migration := range m.migrations {
+		if migration.Version < current {
+			target = migration.Version
+		}
+	}
+	_, err = m.migrate(target)
+	return err
+}
+
+// To migrates up or down until the given version is the latest applied one
+// and returns how many migrations were applied or rolled back
+func (m *Migrator) To(version int) (int, error) {
+	if version != 0 {
+		found := false
+		for _, migration := range m.migrations {
+			if migration.Version == version {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("unknown migration version %d", version)
+		}
+	}
+	return m.migrate(version)
+}
+
+// migrate moves the schema to the target version, one transaction per step
+func (m *Migrator) migrate(target int) (int, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return 0, err
+	}
+	if err := m.verify(applied); err != nil {
+		return 0, err
+	}
+
+	count := 0
+
+	// Apply pending migrations up to the target, in order
+	for _, migration := range m.migrations {
+		if migration.Version > target {
+			break
+		}
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		if err := m.apply(migration); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	// Roll back applied migrations above the target, newest first
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if migration.Version <= target {
+			break
+		}
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if err := m.rollback(migration); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// apply runs a migration's up step and records it in one transaction
+func (m *Migrator) apply(migration Migration) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin migration %d: %w", migration.Version, err)
+	}
+	defer tx.Rollback()
+
+	// Migration files are run verbatim, without placeholder rebinding
+	if _, err := tx.Tx.Exec(migration.Up); err != nil {
+		// The SQLite search index needs FTS5, which is behind a build tag
+		if strings.Contains(err.Error(), "no such module: fts5") {
+			err = fmt.Errorf("%w (build with -tags sqlite_fts5)", err)
+		}
+		return fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Name, err)
+	}
+
+	if _, err := tx.Exec(
+		"INSERT INTO schema_migrations (version, name, checksum) VALUES (?, ?, ?)",
+		migration.Version, migration.Name, migration.Checksum,
+	); err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %d: %w", migration.Version, err)
+	}
+
+	log.Printf("Applied migration %04d_%s", migration.Version, migration.Name)
+	return nil
+}
+
+// rollback runs a migration's down step and removes its record in one transaction
+func (m *Migrator) rollback(migration Migration) error {
+	if migration.Down == "" {
+		return fmt.Errorf("migration %d (%s) has no down step", migration.Version, migration.Name)
+	}
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin rollback of migration %d: %w", migration.Version, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Tx.Exec(migration.Down); err != nil {
+		return fmt.Errorf("rollback of migration %d (%s) failed: %w", migration.Version, migration.Name, err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM schema_migrations WHERE version = ?", migration.Version); err != nil {
+		return fmt.Errorf("failed to unrecord migration %d: %w", migration.Version, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit rollback of migration %d: %w", migration.Version, err)
+	}
+
+	log.Printf("Rolled back migration %04d_%s", migration.Version, migration.Name)
+	return nil
+}
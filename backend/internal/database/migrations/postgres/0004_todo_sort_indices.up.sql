@@ -0,0 +1,5 @@
+-- Keyset pagination indices for each GET /api/todos sort order
+CREATE INDEX IF NOT EXISTS idx_todos_user_created_at ON todos(user_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_todos_user_updated_at ON todos(user_id, updated_at, id);
+CREATE INDEX IF NOT EXISTS idx_todos_user_priority ON todos(user_id, priority, id);
+CREATE INDEX IF NOT EXISTS idx_todos_user_title ON todos(user_id, LOWER(title), id);
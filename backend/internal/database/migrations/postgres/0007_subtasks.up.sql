@@ -0,0 +1,5 @@
+-- Subtasks: a todo may belong to a parent todo of the same user. Deleting a
+-- parent deletes its subtasks.
+ALTER TABLE todos ADD COLUMN parent_id INTEGER REFERENCES todos(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_todos_parent_id ON todos(parent_id);
@@ -0,0 +1,3 @@
+-- Recurrence rule (RFC 5545 RRULE subset) of a repeating todo. Completing
+-- an occurrence moves the rule to the next occurrence it creates.
+ALTER TABLE todos ADD COLUMN recurrence TEXT;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_todos_assignee_id;
+ALTER TABLE todos DROP COLUMN assigned_at;
+ALTER TABLE todos DROP COLUMN assigned_by;
+ALTER TABLE todos DROP COLUMN assignee_id;
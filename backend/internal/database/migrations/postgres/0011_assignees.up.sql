@@ -0,0 +1,6 @@
+-- A todo can be assigned to anyone who can see it
+ALTER TABLE todos ADD COLUMN assignee_id INTEGER REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE todos ADD COLUMN assigned_by INTEGER REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE todos ADD COLUMN assigned_at TIMESTAMP(0);
+
+CREATE INDEX IF NOT EXISTS idx_todos_assignee_id ON todos(assignee_id);
@@ -0,0 +1,15 @@
+-- Append-only history of each todo. revision counts from 1 per todo;
+-- changes holds the field diffs as JSON and state the tracked fields after
+-- the change, which is what a revert goes back to.
+CREATE TABLE IF NOT EXISTS todo_revisions (
+    id SERIAL PRIMARY KEY,
+    todo_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+    revision INTEGER NOT NULL,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    action TEXT NOT NULL,
+    changes TEXT NOT NULL,
+    state TEXT NOT NULL,
+    reverted_to INTEGER,
+    created_at TIMESTAMP(0) DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+    UNIQUE (todo_id, revision)
+);
@@ -0,0 +1,15 @@
+-- Responses to requests sent with an Idempotency-Key, kept until expires_at
+-- so that a retried request gets the same response instead of running
+-- twice. status is 0 while the first request is still running.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    idempotency_key TEXT NOT NULL,
+    request_hash TEXT NOT NULL,
+    status INTEGER NOT NULL DEFAULT 0,
+    header TEXT NOT NULL DEFAULT '{}',
+    body BYTEA,
+    created_at TIMESTAMP(0) DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC'),
+    expires_at TIMESTAMP(0) NOT NULL,
+    UNIQUE (user_id, idempotency_key)
+);
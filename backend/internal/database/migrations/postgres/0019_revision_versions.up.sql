@@ -0,0 +1,4 @@
+-- version is the todo's version once the change was saved, so that the
+-- changes a client has not seen yet can be told apart when it syncs.
+-- Revisions recorded before this migration have none.
+ALTER TABLE todo_revisions ADD COLUMN version INTEGER;
@@ -0,0 +1,3 @@
+-- The SQLite full-text index has no Postgres counterpart; search on
+-- Postgres uses ILIKE. This keeps the versions in step.
+SELECT 1;
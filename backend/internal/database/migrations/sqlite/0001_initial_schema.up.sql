@@ -1,8 +1,5 @@
--- Migration: Initial schema setup
--- Version: 001
--- Description: Create users and todos tables with proper relationships and indices
-
--- Users table
+-- Users and todos as shipped in the first release. IF NOT EXISTS lets
+-- databases created before versioned migrations adopt this baseline.
 CREATE TABLE IF NOT EXISTS users (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     email TEXT UNIQUE NOT NULL,
@@ -10,7 +7,6 @@ CREATE TABLE IF NOT EXISTS users (
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 );
 
--- Todos table
 CREATE TABLE IF NOT EXISTS todos (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     user_id INTEGER NOT NULL,
@@ -23,17 +19,6 @@ CREATE TABLE IF NOT EXISTS todos (
     FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
 );
 
--- Indices for performance optimization
 CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
 CREATE INDEX IF NOT EXISTS idx_todos_user_id ON todos(user_id);
 CREATE INDEX IF NOT EXISTS idx_todos_created_at ON todos(created_at);
-CREATE INDEX IF NOT EXISTS idx_todos_priority ON todos(priority);
-CREATE INDEX IF NOT EXISTS idx_todos_completed ON todos(completed);
-
--- Trigger to automatically update updated_at timestamp
-CREATE TRIGGER IF NOT EXISTS update_todos_timestamp 
-    AFTER UPDATE ON todos
-    FOR EACH ROW
-    BEGIN
-        UPDATE todos SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
-    END;
\ No newline at end of file
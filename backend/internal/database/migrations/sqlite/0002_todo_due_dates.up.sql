@@ -0,0 +1,6 @@
+-- Optional deadline per todo. All-day due dates are stored as midnight UTC
+-- of their calendar date.
+ALTER TABLE todos ADD COLUMN due_at DATETIME;
+ALTER TABLE todos ADD COLUMN all_day BOOLEAN DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_todos_user_due_at ON todos(user_id, due_at, id);
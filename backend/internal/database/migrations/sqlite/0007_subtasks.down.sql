@@ -0,0 +1,3 @@
+-- Subtasks become top-level todos
+DROP INDEX IF EXISTS idx_todos_parent_id;
+ALTER TABLE todos DROP COLUMN parent_id;
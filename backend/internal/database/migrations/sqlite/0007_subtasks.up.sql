@@ -0,0 +1,6 @@
+-- Subtasks: a todo may belong to a parent todo of the same user. The column
+-- has no REFERENCES clause so that it can be dropped again; the store
+-- deletes subtasks together with their parent.
+ALTER TABLE todos ADD COLUMN parent_id INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_todos_parent_id ON todos(parent_id);
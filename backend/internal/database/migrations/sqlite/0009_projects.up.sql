@@ -0,0 +1,19 @@
+-- Projects group a user's todos; todos without a project are in the inbox.
+-- todos.project_id has no REFERENCES clause so that it can be dropped again.
+CREATE TABLE IF NOT EXISTS projects (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    color TEXT NOT NULL DEFAULT '',
+    archived BOOLEAN NOT NULL DEFAULT FALSE,
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_projects_user_id ON projects(user_id, sort_order);
+
+ALTER TABLE todos ADD COLUMN project_id INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_todos_project_id ON todos(project_id);
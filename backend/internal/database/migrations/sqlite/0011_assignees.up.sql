@@ -0,0 +1,7 @@
+-- A todo can be assigned to anyone who can see it. The columns have no
+-- REFERENCES clause so that they can be dropped again.
+ALTER TABLE todos ADD COLUMN assignee_id INTEGER;
+ALTER TABLE todos ADD COLUMN assigned_by INTEGER;
+ALTER TABLE todos ADD COLUMN assigned_at DATETIME;
+
+CREATE INDEX IF NOT EXISTS idx_todos_assignee_id ON todos(assignee_id);
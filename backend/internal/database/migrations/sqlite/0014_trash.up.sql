@@ -0,0 +1,5 @@
+-- Deleted todos go to the trash first: deleted_at is set on the todo and
+-- the subtasks deleted with it, and they are purged for good later
+ALTER TABLE todos ADD COLUMN deleted_at DATETIME;
+
+CREATE INDEX IF NOT EXISTS idx_todos_deleted_at ON todos(deleted_at);
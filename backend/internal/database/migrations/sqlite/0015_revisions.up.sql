@@ -0,0 +1,17 @@
+-- Append-only history of each todo. revision counts from 1 per todo;
+-- changes holds the field diffs as JSON and state the tracked fields after
+-- the change, which is what a revert goes back to.
+CREATE TABLE IF NOT EXISTS todo_revisions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    todo_id INTEGER NOT NULL,
+    revision INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    action TEXT NOT NULL,
+    changes TEXT NOT NULL,
+    state TEXT NOT NULL,
+    reverted_to INTEGER,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (todo_id, revision),
+    FOREIGN KEY (todo_id) REFERENCES todos(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
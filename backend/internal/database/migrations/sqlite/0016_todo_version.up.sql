@@ -0,0 +1,3 @@
+-- version goes up by one with every change to a todo and backs its ETag,
+-- so that concurrent edits can be detected
+ALTER TABLE todos ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
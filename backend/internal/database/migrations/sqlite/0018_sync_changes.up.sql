@@ -0,0 +1,36 @@
+-- Each user's change feed for offline sync. A user's changes are numbered
+-- by their own sequence in sync_sequences; sync_changes keeps the latest
+-- change of every todo the user has been sent, and a tombstone (deleted)
+-- once the todo is deleted or the user can no longer see it. created_seq is
+-- the change that first showed the user the todo. Tombstones outlive their
+-- todos, so todo_id has no foreign key.
+CREATE TABLE IF NOT EXISTS sync_sequences (
+    user_id INTEGER PRIMARY KEY,
+    last_seq INTEGER NOT NULL DEFAULT 0,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS sync_changes (
+    user_id INTEGER NOT NULL,
+    todo_id INTEGER NOT NULL,
+    seq INTEGER NOT NULL,
+    created_seq INTEGER NOT NULL,
+    deleted BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (user_id, todo_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sync_changes_seq ON sync_changes(user_id, seq);
+
+-- Every todo a user can already see starts out in their feed
+INSERT INTO sync_changes (user_id, todo_id, seq, created_seq, deleted)
+SELECT user_id, todo_id, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY todo_id), 0, FALSE
+FROM (
+    SELECT user_id, id AS todo_id FROM todos WHERE deleted_at IS NULL AND project_id IS NULL
+    UNION SELECT p.user_id, t.id FROM todos t JOIN projects p ON p.id = t.project_id WHERE t.deleted_at IS NULL
+    UNION SELECT m.user_id, t.id FROM todos t JOIN project_members m ON m.project_id = t.project_id WHERE t.deleted_at IS NULL
+) visible;
+
+UPDATE sync_changes SET created_seq = seq;
+
+INSERT INTO sync_sequences (user_id, last_seq)
+SELECT user_id, MAX(seq) FROM sync_changes GROUP BY user_id;
@@ -0,0 +1 @@
+ALTER TABLE todo_revisions DROP COLUMN version;
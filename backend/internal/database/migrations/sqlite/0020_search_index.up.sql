@@ -0,0 +1,26 @@
+-- Full-text index over todo titles and descriptions, kept in step with the
+-- todos table by triggers. FTS5 is only compiled into go-sqlite3 with the
+-- sqlite_fts5 build tag. Databases created before this migration may
+-- already have the index, and the rebuild indexes every existing todo.
+CREATE VIRTUAL TABLE IF NOT EXISTS todos_fts USING fts5(
+    title,
+    description,
+    content='todos',
+    content_rowid='id',
+    tokenize='unicode61 remove_diacritics 2'
+);
+
+CREATE TRIGGER IF NOT EXISTS todos_fts_insert AFTER INSERT ON todos BEGIN
+    INSERT INTO todos_fts(rowid, title, description) VALUES (new.id, new.title, new.description);
+END;
+
+CREATE TRIGGER IF NOT EXISTS todos_fts_delete AFTER DELETE ON todos BEGIN
+    INSERT INTO todos_fts(todos_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+END;
+
+CREATE TRIGGER IF NOT EXISTS todos_fts_update AFTER UPDATE OF title, description ON todos BEGIN
+    INSERT INTO todos_fts(todos_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+    INSERT INTO todos_fts(rowid, title, description) VALUES (new.id, new.title, new.description);
+END;
+
+INSERT INTO todos_fts(todos_fts) VALUES ('rebuild');
@@ -0,0 +1,4 @@
+-- timezone is the IANA time zone a repeating todo's rule is expanded in, so
+-- that BYDAY and the day of the month follow the user's calendar rather than
+-- UTC's. Todos without one repeat in UTC as before.
+ALTER TABLE todos ADD COLUMN timezone TEXT;
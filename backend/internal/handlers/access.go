@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"net/http"
+
+	"todo-list-app/internal/models"
+	"todo-list-app/internal/store"
+)
+
+// roleRanks orders project roles from least to most access
+var roleRanks = map[string]int{
+	models.RoleViewer: 1,
+	models.RoleEditor: 2,
+	models.RoleOwner:  3,
+}
+
+// hasRole reports whether role grants at least the access of need. The
+// empty role, i.e. no access at all, grants nothing.
+func hasRole(role, need string) bool {
+	return roleRanks[role] > 0 && roleRanks[role] >= roleRanks[need]
+}
+
+// todoRole returns the user's role on a todo. Inbox todos are private to
+// the user who created them; a todo in a project is shared with everyone
+// who has a role on the project. No access at all is the empty role.
+func todoRole(projects store.ProjectStore, userID int, todo models.Todo) (string, error) {
+	if todo.ProjectID == nil {
+		if todo.UserID == userID {
+			return models.RoleOwner, nil
+		}
+		return "", nil
+	}
+
+	project, err := projects.GetProject(*todo.ProjectID, userID)
+	if err == store.ErrNotFound {
+		return "", nil
+	}
+	return project.Role, err
+}
+
+// authorizeTodo loads a todo and checks that the user has at least the need
+// role on it. A todo the user cannot see at all is reported as not found,
+// so that its existence is not revealed; one the user can see but not
+// change is forbidden. On failure the error response has been written.
+func (h *TodoHandler) authorizeTodo(w http.ResponseWriter, userID, todoID int, need string) (models.Todo, bool) {
+	todo, err := h.todos.GetTodo(todoID)
+	if err == store.ErrNotFound {
+		http.Error(w, "Todo not found", http.StatusNotFound)
+		return todo, false
+	} else if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return todo, false
+	}
+
+	role, err := todoRole(h.projects, userID, todo)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return todo, false
+	}
+	if !writeRoleError(w, role, need, "Todo not found") {
+		return todo, false
+	}
+	return todo, true
+}
+
+// authorizeProject loads a project and checks that the user has at least
+// the need role on it, reporting failures the same way as authorizeTodo
+func authorizeProject(w http.ResponseWriter, projects store.ProjectStore, userID, projectID int, need string) (models.Project, bool) {
+	project, err := projects.GetProject(projectID, userID)
+	if err == store.ErrNotFound {
+		http.Error(w, "Project not found", http.StatusNotFound)
+		return project, false
+	} else if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return project, false
+	}
+	if !writeRoleError(w, project.Role, need, "Project not found") {
+		return project, false
+	}
+	return project, true
+}
+
+// writeRoleError writes 404 with notFound when role grants no access and
+// 403 when it grants too little. It reports whether role is sufficient.
+func writeRoleError(w http.ResponseWriter, role, need, notFound string) bool {
+	switch {
+	case role == "":
+		http.Error(w, notFound, http.StatusNotFound)
+		return false
+	case !hasRole(role, need):
+		http.Error(w, "Unauthorized", http.StatusForbidden)
+		return false
+	}
+	return true
+}
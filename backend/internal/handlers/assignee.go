@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"todo-list-app/internal/models"
+)
+
+// parseAssigneeFilter reads the assignee query parameter of GetTodos: "me"
+// selects todos assigned to the user, "none" unassigned todos and a number
+// the todos assigned to that user
+func parseAssigneeFilter(value string, userID int) (*int, error) {
+	switch value {
+	case "me":
+		return &userID, nil
+	case "none":
+		none := 0
+		return &none, nil
+	}
+	assigneeID, err := strconv.Atoi(value)
+	if err != nil || assigneeID < 1 {
+		return nil, fmt.Errorf("assignee must be me, none or a user ID")
+	}
+	return &assigneeID, nil
+}
+
+// checkAssignee validates assigning a todo, with its project already
+// decided, to assigneeID: the assignee must be able to see the todo.
+// Problems with the request come back with status 400, database failures
+// with status 500.
+func (h *TodoHandler) checkAssignee(todo models.Todo, assigneeID int) (int, error) {
+	role, err := todoRole(h.projects, assigneeID, todo)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if role == "" {
+		if todo.ProjectID == nil {
+			return http.StatusBadRequest, fmt.Errorf("a todo in the inbox can only be assigned to its creator")
+		}
+		return http.StatusBadRequest, fmt.Errorf("assignee_id must be a member of the todo's project")
+	}
+	return http.StatusOK, nil
+}
+
+// assign records that userID assigned todo to assigneeID; 0 unassigns it.
+// Assigning a todo to its current assignee keeps the original record.
+func assign(todo *models.Todo, assigneeID, userID int) {
+	if assigneeID == 0 {
+		todo.AssigneeID, todo.AssignedBy, todo.AssignedAt = nil, nil, nil
+		return
+	}
+	if todo.AssigneeID != nil && *todo.AssigneeID == assigneeID {
+		return
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	todo.AssigneeID, todo.AssignedBy, todo.AssignedAt = &assigneeID, &userID, &now
+}
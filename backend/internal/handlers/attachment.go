@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gorilla/mux"
+	"todo-list-app/internal/middleware"
+	"todo-list-app/internal/models"
+	"todo-list-app/internal/store"
+)
+
+// maxAttachmentSize is the largest file accepted as an attachment
+const maxAttachmentSize = 10 << 20
+
+// maxFilenameLength is the longest attachment filename kept, in characters
+const maxFilenameLength = 255
+
+// attachmentTypes are the content types accepted as attachments. The type
+// is detected from the file's first bytes; what the client claims is
+// ignored.
+var attachmentTypes = map[string]bool{
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+}
+
+// errAttachmentTooLarge is returned while reading an upload that exceeds
+// maxAttachmentSize
+var errAttachmentTooLarge = fmt.Errorf("attachment must be at most %d MB", maxAttachmentSize>>20)
+
+// sizeLimitReader fails with errAttachmentTooLarge once more than
+// maxAttachmentSize bytes have been read
+type sizeLimitReader struct {
+	r    io.Reader
+	read int64
+}
+
+func (l *sizeLimitReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	if l.read > maxAttachmentSize {
+		return n, errAttachmentTooLarge
+	}
+	return n, err
+}
+
+// cleanFilename reduces an uploaded filename to its base name without
+// control characters, falling back to "attachment"
+func cleanFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+	if runes := []rune(name); len(runes) > maxFilenameLength {
+		name = string(runes[:maxFilenameLength])
+	}
+	return name
+}
+
+// authorizeAttachment loads an attachment of a todo the user can see,
+// reporting failures like authorizeComment
+func (h *TodoHandler) authorizeAttachment(w http.ResponseWriter, r *http.Request, userID int) (models.Todo, models.Attachment, bool) {
+	todoID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid todo ID", http.StatusBadRequest)
+		return models.Todo{}, models.Attachment{}, false
+	}
+	attachmentID, err := strconv.Atoi(mux.Vars(r)["attachmentID"])
+	if err != nil {
+		http.Error(w, "Invalid attachment ID", http.StatusBadRequest)
+		return models.Todo{}, models.Attachment{}, false
+	}
+
+	todo, ok := h.authorizeTodo(w, userID, todoID, models.RoleViewer)
+	if !ok {
+		return todo, models.Attachment{}, false
+	}
+
+	attachment, err := h.attachments.GetAttachment(attachmentID)
+	if err == store.ErrNotFound || (err == nil && attachment.TodoID != todoID) {
+		http.Error(w, "Attachment not found", http.StatusNotFound)
+		return todo, attachment, false
+	} else if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return todo, attachment, false
+	}
+	return todo, attachment, true
+}
+
+// GetAttachments lists a todo's attachments, oldest first
+func (h *TodoHandler) GetAttachments(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	todoID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid todo ID", http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := h.authorizeTodo(w, userID, todoID, models.RoleViewer); !ok {
+		return
+	}
+
+	attachments, err := h.attachments.ListAttachments(todoID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(attachments)
+}
+
+// UploadAttachment attaches the file sent in the "file" field of a
+// multipart form to a todo. The file is streamed into the blob store.
+func (h *TodoHandler) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	todoID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid todo ID", http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := h.authorizeTodo(w, userID, todoID, models.RoleEditor); !ok {
+		return
+	}
+
+	// Leave room for the multipart headers around the file
+	r.Body = http.MaxBytesReader(w, r.Body, maxAttachmentSize+1<<20)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "request must be multipart/form-data with a file field")
+		return
+	}
+	var part io.Reader
+	var filename string
+	for {
+		p, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Invalid multipart body")
+			return
+		}
+		if p.FormName() == "file" {
+			part, filename = p, p.FileName()
+			break
+		}
+	}
+	if part == nil {
+		writeJSONError(w, http.StatusBadRequest, "file is required")
+		return
+	}
+
+	content := bufio.NewReaderSize(&sizeLimitReader{r: part}, 512)
+	head, err := content.Peek(512)
+	if err != nil && err != io.EOF {
+		writeJSONError(w, http.StatusBadRequest, "Invalid multipart body")
+		return
+	}
+	if len(head) == 0 {
+		writeJSONError(w, http.StatusBadRequest, "file is empty")
+		return
+	}
+	contentType := http.DetectContentType(head)
+	if !attachmentTypes[contentType] {
+		writeJSONError(w, http.StatusUnsupportedMediaType, "only PNG, JPEG, GIF, WebP images and PDF files can be attached")
+		return
+	}
+
+	attachment := models.Attachment{
+		TodoID:      todoID,
+		UserID:      userID,
+		Filename:    cleanFilename(filename),
+		ContentType: contentType,
+	}
+	var maxBytesErr *http.MaxBytesError
+	if err := h.attachments.CreateAttachment(&attachment, content); errors.Is(err, errAttachmentTooLarge) || errors.As(err, &maxBytesErr) {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, errAttachmentTooLarge.Error())
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to store attachment", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(attachment)
+}
+
+// DownloadAttachment sends an attachment's content. It is always offered
+// as a download under its original filename, never rendered inline.
+func (h *TodoHandler) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	_, attachment, ok := h.authorizeAttachment(w, r, userID)
+	if !ok {
+		return
+	}
+
+	content, err := h.attachments.OpenAttachment(attachment)
+	if err == store.ErrNotFound {
+		http.Error(w, "Attachment content is missing", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to read attachment", http.StatusInternalServerError)
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("ETag", `"`+attachment.SHA256+`"`)
+	http.ServeContent(w, r, "", attachment.CreatedAt, content)
+}
+
+// DeleteAttachment removes an attachment. Its uploader can delete it, and
+// so can an owner of the todo.
+func (h *TodoHandler) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	todo, attachment, ok := h.authorizeAttachment(w, r, userID)
+	if !ok {
+		return
+	}
+	if attachment.UserID != userID {
+		role, err := todoRole(h.projects, userID, todo)
+		if err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if !hasRole(role, models.RoleOwner) {
+			http.Error(w, "Unauthorized", http.StatusForbidden)
+			return
+		}
+	}
+
+	if err := h.attachments.DeleteAttachment(attachment.ID); err == store.ErrNotFound {
+		http.Error(w, "Attachment not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to delete attachment", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Attachment deleted successfully"})
+}
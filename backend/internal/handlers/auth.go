@@ -1,24 +1,28 @@
 package handlers
 
 import (
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"regexp"
+	"strconv"
+	"time"
 
+	"github.com/gorilla/mux"
 	"golang.org/x/crypto/bcrypt"
 	"todo-list-app/internal/middleware"
 	"todo-list-app/internal/models"
+	"todo-list-app/internal/store"
 )
 
 type AuthHandler struct {
-	db *sql.DB
+	users    store.UserStore
+	sessions store.SessionStore
 }
 
 // NewAuthHandler creates a new auth handler
-func NewAuthHandler(db *sql.DB) *AuthHandler {
-	return &AuthHandler{db: db}
+func NewAuthHandler(users store.UserStore, sessions store.SessionStore) *AuthHandler {
+	return &AuthHandler{users: users, sessions: sessions}
 }
 
 // Register handles user registration
@@ -37,19 +41,6 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if user already exists
-	var existingID int
-	err := h.db.QueryRow("SELECT id FROM users WHERE email = ?", req.Email).Scan(&existingID)
-	if err == nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusConflict)
-		json.NewEncoder(w).Encode(map[string]string{"error": "User already exists"})
-		return
-	} else if err != sql.ErrNoRows {
-		http.Error(w, "Database error", http.StatusInternalServerError)
-		return
-	}
-
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -57,30 +48,15 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Create user
-	result, err := h.db.Exec(
-		"INSERT INTO users (email, password_hash) VALUES (?, ?)",
-		req.Email, string(hashedPassword),
-	)
-	if err != nil {
-		http.Error(w, "Failed to create user", http.StatusInternalServerError)
-		return
-	}
-
-	userID, err := result.LastInsertId()
-	if err != nil {
-		http.Error(w, "Failed to get user ID", http.StatusInternalServerError)
+	// Create user, unless one already exists with this email
+	user, err := h.users.CreateUser(req.Email, string(hashedPassword))
+	if err == store.ErrConflict {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": "User already exists"})
 		return
-	}
-
-	// Get the created user
-	user := models.User{}
-	err = h.db.QueryRow(
-		"SELECT id, email, created_at FROM users WHERE id = ?",
-		userID,
-	).Scan(&user.ID, &user.Email, &user.CreatedAt)
-	if err != nil {
-		http.Error(w, "Failed to retrieve user", http.StatusInternalServerError)
+	} else if err != nil {
+		http.Error(w, "Failed to create user", http.StatusInternalServerError)
 		return
 	}
 
@@ -101,12 +77,8 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get user from database
-	var user models.User
-	err := h.db.QueryRow(
-		"SELECT id, email, password_hash, created_at FROM users WHERE email = ?",
-		req.Email,
-	).Scan(&user.ID, &user.Email, &user.Password, &user.CreatedAt)
-	if err == sql.ErrNoRows {
+	user, err := h.users.GetUserByEmail(req.Email)
+	if err == store.ErrNotFound {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid credentials"})
@@ -125,16 +97,7 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create session
-	session, err := middleware.SessionStore.Get(r, "auth-session")
-	if err != nil {
-		http.Error(w, "Session error", http.StatusInternalServerError)
-		return
-	}
-
-	session.Values["user_id"] = user.ID
-	session.Values["email"] = user.Email
-
-	if err := session.Save(r, w); err != nil {
+	if err := middleware.StartSession(w, r, user.ID); err != nil {
 		http.Error(w, "Failed to save session", http.StatusInternalServerError)
 		return
 	}
@@ -151,38 +114,161 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 
 // Logout handles user logout
 func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
-	session, err := middleware.SessionStore.Get(r, "auth-session")
+	// End the session and clear the cookie
+	if err := middleware.EndSession(w, r); err != nil {
+		http.Error(w, "Failed to clear session", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Logout successful"})
+}
+
+// GetSessions lists the devices the user is logged in on
+func (h *AuthHandler) GetSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	sessions, err := h.sessions.ListSessions(userID, time.Now())
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	currentID, _ := middleware.GetSessionIDFromContext(r)
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentID
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessions)
+}
+
+// DeleteSession logs one of the user's devices out
+func (h *AuthHandler) DeleteSession(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	sessionID, err := strconv.Atoi(mux.Vars(r)["id"])
 	if err != nil {
-		http.Error(w, "Session error", http.StatusInternalServerError)
+		http.Error(w, "Invalid session ID", http.StatusBadRequest)
 		return
 	}
 
-	// Clear session
-	session.Values["user_id"] = nil
-	session.Values["email"] = nil
-	session.Options.MaxAge = -1
+	if currentID, _ := middleware.GetSessionIDFromContext(r); sessionID == currentID {
+		h.Logout(w, r)
+		return
+	}
 
-	if err := session.Save(r, w); err != nil {
+	if err := h.sessions.DeleteSession(sessionID, userID); err == store.ErrNotFound {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to revoke session", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Session revoked successfully"})
+}
+
+// DeleteSessions logs the user out everywhere, including this client
+func (h *AuthHandler) DeleteSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.sessions.DeleteUserSessions(userID); err != nil {
+		http.Error(w, "Failed to revoke sessions", http.StatusInternalServerError)
+		return
+	}
+	if err := middleware.EndSession(w, r); err != nil {
 		http.Error(w, "Failed to clear session", http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"message": "Logout successful"})
+	json.NewEncoder(w).Encode(map[string]string{"message": "Logged out everywhere"})
+}
+
+// ChangePassword replaces the user's password. Every session is ended, and
+// this client is logged in again with a fresh one.
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.users.GetUserByID(userID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword)); err != nil {
+		writeJSONError(w, http.StatusForbidden, "Current password is incorrect")
+		return
+	}
+	if err := ValidatePassword(req.NewPassword); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		http.Error(w, "Failed to hash password", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.users.UpdatePassword(userID, string(hashedPassword)); err != nil {
+		http.Error(w, "Failed to update password", http.StatusInternalServerError)
+		return
+	}
+	if err := middleware.StartSession(w, r, userID); err != nil {
+		http.Error(w, "Failed to save session", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Password changed; all other sessions have been logged out"})
 }
 
 // validateRegisterRequest validates the registration request
 func (h *AuthHandler) validateRegisterRequest(req models.RegisterRequest) error {
-	// Email validation
+	if err := ValidateEmail(req.Email); err != nil {
+		return err
+	}
+	return ValidatePassword(req.Password)
+}
+
+// ValidateEmail checks that an email address is well formed
+func ValidateEmail(email string) error {
 	emailRegex := `^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`
-	if !regexp.MustCompile(emailRegex).MatchString(req.Email) {
+	if !regexp.MustCompile(emailRegex).MatchString(email) {
 		return fmt.Errorf("invalid email format")
 	}
+	return nil
+}
 
-	// Password validation
-	if len(req.Password) < 6 {
+// ValidatePassword checks that a password meets the minimum requirements
+func ValidatePassword(password string) error {
+	if len(password) < 6 {
 		return fmt.Errorf("password must be at least 6 characters long")
 	}
-
 	return nil
 }
\ No newline at end of file
@@ -0,0 +1,467 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"todo-list-app/internal/middleware"
+	"todo-list-app/internal/models"
+	"todo-list-app/internal/store"
+)
+
+// maxBulkTodos is how many todos one bulk operation may change
+const maxBulkTodos = 500
+
+// followFailed is the error for a subtask left alone because the ancestor
+// it goes along with failed
+const followFailed = "Not applied because its parent failed"
+
+// bulkFilterParams are the GET /api/todos parameters a bulk filter may use
+var bulkFilterParams = []string{"project", "assignee", "completed", "due", "tz", "tag", "tag_mode"}
+
+// bulkItem is the plan for one todo of a bulk operation: its result so far,
+// the change to make, and the state it is diffed against. A subtask the
+// operation moves or deletes along with an ancestor follows that ancestor's
+// item.
+type bulkItem struct {
+	result   models.BulkTodoResult
+	change   *store.TodoChange
+	before   models.TodoState
+	subtasks subtaskMode
+	moves    bool
+	follows  *bulkItem
+}
+
+// fail marks the item as failed with an HTTP status and message
+func (item *bulkItem) fail(status int, message string) {
+	item.result.Status = status
+	item.result.Error = message
+	item.change = nil
+}
+
+// parseBulkActions validates the actions of a bulk request, checking the
+// target project of a move once for all todos. Tag names come back
+// normalized and an inbox move has project ID 0. Problems with the request
+// come back with status 400, database failures with status 500.
+func (h *TodoHandler) parseBulkActions(userID int, actions []models.BulkAction) ([]models.BulkAction, int, error) {
+	if len(actions) == 0 {
+		return nil, http.StatusBadRequest, fmt.Errorf("actions is required")
+	}
+
+	parsed := make([]models.BulkAction, len(actions))
+	for i, action := range actions {
+		switch action.Action {
+		case models.BulkComplete:
+			mode, err := parseSubtaskMode(action.Subtasks)
+			if err != nil {
+				return nil, http.StatusBadRequest, err
+			}
+			action.Subtasks = string(mode)
+		case models.BulkUncomplete:
+		case models.BulkDelete:
+			if len(actions) > 1 {
+				return nil, http.StatusBadRequest, fmt.Errorf("delete cannot be combined with other actions")
+			}
+		case models.BulkSetPriority:
+			if err := validatePriority(action.Priority); err != nil {
+				return nil, http.StatusBadRequest, err
+			}
+		case models.BulkMove:
+			if action.ProjectID == nil || *action.ProjectID == 0 {
+				inbox := 0
+				action.ProjectID = &inbox
+			} else if status, err := h.checkProject(userID, *action.ProjectID); err != nil {
+				return nil, status, err
+			}
+		case models.BulkAddTag, models.BulkRemoveTag:
+			name, err := normalizeTagName(action.Tag)
+			if err != nil {
+				return nil, http.StatusBadRequest, err
+			}
+			action.Tag = name
+		default:
+			return nil, http.StatusBadRequest, fmt.Errorf(
+				"action must be one of complete, uncomplete, delete, set_priority, move, add_tag, remove_tag")
+		}
+		parsed[i] = action
+	}
+	return parsed, http.StatusOK, nil
+}
+
+// parseBulkFilter turns the filter of a bulk request into a todo query
+func parseBulkFilter(filter string, userID int) (store.TodoQuery, error) {
+	query := store.TodoQuery{UserID: userID}
+	values, err := url.ParseQuery(filter)
+	if err != nil {
+		return query, fmt.Errorf("filter must be a query string such as completed=true&project=inbox")
+	}
+	for name := range values {
+		if !slices.Contains(bulkFilterParams, name) {
+			return query, fmt.Errorf("filter cannot use %s; use one of %s", name, strings.Join(bulkFilterParams, ", "))
+		}
+	}
+	if err := parseTodoScope(values, userID, &query); err != nil {
+		return query, err
+	}
+	if err := parseTodoFilter(values, &query); err != nil {
+		return query, err
+	}
+	return query, nil
+}
+
+// planBulkChange works out what the actions do to one todo the user can see
+func (h *TodoHandler) planBulkChange(userID int, todo models.Todo, actions []models.BulkAction, item *bulkItem) {
+	role, err := todoRole(h.projects, userID, todo)
+	if err != nil {
+		item.fail(http.StatusInternalServerError, "Database error")
+		return
+	} else if role == "" {
+		item.fail(http.StatusNotFound, "Todo not found")
+		return
+	} else if !hasRole(role, models.RoleEditor) {
+		item.fail(http.StatusForbidden, "Unauthorized")
+		return
+	}
+
+	item.before = store.StateOf(todo)
+	change := store.TodoChange{Todo: todo}
+	if change.Todo.Tags == nil {
+		change.Todo.Tags = []string{}
+	}
+	completed := todo.Completed
+
+	for _, action := range actions {
+		switch action.Action {
+		case models.BulkDelete:
+			change.Delete = true
+		case models.BulkComplete:
+			completed = true
+			item.subtasks = subtaskMode(action.Subtasks)
+		case models.BulkUncomplete:
+			completed = false
+		case models.BulkSetPriority:
+			change.Todo.Priority = action.Priority
+		case models.BulkMove:
+			var projectID *int
+			if *action.ProjectID != 0 {
+				projectID = action.ProjectID
+			}
+			if sameID(change.Todo.ProjectID, projectID) {
+				continue
+			}
+			// A subtask can only move along with its parent, which
+			// followBulkAncestors checks
+			item.moves = true
+			if todo.ParentID != nil {
+				continue
+			}
+			if projectID == nil && todo.UserID != userID {
+				item.fail(http.StatusForbidden, "Only the todo's creator can move it to the inbox")
+				return
+			}
+			change.Todo.ProjectID = projectID
+		case models.BulkAddTag:
+			if !slices.ContainsFunc(change.Todo.Tags, func(tag string) bool { return strings.EqualFold(tag, action.Tag) }) {
+				change.Todo.Tags = append(slices.Clone(change.Todo.Tags), action.Tag)
+			}
+		case models.BulkRemoveTag:
+			change.Todo.Tags = slices.DeleteFunc(slices.Clone(change.Todo.Tags), func(tag string) bool {
+				return strings.EqualFold(tag, action.Tag)
+			})
+		}
+	}
+
+	if change.Delete {
+		item.change = &change
+		return
+	}
+
+	// Only save the fields when an action changed one of them
+	fields := store.StateOf(change.Todo)
+	fields.Completed = item.before.Completed
+	if changes, err := store.DiffStates(&item.before, fields); err != nil {
+		item.fail(http.StatusInternalServerError, "Database error")
+		return
+	} else if len(changes) > 0 {
+		change.Update = true
+	}
+
+	if completed != todo.Completed {
+		change.Completed = &completed
+		change.Cascade = completed && item.subtasks == subtasksCascade
+		if completed {
+			next, err := nextOccurrence(change.Todo)
+			if err != nil {
+				item.fail(http.StatusInternalServerError, "Stored recurrence rule or timezone is invalid")
+				return
+			}
+			change.Next = next
+		}
+	}
+	item.change = &change
+}
+
+// followBulkAncestors settles the subtasks a bulk operation deletes or
+// moves along with an ancestor it deletes or moves too. Such a subtask
+// follows the topmost of those ancestors and is left out of the changes
+// unless other actions change it too. A subtask cannot move on its own.
+func (h *TodoHandler) followBulkAncestors(items []*bulkItem) {
+	byID := make(map[int]*bulkItem, len(items))
+	for _, item := range items {
+		if item.change != nil {
+			byID[item.result.ID] = item
+		}
+	}
+
+	for _, item := range items {
+		if item.change == nil || item.change.Todo.ParentID == nil || !(item.change.Delete || item.moves) {
+			continue
+		}
+		ancestorIDs, err := h.todos.GetAncestorIDs(item.result.ID)
+		if err != nil {
+			item.fail(http.StatusInternalServerError, "Database error")
+			continue
+		}
+		for _, id := range ancestorIDs {
+			ancestor := byID[id]
+			if ancestor == nil || ancestor.change == nil {
+				continue
+			}
+			if ancestor.change.Delete || (ancestor.moves && ancestor.change.Todo.ParentID == nil) {
+				item.follows = ancestor
+			}
+		}
+
+		switch {
+		case item.follows == nil && item.moves:
+			item.fail(http.StatusBadRequest, "A subtask always belongs to its parent's project")
+		case item.follows == nil:
+		case item.change.Delete || (!item.change.Update && item.change.Completed == nil):
+			item.change = nil
+		}
+	}
+}
+
+// checkBulkSubtasks fails the items that complete a todo with open
+// subtasks in block mode, as ToggleTodo would. Subtasks that the operation
+// completes as well do not count as open.
+func (h *TodoHandler) checkBulkSubtasks(items []*bulkItem) {
+	subtasks := make(map[int][]models.Todo)
+	for failed := true; failed; {
+		completing := make(map[int]bool)
+		for _, item := range items {
+			if item.change != nil && item.change.Completed != nil && *item.change.Completed {
+				completing[item.result.ID] = true
+			}
+		}
+
+		failed = false
+		for _, item := range items {
+			if !completing[item.result.ID] || item.subtasks != subtasksBlock {
+				continue
+			}
+			todo := item.change.Todo
+			if todo.SubtasksTotal == todo.SubtasksDone {
+				continue
+			}
+			if _, ok := subtasks[todo.ID]; !ok {
+				list, err := h.todos.ListSubtasks(todo.ID)
+				if err != nil {
+					item.fail(http.StatusInternalServerError, "Database error")
+					failed = true
+					continue
+				}
+				subtasks[todo.ID] = list
+			}
+			open := 0
+			for _, subtask := range subtasks[todo.ID] {
+				if !subtask.Completed && !completing[subtask.ID] {
+					open++
+				}
+			}
+			if open > 0 {
+				item.fail(http.StatusConflict, openSubtasksError(open))
+				failed = true
+			}
+		}
+	}
+}
+
+// BulkTodos applies a list of actions to many todos at once, in a single
+// transaction. Every todo gets its own result; a todo that cannot be
+// changed does not stop the others unless the request is atomic, in which
+// case nothing is changed and the response is 409.
+func (h *TodoHandler) BulkTodos(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.BulkTodoRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if (req.IDs == nil) == (req.Filter == nil) {
+		writeJSONError(w, http.StatusBadRequest, "Give either ids or filter")
+		return
+	}
+	actions, status, err := h.parseBulkActions(userID, req.Actions)
+	if err != nil {
+		writeParentError(w, status, err)
+		return
+	}
+
+	// Select the todos, either by ID, in the order given, or by filter
+	var items []*bulkItem
+	if req.Filter != nil {
+		query, err := parseBulkFilter(*req.Filter, userID)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		query.Sort, query.Limit = store.SortCreatedAt, maxBulkTodos+1
+		todos, err := h.todos.ListTodos(query)
+		if err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if len(todos) > maxBulkTodos {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("The filter matches more than %d todos", maxBulkTodos))
+			return
+		}
+		for _, todo := range todos {
+			item := &bulkItem{result: models.BulkTodoResult{ID: todo.ID}}
+			h.planBulkChange(userID, todo, actions, item)
+			items = append(items, item)
+		}
+	} else {
+		var ids []int
+		seen := make(map[int]bool)
+		for _, id := range req.IDs {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+		if len(ids) > maxBulkTodos {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("At most %d todos can be changed at once", maxBulkTodos))
+			return
+		}
+		for _, id := range ids {
+			item := &bulkItem{result: models.BulkTodoResult{ID: id}}
+			todo, err := h.todos.GetTodo(id)
+			if err == store.ErrNotFound {
+				item.fail(http.StatusNotFound, "Todo not found")
+			} else if err != nil {
+				item.fail(http.StatusInternalServerError, "Database error")
+			} else {
+				h.planBulkChange(userID, todo, actions, item)
+			}
+			items = append(items, item)
+		}
+	}
+
+	h.followBulkAncestors(items)
+	h.checkBulkSubtasks(items)
+
+	// An atomic request stops before the database if any todo failed.
+	// Subtasks that follow an ancestor change after it, so that their
+	// results show where it took them.
+	planned := []*bulkItem{}
+	var changes []store.TodoChange
+	failed := false
+	for _, item := range items {
+		if item.follows != nil && item.follows.change == nil {
+			item.fail(http.StatusFailedDependency, followFailed)
+		}
+		if item.change == nil {
+			failed = failed || item.follows == nil || item.result.Status != 0
+			continue
+		}
+		if item.follows == nil {
+			planned = append(planned, item)
+		}
+	}
+	for _, item := range items {
+		if item.change != nil && item.follows != nil {
+			planned = append(planned, item)
+		}
+	}
+	for _, item := range planned {
+		changes = append(changes, *item.change)
+	}
+
+	var results []store.TodoChangeResult
+	if !(req.Atomic && failed) && len(changes) > 0 {
+		if results, err = h.todos.ApplyTodoChanges(userID, changes, req.Atomic); err != nil {
+			http.Error(w, "Failed to apply bulk operation", http.StatusInternalServerError)
+			return
+		}
+		for i, result := range results {
+			switch result.Err {
+			case nil:
+			case store.ErrNotFound:
+				planned[i].fail(http.StatusNotFound, "Todo not found")
+				failed = true
+			default:
+				planned[i].fail(http.StatusConflict, todoModified)
+				failed = true
+			}
+		}
+	}
+
+	response := models.BulkTodoResponse{Applied: !(req.Atomic && failed), Results: []models.BulkTodoResult{}}
+	for i, item := range planned {
+		if item.change == nil {
+			continue
+		}
+		if !response.Applied {
+			item.fail(http.StatusFailedDependency, "Not applied because another todo failed")
+			continue
+		}
+		item.result.Status = http.StatusOK
+		if !item.change.Delete {
+			item.result.Todo = &results[i].Todo
+			item.result.NextOccurrence = results[i].Next
+		}
+	}
+	for _, item := range items {
+		if item.follows == nil || item.result.Status != 0 {
+			continue
+		}
+		if item.follows.result.Status != http.StatusOK {
+			item.fail(http.StatusFailedDependency, followFailed)
+			continue
+		}
+		item.result.Status = http.StatusOK
+		if !item.follows.change.Delete {
+			todo, err := h.todos.GetTodo(item.result.ID)
+			if err != nil {
+				http.Error(w, "Database error", http.StatusInternalServerError)
+				return
+			}
+			item.result.Todo = &todo
+		}
+	}
+	for _, item := range items {
+		if item.result.Status == http.StatusOK {
+			response.Succeeded++
+		} else {
+			response.Failed++
+		}
+		response.Results = append(response.Results, item.result)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !response.Applied {
+		w.WriteHeader(http.StatusConflict)
+	}
+	json.NewEncoder(w).Encode(response)
+}
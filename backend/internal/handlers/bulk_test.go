@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+
+	"todo-list-app/internal/models"
+)
+
+func TestBulkSubtrees(t *testing.T) {
+	ts := newTestServer(t)
+	token := ts.addUser("bulk@example.com")
+	user, err := ts.store.GetUserByEmail("bulk@example.com")
+	if err != nil {
+		t.Fatalf("GetUserByEmail: %v", err)
+	}
+	project := models.Project{UserID: user.ID, Name: "Travel"}
+	if err := ts.store.CreateProject(&project); err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+
+	var parent, child models.Todo
+	expect(t, ts.do(token, "POST", "/api/todos", `{"title":"Plan trip"}`), http.StatusCreated, &parent)
+	expect(t, ts.do(token, "POST", "/api/todos", fmt.Sprintf(`{"title":"Book hotel","parent_id":%d}`, parent.ID)),
+		http.StatusCreated, &child)
+
+	bulk := func(ids []int, atomic bool, actions string) ([]int, models.BulkTodoResponse) {
+		t.Helper()
+		var response models.BulkTodoResponse
+		expect(t, ts.do(token, "POST", "/api/todos/bulk",
+			fmt.Sprintf(`{"ids":%s,"atomic":%t,"actions":%s}`, jsonIDs(ids), atomic, actions)), http.StatusOK, &response)
+		var statuses []int
+		for _, result := range response.Results {
+			statuses = append(statuses, result.Status)
+		}
+		return statuses, response
+	}
+	both := []int{child.ID, parent.ID}
+
+	// Completing a todo with an open subtask is refused as by the toggle,
+	// unless the subtask is completed too or the action cascades
+	if got, _ := bulk([]int{parent.ID}, false, `[{"action":"complete"}]`); !slices.Equal(got, []int{http.StatusConflict}) {
+		t.Errorf("complete with an open subtask = %v", got)
+	}
+	if got, _ := bulk(both, true, `[{"action":"complete"}]`); !slices.Equal(got, []int{200, 200}) {
+		t.Errorf("complete with the subtask = %v", got)
+	}
+	bulk(both, true, `[{"action":"uncomplete"}]`)
+	if got, _ := bulk([]int{parent.ID}, false, `[{"action":"complete","subtasks":"cascade"}]`); !slices.Equal(got, []int{200}) {
+		t.Errorf("cascading complete = %v", got)
+	}
+	expect(t, ts.do(token, "GET", fmt.Sprintf("/api/todos/%d", child.ID), ""), http.StatusOK, &child)
+	if !child.Completed {
+		t.Errorf("subtask after cascading complete = %+v", child)
+	}
+
+	// A subtask moves along with its parent, keeping its other changes
+	got, response := bulk(both, true,
+		fmt.Sprintf(`[{"action":"move","project_id":%d},{"action":"set_priority","priority":3}]`, project.ID))
+	if !slices.Equal(got, []int{200, 200}) {
+		t.Fatalf("move with the subtask = %v", got)
+	}
+	for _, result := range response.Results {
+		if todo := result.Todo; todo == nil || todo.ProjectID == nil || *todo.ProjectID != project.ID || todo.Priority != 3 {
+			t.Errorf("moved todo = %+v", todo)
+		}
+	}
+	if got, _ := bulk([]int{child.ID}, false, `[{"action":"move"}]`); !slices.Equal(got, []int{http.StatusBadRequest}) {
+		t.Errorf("moving a subtask alone = %v", got)
+	}
+
+	// A subtask is deleted along with its parent
+	if got, _ := bulk(both, true, `[{"action":"delete"}]`); !slices.Equal(got, []int{200, 200}) {
+		t.Errorf("delete with the subtask = %v", got)
+	}
+	expect(t, ts.do(token, "GET", fmt.Sprintf("/api/todos/%d", child.ID), ""), http.StatusNotFound, nil)
+}
+
+// jsonIDs encodes todo IDs as a JSON array
+func jsonIDs(ids []int) string {
+	encoded := "["
+	for i, id := range ids {
+		if i > 0 {
+			encoded += ","
+		}
+		encoded += fmt.Sprint(id)
+	}
+	return encoded + "]"
+}
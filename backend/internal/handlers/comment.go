@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"todo-list-app/internal/middleware"
+	"todo-list-app/internal/models"
+	"todo-list-app/internal/store"
+)
+
+// maxCommentLength is the longest comment body accepted, in characters
+const maxCommentLength = 10000
+
+// validateCommentBody checks a comment body. The Markdown is stored as
+// written; only an all-blank body is refused.
+func validateCommentBody(body string) error {
+	if strings.TrimSpace(body) == "" {
+		return fmt.Errorf("comment body is required")
+	}
+	if len([]rune(body)) > maxCommentLength {
+		return fmt.Errorf("comment body must be at most %d characters", maxCommentLength)
+	}
+	return nil
+}
+
+// authorizeComment loads a comment of a todo the user can see. A comment
+// that belongs to another todo is reported as not found. On failure the
+// error response has been written.
+func (h *TodoHandler) authorizeComment(w http.ResponseWriter, r *http.Request, userID int) (models.Todo, models.Comment, bool) {
+	todoID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid todo ID", http.StatusBadRequest)
+		return models.Todo{}, models.Comment{}, false
+	}
+	commentID, err := strconv.Atoi(mux.Vars(r)["commentID"])
+	if err != nil {
+		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+		return models.Todo{}, models.Comment{}, false
+	}
+
+	todo, ok := h.authorizeTodo(w, userID, todoID, models.RoleViewer)
+	if !ok {
+		return todo, models.Comment{}, false
+	}
+
+	comment, err := h.comments.GetComment(commentID)
+	if err == store.ErrNotFound || (err == nil && comment.TodoID != todoID) {
+		http.Error(w, "Comment not found", http.StatusNotFound)
+		return todo, comment, false
+	} else if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return todo, comment, false
+	}
+	return todo, comment, true
+}
+
+// GetComments lists a todo's comments, oldest first
+func (h *TodoHandler) GetComments(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	todoID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid todo ID", http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := h.authorizeTodo(w, userID, todoID, models.RoleViewer); !ok {
+		return
+	}
+
+	comments, err := h.comments.ListComments(todoID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(comments)
+}
+
+// CreateComment adds a comment to a todo, written by the authenticated user
+func (h *TodoHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	todoID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid todo ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validateCommentBody(req.Body); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if _, ok := h.authorizeTodo(w, userID, todoID, models.RoleEditor); !ok {
+		return
+	}
+
+	comment := models.Comment{
+		TodoID: todoID,
+		UserID: userID,
+		Body:   req.Body,
+	}
+	if err := h.comments.CreateComment(&comment); err != nil {
+		http.Error(w, "Failed to create comment", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(comment)
+}
+
+// UpdateComment replaces the body of a comment. Only its author can edit
+// it, and the comment is flagged as edited from then on.
+func (h *TodoHandler) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.CommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validateCommentBody(req.Body); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	_, comment, ok := h.authorizeComment(w, r, userID)
+	if !ok {
+		return
+	}
+	if comment.UserID != userID {
+		http.Error(w, "Unauthorized", http.StatusForbidden)
+		return
+	}
+
+	comment.Body = req.Body
+	if err := h.comments.UpdateComment(&comment); err == store.ErrNotFound {
+		http.Error(w, "Comment not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to update comment", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(comment)
+}
+
+// DeleteComment deletes a comment. Its author can delete it, and so can
+// an owner of the todo, to moderate the thread.
+func (h *TodoHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	todo, comment, ok := h.authorizeComment(w, r, userID)
+	if !ok {
+		return
+	}
+	if comment.UserID != userID {
+		role, err := todoRole(h.projects, userID, todo)
+		if err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if !hasRole(role, models.RoleOwner) {
+			http.Error(w, "Unauthorized", http.StatusForbidden)
+			return
+		}
+	}
+
+	if err := h.comments.DeleteComment(comment.ID); err == store.ErrNotFound {
+		http.Error(w, "Comment not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to delete comment", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Comment deleted successfully"})
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"todo-list-app/internal/store"
+)
+
+// parseDueAt parses the due_at field of a todo request. Timed due dates must
+// be RFC 3339; all-day due dates may also be given as YYYY-MM-DD and are
+// normalized to midnight UTC of the calendar date they name.
+func parseDueAt(raw *string, allDay bool) (*time.Time, bool, error) {
+	if raw == nil || *raw == "" {
+		return nil, false, nil
+	}
+
+	if allDay {
+		if date, err := time.Parse("2006-01-02", *raw); err == nil {
+			return &date, true, nil
+		}
+		t, err := time.Parse(time.RFC3339, *raw)
+		if err != nil {
+			return nil, false, fmt.Errorf("due_at must be a YYYY-MM-DD date or RFC 3339 timestamp")
+		}
+		date := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return &date, true, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, *raw)
+	if err != nil {
+		return nil, false, fmt.Errorf("due_at must be an RFC 3339 timestamp")
+	}
+	t = t.UTC()
+	return &t, false, nil
+}
+
+// dueFilter builds the store filter for the ?due= views. Timed todos are
+// compared against the boundaries of the user's local day, while all-day
+// todos are compared by calendar date in that same timezone.
+func dueFilter(view string, now time.Time, loc *time.Location) (*store.DueFilter, error) {
+	local := now.In(loc)
+	startOfDay := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+
+	switch view {
+	case "overdue":
+		return &store.DueFilter{TimedTo: now, DateTo: today, OpenOnly: true}, nil
+	case "today":
+		return &store.DueFilter{
+			TimedFrom: startOfDay, TimedTo: startOfDay.AddDate(0, 0, 1),
+			DateFrom: today, DateTo: today.AddDate(0, 0, 1),
+		}, nil
+	case "week":
+		return &store.DueFilter{
+			TimedFrom: startOfDay, TimedTo: startOfDay.AddDate(0, 0, 7),
+			DateFrom: today, DateTo: today.AddDate(0, 0, 7),
+		}, nil
+	default:
+		return nil, fmt.Errorf("due must be one of overdue, today, week")
+	}
+}
+
+// parseLocation resolves the user's timezone from the tz parameter,
+// falling back to UTC when it is not provided
+func parseLocation(tz string) (*time.Location, error) {
+	if tz == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tz: %s", tz)
+	}
+	return loc, nil
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"todo-list-app/internal/models"
+)
+
+// todoModified is the error for a write based on an outdated todo
+const todoModified = "Todo has been modified; reload it and try again"
+
+// todoETag returns the entity tag of a todo, which is its version
+func todoETag(todo models.Todo) string {
+	return `"` + strconv.Itoa(todo.Version) + `"`
+}
+
+// etagMatches reports whether an If-Match or If-None-Match header lists
+// etag or is "*". Weak comparison, used for If-None-Match, ignores the W/
+// prefix; strong comparison never matches a weak tag.
+func etagMatches(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIfMatch enforces the If-Match precondition of a write to todo. It
+// reports whether the write may go ahead; otherwise 412 has been written.
+func checkIfMatch(w http.ResponseWriter, r *http.Request, todo models.Todo) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" || etagMatches(header, todoETag(todo), false) {
+		return true
+	}
+	w.Header().Set("ETag", todoETag(todo))
+	writeJSONError(w, http.StatusPreconditionFailed, todoModified)
+	return false
+}
+
+// writeTodoModified reports a todo that changed between loading and saving
+// it: 412 for a conditional request, 409 otherwise
+func writeTodoModified(w http.ResponseWriter, r *http.Request) {
+	status := http.StatusConflict
+	if r.Header.Get("If-Match") != "" {
+		status = http.StatusPreconditionFailed
+	}
+	writeJSONError(w, status, todoModified)
+}
+
+// writeTodo writes a single todo along with its ETag
+func writeTodo(w http.ResponseWriter, status int, todo models.Todo) {
+	w.Header().Set("ETag", todoETag(todo))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(todo)
+}
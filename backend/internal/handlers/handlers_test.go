@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"todo-list-app/internal/middleware"
+	"todo-list-app/internal/models"
+	"todo-list-app/internal/store"
+)
+
+// testServer serves the todo and sync routes from a MemoryStore, with
+// requests authenticated by personal access token as in production
+type testServer struct {
+	t      *testing.T
+	store  *store.MemoryStore
+	router *mux.Router
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	s := store.NewMemoryStore()
+	todoHandler := NewTodoHandler(s, s, s, s, s, s)
+
+	r := mux.NewRouter()
+	todos := r.PathPrefix("/api/todos").Subrouter()
+	todos.Use(middleware.RequireAuth(s, "todos"))
+	todos.HandleFunc("", todoHandler.GetTodos).Methods("GET")
+	todos.HandleFunc("", todoHandler.CreateTodo).Methods("POST")
+	todos.HandleFunc("/bulk", todoHandler.BulkTodos).Methods("POST")
+	todos.HandleFunc("/{id}", todoHandler.GetTodo).Methods("GET")
+	todos.HandleFunc("/{id}", todoHandler.UpdateTodo).Methods("PUT")
+	todos.HandleFunc("/{id}", todoHandler.PatchTodo).Methods("PATCH")
+	todos.HandleFunc("/{id}", todoHandler.DeleteTodo).Methods("DELETE")
+	todos.HandleFunc("/{id}/toggle", todoHandler.ToggleTodo).Methods("PATCH")
+	todos.HandleFunc("/{id}/history", todoHandler.GetHistory).Methods("GET")
+
+	sync := r.PathPrefix("/api/sync").Subrouter()
+	sync.Use(middleware.RequireAuth(s, "todos"))
+	sync.HandleFunc("", todoHandler.GetChanges).Methods("GET")
+	sync.HandleFunc("/push", todoHandler.PushChanges).Methods("POST")
+
+	return &testServer{t: t, store: s, router: r}
+}
+
+// addUser creates a user and returns a token that can read and write
+// their todos
+func (ts *testServer) addUser(email string) string {
+	ts.t.Helper()
+	user, err := ts.store.CreateUser(email, "unused")
+	if err != nil {
+		ts.t.Fatalf("CreateUser: %v", err)
+	}
+	secret := "secret-" + email
+	token := models.APIToken{UserID: user.ID, Name: "test", Prefix: secret[:8], Scopes: []string{"todos:write"}}
+	if err := ts.store.CreateToken(&token, middleware.HashToken(secret)); err != nil {
+		ts.t.Fatalf("CreateToken: %v", err)
+	}
+	return secret
+}
+
+// do sends a request as the owner of token; header holds extra header
+// names and values in pairs
+func (ts *testServer) do(token, method, path, body string, header ...string) *httptest.ResponseRecorder {
+	ts.t.Helper()
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	ts.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// expect fails the test unless the response has the wanted status, and
+// decodes its body into v when v is not nil
+func expect(t *testing.T, rec *httptest.ResponseRecorder, status int, v interface{}) {
+	t.Helper()
+	if rec.Code != status {
+		t.Fatalf("status = %d, want %d; body: %s", rec.Code, status, rec.Body.String())
+	}
+	if v != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("decoding %s: %v", rec.Body.String(), err)
+		}
+	}
+}
@@ -0,0 +1,382 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"todo-list-app/internal/middleware"
+	"todo-list-app/internal/models"
+	"todo-list-app/internal/store"
+)
+
+// validateRole checks that role is one of the project roles
+func validateRole(role string) error {
+	if _, ok := roleRanks[role]; !ok {
+		return fmt.Errorf("role must be one of viewer, editor, owner")
+	}
+	return nil
+}
+
+// GetMembers lists everyone with access to a project
+func (h *ProjectHandler) GetMembers(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	projectID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := authorizeProject(w, h.projects, userID, projectID, models.RoleViewer); !ok {
+		return
+	}
+
+	members, err := h.projects.ListMembers(projectID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(members)
+}
+
+// UpdateMember changes a member's role. Only owners can do this, and the
+// project's creator always stays an owner.
+func (h *ProjectHandler) UpdateMember(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	projectID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+	memberID, err := strconv.Atoi(mux.Vars(r)["userID"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validateRole(req.Role); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	project, ok := authorizeProject(w, h.projects, userID, projectID, models.RoleOwner)
+	if !ok {
+		return
+	}
+	if memberID == project.UserID {
+		writeJSONError(w, http.StatusBadRequest, "The project's creator is always an owner")
+		return
+	}
+
+	if err := h.projects.SetMemberRole(projectID, memberID, req.Role); err == store.ErrNotFound {
+		http.Error(w, "Member not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to update member", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Member updated successfully"})
+}
+
+// DeleteMember removes a member from a project. Owners can remove anyone
+// but the creator; any member can remove themselves to leave the project.
+func (h *ProjectHandler) DeleteMember(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	projectID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+	memberID, err := strconv.Atoi(mux.Vars(r)["userID"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	need := models.RoleOwner
+	if memberID == userID {
+		need = models.RoleViewer
+	}
+	project, ok := authorizeProject(w, h.projects, userID, projectID, need)
+	if !ok {
+		return
+	}
+	if memberID == project.UserID {
+		writeJSONError(w, http.StatusBadRequest, "The project's creator cannot be removed; delete the project instead")
+		return
+	}
+
+	if err := h.projects.DeleteMember(projectID, memberID, userID); err == store.ErrNotFound {
+		http.Error(w, "Member not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to remove member", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Member removed successfully"})
+}
+
+// GetProjectInvitations lists a project's open invitations for its owners
+func (h *ProjectHandler) GetProjectInvitations(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	projectID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := authorizeProject(w, h.projects, userID, projectID, models.RoleOwner); !ok {
+		return
+	}
+
+	invitations, err := h.projects.ListProjectInvitations(projectID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invitations)
+}
+
+// CreateInvitation invites an email address to a project. Whoever has or
+// later registers an account with that address can accept it.
+func (h *ProjectHandler) CreateInvitation(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	projectID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.InviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if err := ValidateEmail(email); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validateRole(req.Role); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if _, ok := authorizeProject(w, h.projects, userID, projectID, models.RoleOwner); !ok {
+		return
+	}
+
+	members, err := h.projects.ListMembers(projectID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	for _, member := range members {
+		if strings.EqualFold(member.Email, email) {
+			writeJSONError(w, http.StatusConflict, "User is already a member of this project")
+			return
+		}
+	}
+
+	invitation := models.ProjectInvitation{ProjectID: projectID, Email: email, Role: req.Role, InvitedBy: userID}
+	if err := h.projects.CreateInvitation(&invitation); err == store.ErrConflict {
+		writeJSONError(w, http.StatusConflict, "This email address is already invited")
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to create invitation", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(invitation)
+}
+
+// DeleteProjectInvitation revokes an open invitation to a project
+func (h *ProjectHandler) DeleteProjectInvitation(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	projectID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+	invitationID, err := strconv.Atoi(mux.Vars(r)["invitationID"])
+	if err != nil {
+		http.Error(w, "Invalid invitation ID", http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := authorizeProject(w, h.projects, userID, projectID, models.RoleOwner); !ok {
+		return
+	}
+
+	invitation, err := h.projects.GetInvitation(invitationID)
+	if err == store.ErrNotFound || (err == nil && invitation.ProjectID != projectID) {
+		http.Error(w, "Invitation not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.projects.DeleteInvitation(invitationID); err != nil && err != store.ErrNotFound {
+		http.Error(w, "Failed to revoke invitation", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Invitation revoked successfully"})
+}
+
+// userInvitation loads an invitation addressed to the user's email address.
+// On failure the error response has been written.
+func (h *ProjectHandler) userInvitation(w http.ResponseWriter, r *http.Request, userID int) (models.ProjectInvitation, bool) {
+	invitationID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid invitation ID", http.StatusBadRequest)
+		return models.ProjectInvitation{}, false
+	}
+
+	user, err := h.users.GetUserByID(userID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return models.ProjectInvitation{}, false
+	}
+
+	invitation, err := h.projects.GetInvitation(invitationID)
+	if err == store.ErrNotFound || (err == nil && invitation.Email != strings.ToLower(user.Email)) {
+		http.Error(w, "Invitation not found", http.StatusNotFound)
+		return invitation, false
+	} else if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return invitation, false
+	}
+	return invitation, true
+}
+
+// GetInvitations lists the open invitations sent to the user's email address
+func (h *ProjectHandler) GetInvitations(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := h.users.GetUserByID(userID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	invitations, err := h.projects.ListUserInvitations(strings.ToLower(user.Email))
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invitations)
+}
+
+// AcceptInvitation joins the project an invitation is for and returns it
+func (h *ProjectHandler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	invitation, ok := h.userInvitation(w, r, userID)
+	if !ok {
+		return
+	}
+
+	if err := h.projects.AcceptInvitation(invitation.ID, userID); err == store.ErrConflict {
+		writeJSONError(w, http.StatusConflict, "You are already a member of this project")
+		return
+	} else if err == store.ErrNotFound {
+		http.Error(w, "Invitation not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to accept invitation", http.StatusInternalServerError)
+		return
+	}
+
+	project, err := h.projects.GetProject(invitation.ProjectID, userID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(project)
+}
+
+// DeclineInvitation turns down an invitation
+func (h *ProjectHandler) DeclineInvitation(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	invitation, ok := h.userInvitation(w, r, userID)
+	if !ok {
+		return
+	}
+
+	if err := h.projects.DeleteInvitation(invitation.ID); err != nil && err != store.ErrNotFound {
+		http.Error(w, "Failed to decline invitation", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Invitation declined"})
+}
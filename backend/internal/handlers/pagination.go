@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"todo-list-app/internal/models"
+	"todo-list-app/internal/store"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// todoSortOrders lists the columns GET /api/todos can be ordered by, with
+// the order each one defaults to
+var todoSortOrders = map[string]string{
+	"priority":   "desc",
+	"created_at": "desc",
+	"updated_at": "desc",
+	"due_at":     "asc",
+	"title":      "asc",
+}
+
+// todoCursor is the decoded form of the opaque cursor handed to clients. It
+// records the sort it was issued for and the keyset position of the last
+// todo on the previous page.
+type todoCursor struct {
+	Sort  string `json:"s"`
+	Order string `json:"o"`
+	store.TodoCursor
+}
+
+// todoPage holds the parsed sort and pagination parameters of a list request
+type todoPage struct {
+	sort   store.TodoSort
+	order  string
+	limit  int
+	cursor *store.TodoCursor
+}
+
+// parseTodoPage reads sort, order, limit and cursor from the query string.
+// defaultSort is used when the request does not specify one.
+func parseTodoPage(sortName, order, limit, cursor, defaultSort string) (todoPage, error) {
+	if sortName == "" {
+		sortName = defaultSort
+	}
+	defaultOrder, ok := todoSortOrders[sortName]
+	if !ok {
+		return todoPage{}, fmt.Errorf("sort must be one of priority, created_at, updated_at, due_at, title")
+	}
+
+	if order == "" {
+		order = defaultOrder
+	}
+	if order != "asc" && order != "desc" {
+		return todoPage{}, fmt.Errorf("order must be one of asc, desc")
+	}
+
+	page := todoPage{sort: store.TodoSort(sortName), order: order, limit: defaultPageSize}
+
+	if limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > maxPageSize {
+			return todoPage{}, fmt.Errorf("limit must be between 1 and %d", maxPageSize)
+		}
+		page.limit = n
+	}
+
+	if cursor != "" {
+		decoded, err := decodeTodoCursor(cursor)
+		if err != nil {
+			return todoPage{}, fmt.Errorf("invalid cursor")
+		}
+		if decoded.Sort != sortName || decoded.Order != order {
+			return todoPage{}, fmt.Errorf("cursor was issued for a different sort order")
+		}
+		page.cursor = &decoded.TodoCursor
+	}
+
+	return page, nil
+}
+
+// apply copies the sort and pagination parameters onto a store query. One
+// extra todo is requested to learn whether another page follows.
+func (p todoPage) apply(q *store.TodoQuery) {
+	q.Sort = p.sort
+	q.Desc = p.order == "desc"
+	q.After = p.cursor
+	q.Limit = p.limit + 1
+}
+
+// result trims the todos fetched with apply to a page and sets the cursor
+// for the next one
+func (p todoPage) result(todos []models.Todo) models.TodoPage {
+	page := models.TodoPage{Todos: todos}
+	if len(todos) > p.limit {
+		page.Todos = todos[:p.limit]
+		next := encodeTodoCursor(todoCursor{
+			Sort:       string(p.sort),
+			Order:      p.order,
+			TodoCursor: store.CursorFor(p.sort, page.Todos[p.limit-1]),
+		})
+		page.NextCursor = &next
+	}
+	return page
+}
+
+func encodeTodoCursor(c todoCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeTodoCursor(s string) (*todoCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var c todoCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"todo-list-app/internal/middleware"
+	"todo-list-app/internal/models"
+)
+
+// requiredTodoFields are the fields a PUT must include, since leaving them
+// out would silently blank or zero them
+var requiredTodoFields = []string{"title", "description", "priority"}
+
+// missingFields returns the names in required that are absent or null in
+// fields, sorted
+func missingFields(fields map[string]json.RawMessage, required []string) []string {
+	var missing []string
+	for _, name := range required {
+		if value, ok := fields[name]; !ok || string(value) == "null" {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// validatePriority checks that a priority is low (1), medium (2) or high (3)
+func validatePriority(priority int) error {
+	if priority < 1 || priority > 3 {
+		return fmt.Errorf("priority must be 1, 2 or 3")
+	}
+	return nil
+}
+
+// todoRequest returns the update request that would leave a todo as it is.
+// Fields that applyTodoRequest keeps when nil are left nil.
+func todoRequest(todo models.Todo) models.UpdateTodoRequest {
+	req := models.UpdateTodoRequest{
+		Title:       todo.Title,
+		Description: todo.Description,
+		Priority:    todo.Priority,
+		AllDay:      todo.AllDay,
+	}
+	if todo.DueAt != nil {
+		due := todo.DueAt.UTC().Format(time.RFC3339)
+		req.DueAt = &due
+	}
+	return req
+}
+
+// mergeTodoPatch applies the members of a JSON merge patch to req. null
+// clears a nullable field: due_at, recurrence, timezone (back to UTC), tags,
+// project_id (back to the inbox), parent_id (to the top level) and
+// assignee_id. title, priority and all_day cannot be null, and description
+// is cleared to "".
+func mergeTodoPatch(req *models.UpdateTodoRequest, patch map[string]json.RawMessage) error {
+	for field, value := range patch {
+		null := string(value) == "null"
+		switch field {
+		case "title":
+			if null {
+				return fmt.Errorf("title cannot be null")
+			}
+			if json.Unmarshal(value, &req.Title) != nil {
+				return fmt.Errorf("title must be a string")
+			}
+		case "description":
+			req.Description = ""
+			if !null && json.Unmarshal(value, &req.Description) != nil {
+				return fmt.Errorf("description must be a string")
+			}
+		case "priority":
+			if null {
+				return fmt.Errorf("priority cannot be null")
+			}
+			if json.Unmarshal(value, &req.Priority) != nil {
+				return fmt.Errorf("priority must be an integer")
+			}
+		case "all_day":
+			if null {
+				return fmt.Errorf("all_day cannot be null")
+			}
+			if json.Unmarshal(value, &req.AllDay) != nil {
+				return fmt.Errorf("all_day must be a boolean")
+			}
+		case "due_at":
+			req.DueAt = nil
+			if !null && json.Unmarshal(value, &req.DueAt) != nil {
+				return fmt.Errorf("due_at must be a string")
+			}
+		case "recurrence":
+			rule := ""
+			if !null && json.Unmarshal(value, &rule) != nil {
+				return fmt.Errorf("recurrence must be a string")
+			}
+			req.Recurrence = &rule
+		case "timezone":
+			name := ""
+			if !null && json.Unmarshal(value, &name) != nil {
+				return fmt.Errorf("timezone must be a string")
+			}
+			req.Timezone = &name
+		case "tags":
+			req.Tags = []string{}
+			if !null && json.Unmarshal(value, &req.Tags) != nil {
+				return fmt.Errorf("tags must be a list of strings")
+			}
+		case "project_id", "parent_id", "assignee_id":
+			id := 0
+			if !null && json.Unmarshal(value, &id) != nil {
+				return fmt.Errorf("%s must be an integer", field)
+			}
+			switch field {
+			case "project_id":
+				req.ProjectID = &id
+			case "parent_id":
+				req.ParentID = &id
+			default:
+				req.AssigneeID = &id
+			}
+		default:
+			return fmt.Errorf("%s cannot be changed", field)
+		}
+	}
+	return nil
+}
+
+// PatchTodo updates a todo with a JSON merge patch (RFC 7396): only the
+// fields in the patch change and null clears a field. The result is
+// validated like a PUT.
+func (h *TodoHandler) PatchTodo(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	todoID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid todo ID", http.StatusBadRequest)
+		return
+	}
+
+	// A merge patch that is not an object would replace the whole todo
+	var patch map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil || patch == nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Check the user's access to the todo
+	todo, ok := h.authorizeTodo(w, userID, todoID, models.RoleEditor)
+	if !ok || !checkIfMatch(w, r, todo) {
+		return
+	}
+
+	req := todoRequest(todo)
+	if err := mergeTodoPatch(&req, patch); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	h.saveTodo(w, r, userID, todo, req)
+}
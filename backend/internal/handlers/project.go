@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"todo-list-app/internal/middleware"
+	"todo-list-app/internal/models"
+	"todo-list-app/internal/store"
+)
+
+const maxProjectNameLength = 100
+
+// projectColorRegex matches the #rrggbb colors projects can be given
+var projectColorRegex = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+type ProjectHandler struct {
+	projects store.ProjectStore
+	users    store.UserStore
+}
+
+// NewProjectHandler creates a new project handler
+func NewProjectHandler(projects store.ProjectStore, users store.UserStore) *ProjectHandler {
+	return &ProjectHandler{projects: projects, users: users}
+}
+
+// normalizeProjectName trims a project name and checks its length
+func normalizeProjectName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("project name is required")
+	}
+	if len([]rune(name)) > maxProjectNameLength {
+		return "", fmt.Errorf("project name must be at most %d characters", maxProjectNameLength)
+	}
+	return name, nil
+}
+
+// normalizeProjectColor lowercases a #rrggbb color; empty means no color
+func normalizeProjectColor(color string) (string, error) {
+	if color == "" {
+		return "", nil
+	}
+	if !projectColorRegex.MatchString(color) {
+		return "", fmt.Errorf("color must look like #1e90ff")
+	}
+	return strings.ToLower(color), nil
+}
+
+// GetProjects lists the user's projects in sort order. Archived projects
+// are only included with ?include_archived=true.
+func (h *ProjectHandler) GetProjects(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	includeArchived := false
+	if value := r.URL.Query().Get("include_archived"); value != "" {
+		var err error
+		if includeArchived, err = strconv.ParseBool(value); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "include_archived must be true or false")
+			return
+		}
+	}
+
+	projects, err := h.projects.ListProjects(userID, includeArchived)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(projects)
+}
+
+// CreateProject creates a new project for the authenticated user
+func (h *ProjectHandler) CreateProject(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.CreateProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	name, err := normalizeProjectName(req.Name)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	color, err := normalizeProjectColor(req.Color)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	project := models.Project{UserID: userID, Name: name, Color: color}
+	if req.SortOrder != nil {
+		project.SortOrder = *req.SortOrder
+	} else {
+		// New projects go after the existing ones
+		existing, err := h.projects.ListProjects(userID, true)
+		if err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		for _, p := range existing {
+			if p.SortOrder >= project.SortOrder {
+				project.SortOrder = p.SortOrder + 1
+			}
+		}
+	}
+
+	if err := h.projects.CreateProject(&project); err != nil {
+		http.Error(w, "Failed to create project", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(project)
+}
+
+// UpdateProject renames, recolors, archives or reorders a project. Only
+// owners can change a project.
+func (h *ProjectHandler) UpdateProject(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	projectID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	project, ok := authorizeProject(w, h.projects, userID, projectID, models.RoleOwner)
+	if !ok {
+		return
+	}
+
+	if project.Name, err = normalizeProjectName(req.Name); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Color != nil {
+		if project.Color, err = normalizeProjectColor(*req.Color); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if req.Archived != nil {
+		project.Archived = *req.Archived
+	}
+	if req.SortOrder != nil {
+		project.SortOrder = *req.SortOrder
+	}
+
+	if err := h.projects.UpdateProject(&project); err == store.ErrNotFound {
+		http.Error(w, "Project not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to update project", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(project)
+}
+
+// DeleteProject deletes a project, which only owners can do. ?todos=
+// decides what happens to its todos: inbox (the default) moves them to the
+// inbox of whoever created them, delete removes them too.
+func (h *ProjectHandler) DeleteProject(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	projectID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	var deleteTodos bool
+	switch r.URL.Query().Get("todos") {
+	case "", "inbox":
+	case "delete":
+		deleteTodos = true
+	default:
+		writeJSONError(w, http.StatusBadRequest, "todos must be one of inbox, delete")
+		return
+	}
+
+	if _, ok := authorizeProject(w, h.projects, userID, projectID, models.RoleOwner); !ok {
+		return
+	}
+
+	if err := h.projects.DeleteProject(projectID, userID, deleteTodos); err == store.ErrNotFound {
+		http.Error(w, "Project not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to delete project", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Project deleted successfully"})
+}
+
+// checkProject validates putting a todo into projectID, which needs the
+// editor role. Problems with the request come back with status 400,
+// database failures with status 500.
+func (h *TodoHandler) checkProject(userID, projectID int) (int, error) {
+	project, err := h.projects.GetProject(projectID, userID)
+	if err != nil && err != store.ErrNotFound {
+		return http.StatusInternalServerError, err
+	}
+	if err == store.ErrNotFound || !hasRole(project.Role, models.RoleEditor) {
+		return http.StatusBadRequest, fmt.Errorf("project_id does not refer to a project you can edit")
+	}
+	return http.StatusOK, nil
+}
+
+// parseProjectFilter reads the project query parameter of GetTodos: "inbox"
+// selects todos without a project, a number one project
+func parseProjectFilter(value string) (*int, error) {
+	if value == "inbox" {
+		inbox := 0
+		return &inbox, nil
+	}
+	projectID, err := strconv.Atoi(value)
+	if err != nil || projectID < 1 {
+		return nil, fmt.Errorf("project must be inbox or a project ID")
+	}
+	return &projectID, nil
+}
+
+// GetProjectTodos retrieves a page of a project's todos. It takes the same
+// filters and paging parameters as GetTodos.
+func (h *TodoHandler) GetProjectTodos(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	projectID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := authorizeProject(w, h.projects, userID, projectID, models.RoleViewer); !ok {
+		return
+	}
+
+	h.listTodos(w, r, store.TodoQuery{UserID: userID, Project: &projectID})
+}
@@ -0,0 +1,460 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+
+	"todo-list-app/internal/middleware"
+	"todo-list-app/internal/models"
+	"todo-list-app/internal/store"
+)
+
+// maxSyncOperations is how many operations one push may carry
+const maxSyncOperations = 500
+
+// pushItem is one operation of a push and its result so far
+type pushItem struct {
+	result models.SyncPushResult
+	target *pushTodo
+}
+
+// fail marks the operation as failed with an HTTP status and message
+func (item *pushItem) fail(status int, message string) {
+	item.result.Status = status
+	item.result.Error = message
+}
+
+// pushTodo is the plan for one todo a push touches: the todo as it was
+// loaded and as the operations so far leave it. All operations on a todo
+// are saved as a single change, planned in bulk.
+type pushTodo struct {
+	original models.Todo
+	todo     models.Todo
+	create   bool
+	delete   bool
+	items    []*pushItem
+	bulk     bulkItem
+}
+
+// pushPlan collects the todos a push touches, in the order it first
+// touches them
+type pushPlan struct {
+	userID int
+	now    time.Time
+	todos  []*pushTodo
+	byID   map[int]*pushTodo
+}
+
+// popCompleted takes the completed flag, which a merge patch cannot set,
+// out of the fields of an operation
+func popCompleted(fields map[string]json.RawMessage) (*bool, error) {
+	value, ok := fields["completed"]
+	if !ok {
+		return nil, nil
+	}
+	delete(fields, "completed")
+	var completed bool
+	if json.Unmarshal(value, &completed) != nil {
+		return nil, fmt.Errorf("completed must be a boolean")
+	}
+	return &completed, nil
+}
+
+// fieldTimes returns when each field of a todo was last changed by a
+// revision after the given version. Every write that changes a todo,
+// including the subtasks, assignments and tags it changes on the side,
+// records its revisions in the same transaction, so none are missing.
+func (h *TodoHandler) fieldTimes(todoID, version int) (map[string]time.Time, error) {
+	revisions, err := h.revisions.ListRevisions(todoID)
+	if err != nil {
+		return nil, err
+	}
+	times := map[string]time.Time{}
+	for _, revision := range revisions {
+		if revision.Version <= version {
+			continue
+		}
+		for field := range revision.Changes {
+			if revision.CreatedAt.After(times[field]) {
+				times[field] = revision.CreatedAt
+			}
+		}
+	}
+	return times, nil
+}
+
+// clientWins reports whether a client's change made at clientTime is later
+// than a change the server recorded at serverTime. History keeps whole
+// seconds, so the client's time is cut to the second as well, and a tie
+// goes to the server.
+func clientWins(clientTime, serverTime time.Time) bool {
+	return clientTime.Truncate(time.Second).After(serverTime)
+}
+
+// planSyncCreate validates a create operation like POST /api/todos
+func (h *TodoHandler) planSyncCreate(plan *pushPlan, op models.SyncOperation, item *pushItem) {
+	fields := maps.Clone(op.Fields)
+	completed, err := popCompleted(fields)
+	if err != nil {
+		item.fail(http.StatusBadRequest, err.Error())
+		return
+	}
+	var req models.CreateTodoRequest
+	if encoded, err := json.Marshal(fields); err != nil || json.Unmarshal(encoded, &req) != nil {
+		item.fail(http.StatusBadRequest, "Invalid fields")
+		return
+	}
+	todo, status, err := h.newTodo(plan.userID, req)
+	if err != nil {
+		if status == http.StatusInternalServerError {
+			err = fmt.Errorf("Database error")
+		}
+		item.fail(status, err.Error())
+		return
+	}
+	todo.Completed = completed != nil && *completed
+
+	target := &pushTodo{todo: todo, create: true}
+	plan.todos = append(plan.todos, target)
+	item.target = target
+}
+
+// syncTarget returns the plan for the todo an update or delete names,
+// loading the todo and checking the user may edit it the first time the
+// push touches it
+func (h *TodoHandler) syncTarget(plan *pushPlan, id int, item *pushItem) *pushTodo {
+	target, ok := plan.byID[id]
+	if !ok {
+		todo, err := h.todos.GetTodo(id)
+		if err == store.ErrNotFound {
+			item.fail(http.StatusNotFound, "Todo not found")
+			return nil
+		} else if err != nil {
+			item.fail(http.StatusInternalServerError, "Database error")
+			return nil
+		}
+		role, err := todoRole(h.projects, plan.userID, todo)
+		if err != nil {
+			item.fail(http.StatusInternalServerError, "Database error")
+			return nil
+		} else if role == "" {
+			item.fail(http.StatusNotFound, "Todo not found")
+			return nil
+		} else if !hasRole(role, models.RoleEditor) {
+			item.fail(http.StatusForbidden, "Unauthorized")
+			return nil
+		}
+		target = &pushTodo{original: todo, todo: todo}
+		plan.byID[id] = target
+		plan.todos = append(plan.todos, target)
+	}
+	if target.delete {
+		item.fail(http.StatusNotFound, "Todo not found")
+		return nil
+	}
+	return target
+}
+
+// planSyncChange resolves an update or delete against what changed on the
+// server since the client's base version. A field changed on both sides
+// keeps the later change.
+func (h *TodoHandler) planSyncChange(plan *pushPlan, op models.SyncOperation, item *pushItem) {
+	switch {
+	case op.ID <= 0:
+		item.fail(http.StatusBadRequest, "id is required")
+		return
+	case op.BaseVersion <= 0:
+		item.fail(http.StatusBadRequest, "base_version is required")
+		return
+	case op.ClientTime.IsZero():
+		item.fail(http.StatusBadRequest, "client_time is required")
+		return
+	case op.Op == models.SyncUpdate && len(op.Fields) == 0:
+		item.fail(http.StatusBadRequest, "fields is required")
+		return
+	}
+
+	target := h.syncTarget(plan, op.ID, item)
+	if target == nil {
+		return
+	}
+	if op.BaseVersion > target.original.Version {
+		item.fail(http.StatusBadRequest, "base_version is newer than the todo")
+		return
+	}
+
+	// A clock running ahead must not win every conflict
+	clientTime := op.ClientTime
+	if clientTime.After(plan.now) {
+		clientTime = plan.now
+	}
+	times := map[string]time.Time{}
+	if op.BaseVersion != target.original.Version {
+		var err error
+		if times, err = h.fieldTimes(op.ID, op.BaseVersion); err != nil {
+			item.fail(http.StatusInternalServerError, "Database error")
+			return
+		}
+	}
+	server, err := store.StateFields(store.StateOf(target.original))
+	if err != nil {
+		item.fail(http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	if op.Op == models.SyncDelete {
+		var latest time.Time
+		for _, changed := range times {
+			if changed.After(latest) {
+				latest = changed
+			}
+		}
+		if latest.IsZero() || clientWins(clientTime, latest) {
+			target.delete = true
+		}
+		if !latest.IsZero() {
+			conflict := models.SyncConflict{
+				Field:       "deleted",
+				ClientValue: json.RawMessage("true"),
+				ServerValue: json.RawMessage("false"),
+				ServerTime:  latest,
+				Winner:      models.SyncServerWins,
+			}
+			if target.delete {
+				conflict.Winner = models.SyncClientWins
+			}
+			item.result.Conflicts = append(item.result.Conflicts, conflict)
+		}
+		item.target = target
+		return
+	}
+
+	fields := maps.Clone(op.Fields)
+	for _, field := range slices.Sorted(maps.Keys(fields)) {
+		changed, ok := times[field]
+		if !ok || bytes.Equal(fields[field], server[field]) {
+			continue
+		}
+		conflict := models.SyncConflict{
+			Field:       field,
+			ClientValue: fields[field],
+			ServerValue: server[field],
+			ServerTime:  changed,
+			Winner:      models.SyncClientWins,
+		}
+		if !clientWins(clientTime, changed) {
+			conflict.Winner = models.SyncServerWins
+			delete(fields, field)
+		}
+		item.result.Conflicts = append(item.result.Conflicts, conflict)
+	}
+
+	completed, err := popCompleted(fields)
+	if err != nil {
+		item.fail(http.StatusBadRequest, err.Error())
+		return
+	}
+	req := todoRequest(target.todo)
+	if err := mergeTodoPatch(&req, fields); err != nil {
+		item.fail(http.StatusBadRequest, err.Error())
+		return
+	}
+	tags := target.todo.Tags
+	if status, err := h.applyTodoRequest(plan.userID, &target.todo, req); err != nil {
+		if status == http.StatusInternalServerError {
+			err = fmt.Errorf("Database error")
+		}
+		item.fail(status, err.Error())
+		return
+	}
+	if req.Tags == nil {
+		target.todo.Tags = tags
+	}
+	if completed != nil {
+		target.todo.Completed = *completed
+	}
+	item.target = target
+}
+
+// planSyncSave turns the plan for a todo into the change that saves it,
+// leaving it nil when the operations changed nothing
+func (h *TodoHandler) planSyncSave(target *pushTodo) error {
+	item := &target.bulk
+	item.result.ID = target.original.ID
+	item.before = store.StateOf(target.original)
+	change := store.TodoChange{Todo: target.todo, Create: target.create, Delete: target.delete}
+
+	switch {
+	case target.create:
+	case target.delete:
+		change.Todo = target.original
+		item.change = &change
+		return nil
+	default:
+		if change.Todo.Tags == nil {
+			change.Todo.Tags = []string{}
+		}
+		fields := store.StateOf(change.Todo)
+		fields.Completed = item.before.Completed
+		changes, err := store.DiffStates(&item.before, fields)
+		if err != nil {
+			return err
+		}
+		change.Update = len(changes) > 0
+	}
+
+	if target.todo.Completed != target.original.Completed {
+		completed := target.todo.Completed
+		change.Completed = &completed
+		if completed {
+			next, err := nextOccurrence(change.Todo)
+			if err != nil {
+				return err
+			}
+			change.Next = next
+		}
+	}
+	if change.Create || change.Update || change.Completed != nil {
+		item.change = &change
+	}
+	return nil
+}
+
+// PushChanges applies the operations a client made while offline, in one
+// transaction: either all of them apply or, with status 409, none. Updates
+// and deletes are resolved against the changes other clients made since
+// the client's base version, field by field: the later change wins, judged
+// to the second by the client's timestamp and the todo's history, with ties
+// going to the server, and each field changed on both sides is reported as
+// a conflict.
+func (h *TodoHandler) PushChanges(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.SyncPushRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.Operations) == 0 {
+		writeJSONError(w, http.StatusBadRequest, "operations is required")
+		return
+	}
+	if len(req.Operations) > maxSyncOperations {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("At most %d operations can be pushed at once", maxSyncOperations))
+		return
+	}
+
+	// Plan every operation in order, each building on the ones before it
+	plan := &pushPlan{userID: userID, now: time.Now(), byID: map[int]*pushTodo{}}
+	items := make([]*pushItem, len(req.Operations))
+	failed := false
+	for i, op := range req.Operations {
+		item := &pushItem{result: models.SyncPushResult{Op: op.Op, ID: op.ID, ClientID: op.ClientID}}
+		items[i] = item
+		switch op.Op {
+		case models.SyncCreate:
+			item.result.ID = 0
+			h.planSyncCreate(plan, op, item)
+		case models.SyncUpdate, models.SyncDelete:
+			h.planSyncChange(plan, op, item)
+		default:
+			item.fail(http.StatusBadRequest, "op must be one of create, update, delete")
+		}
+		if item.target == nil {
+			failed = true
+			continue
+		}
+		item.target.items = append(item.target.items, item)
+	}
+
+	var planned []*pushTodo
+	var changes []store.TodoChange
+	if !failed {
+		bulkItems := make([]*bulkItem, len(plan.todos))
+		for i, target := range plan.todos {
+			if err := h.planSyncSave(target); err != nil {
+				http.Error(w, "Database error", http.StatusInternalServerError)
+				return
+			}
+			bulkItems[i] = &target.bulk
+		}
+		// A subtask deleted along with its parent is gone once the
+		// parent is
+		h.followBulkAncestors(bulkItems)
+		for _, target := range plan.todos {
+			if target.bulk.result.Status == http.StatusInternalServerError {
+				http.Error(w, "Database error", http.StatusInternalServerError)
+				return
+			}
+			if target.bulk.change != nil {
+				planned = append(planned, target)
+				changes = append(changes, *target.bulk.change)
+			}
+		}
+	}
+
+	var results []store.TodoChangeResult
+	if !failed && len(changes) > 0 {
+		var err error
+		if results, err = h.todos.ApplyTodoChanges(userID, changes, true); err != nil {
+			http.Error(w, "Failed to apply sync operations", http.StatusInternalServerError)
+			return
+		}
+		for i, result := range results {
+			if result.Err == nil {
+				continue
+			}
+			failed = true
+			for _, item := range planned[i].items {
+				if result.Err == store.ErrNotFound {
+					item.fail(http.StatusNotFound, "Todo not found")
+				} else {
+					item.fail(http.StatusConflict, todoModified)
+				}
+			}
+		}
+	}
+
+	if !failed {
+		for i, target := range planned {
+			result := results[i]
+			if !target.delete {
+				target.todo = result.Todo
+			}
+			last := target.items[len(target.items)-1]
+			last.result.NextOccurrence = result.Next
+		}
+	}
+
+	response := models.SyncPushResponse{Applied: !failed, Results: []models.SyncPushResult{}}
+	for _, item := range items {
+		switch {
+		case item.result.Status != 0:
+		case failed:
+			item.fail(http.StatusFailedDependency, "Not applied because another operation failed")
+		default:
+			item.result.Status = http.StatusOK
+			if !item.target.delete {
+				todo := item.target.todo
+				item.result.ID = todo.ID
+				item.result.Todo = &todo
+			}
+		}
+		response.Results = append(response.Results, item.result)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if failed {
+		w.WriteHeader(http.StatusConflict)
+	}
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"todo-list-app/internal/middleware"
+	"todo-list-app/internal/models"
+	"todo-list-app/internal/recurrence"
+)
+
+const (
+	defaultOccurrencePreview = 5
+	maxOccurrencePreview     = 50
+)
+
+// parseRecurrence validates the recurrence field of a todo request and
+// returns the rule in canonical form; an empty rule means no recurrence
+func parseRecurrence(raw string) (*string, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	rule, err := recurrence.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recurrence: %v", err)
+	}
+	canonical := rule.String()
+	return &canonical, nil
+}
+
+// parseTimezone validates the timezone field of a todo request, an IANA
+// name such as "Asia/Seoul", and returns it in canonical form; an empty
+// name means UTC
+func parseTimezone(raw string) (*string, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	location, err := time.LoadLocation(raw)
+	if err != nil || raw == "Local" {
+		return nil, fmt.Errorf("invalid timezone: unknown time zone %q", raw)
+	}
+	name := location.String()
+	return &name, nil
+}
+
+// recurrenceStart returns the due date of a repeating todo in the time zone
+// its rule is expanded in, so that weekdays and days of the month are those
+// of the todo's calendar. All-day due dates are midnight UTC of their date
+// and stay in UTC.
+func recurrenceStart(todo models.Todo) (time.Time, error) {
+	if todo.Timezone == nil || todo.AllDay {
+		return todo.DueAt.UTC(), nil
+	}
+	location, err := time.LoadLocation(*todo.Timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return todo.DueAt.In(location), nil
+}
+
+// nextOccurrence builds the todo that follows a completed occurrence of a
+// repeating todo, or returns nil when the series is over
+func nextOccurrence(todo models.Todo) (*models.Todo, error) {
+	if todo.Recurrence == nil || todo.DueAt == nil {
+		return nil, nil
+	}
+	rule, err := recurrence.Parse(*todo.Recurrence)
+	if err != nil {
+		return nil, err
+	}
+
+	start, err := recurrenceStart(todo)
+	if err != nil {
+		return nil, err
+	}
+
+	next, rest, ok := rule.Next(start)
+	if !ok {
+		return nil, nil
+	}
+	dueAt := next.UTC()
+	restRule := rest.String()
+
+	return &models.Todo{
+		UserID:      todo.UserID,
+		ParentID:    todo.ParentID,
+		ProjectID:   todo.ProjectID,
+		AssigneeID:  todo.AssigneeID,
+		AssignedBy:  todo.AssignedBy,
+		AssignedAt:  todo.AssignedAt,
+		Title:       todo.Title,
+		Description: todo.Description,
+		Priority:    todo.Priority,
+		DueAt:       &dueAt,
+		AllDay:      todo.AllDay,
+		Recurrence:  &restRule,
+		Timezone:    todo.Timezone,
+		Tags:        append([]string{}, todo.Tags...),
+	}, nil
+}
+
+// GetOccurrences previews the next due dates of a repeating todo.
+// ?count= sets how many, up to 50.
+func (h *TodoHandler) GetOccurrences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	todoID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid todo ID", http.StatusBadRequest)
+		return
+	}
+
+	count := defaultOccurrencePreview
+	if raw := r.URL.Query().Get("count"); raw != "" {
+		count, err = strconv.Atoi(raw)
+		if err != nil || count < 1 || count > maxOccurrencePreview {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("count must be between 1 and %d", maxOccurrencePreview))
+			return
+		}
+	}
+
+	// Check the user's access to the todo
+	todo, ok := h.authorizeTodo(w, userID, todoID, models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	if todo.Recurrence == nil || todo.DueAt == nil {
+		writeJSONError(w, http.StatusBadRequest, "Todo does not repeat")
+		return
+	}
+	rule, err := recurrence.Parse(*todo.Recurrence)
+	if err != nil {
+		http.Error(w, "Stored recurrence rule is invalid", http.StatusInternalServerError)
+		return
+	}
+	start, err := recurrenceStart(todo)
+	if err != nil {
+		http.Error(w, "Stored timezone is invalid", http.StatusInternalServerError)
+		return
+	}
+
+	occurrences := rule.Preview(start, count)
+	for i := range occurrences {
+		occurrences[i] = occurrences[i].UTC()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.TodoOccurrences{
+		Recurrence:  *todo.Recurrence,
+		Timezone:    todo.Timezone,
+		Occurrences: occurrences,
+	})
+}
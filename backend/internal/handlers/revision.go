@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"todo-list-app/internal/middleware"
+	"todo-list-app/internal/models"
+	"todo-list-app/internal/store"
+)
+
+// GetHistory lists a todo's revisions, oldest first
+func (h *TodoHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	todoID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid todo ID", http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := h.authorizeTodo(w, userID, todoID, models.RoleViewer); !ok {
+		return
+	}
+
+	revisions, err := h.revisions.ListRevisions(todoID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(revisions)
+}
+
+// RevertTodo restores the content a todo had at ?revision=N: its title,
+// description, priority, due date, recurrence and its time zone, tags and
+// completed flag. Where the todo lives and who it is assigned to are left
+// alone. The revert itself is recorded as a new revision.
+func (h *TodoHandler) RevertTodo(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	todoID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid todo ID", http.StatusBadRequest)
+		return
+	}
+
+	number, err := strconv.Atoi(r.URL.Query().Get("revision"))
+	if err != nil || number < 1 {
+		writeJSONError(w, http.StatusBadRequest, "revision must be a positive integer")
+		return
+	}
+
+	todo, ok := h.authorizeTodo(w, userID, todoID, models.RoleEditor)
+	if !ok || !checkIfMatch(w, r, todo) {
+		return
+	}
+
+	revision, err := h.revisions.GetRevision(todoID, number)
+	if err == store.ErrNotFound {
+		http.Error(w, "Revision not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	target := revision.State
+	todo.Title = target.Title
+	todo.Description = target.Description
+	todo.Priority = target.Priority
+	todo.DueAt = target.DueAt
+	todo.AllDay = target.AllDay
+	todo.Recurrence = target.Recurrence
+	todo.Timezone = target.Timezone
+	todo.Tags = target.Tags
+	if todo.Tags == nil {
+		todo.Tags = []string{}
+	}
+	change := store.TodoChange{Todo: todo, Update: true, RevertedTo: &revision.Revision}
+	if todo.Completed != target.Completed {
+		change.Completed = &target.Completed
+	}
+	result, err := h.applyTodoChange(userID, change)
+	if err == store.ErrConflict {
+		writeTodoModified(w, r)
+		return
+	} else if err == store.ErrNotFound {
+		http.Error(w, "Todo not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to revert todo", http.StatusInternalServerError)
+		return
+	}
+
+	writeTodo(w, http.StatusOK, result.Todo)
+}
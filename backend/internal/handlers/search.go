@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"todo-list-app/internal/middleware"
+	"todo-list-app/internal/store"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// SearchTodos runs a full-text search over the user's todo titles and
+// descriptions. Bare words are prefix-matched and "quoted text" is matched as
+// a phrase; all parts must match. Results are ranked by relevance, with title hits
+// weighted above description hits and higher priority todos boosted.
+func (h *TodoHandler) SearchTodos(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	terms := parseSearchTerms(r.URL.Query().Get("q"))
+	if len(terms) == 0 {
+		writeJSONError(w, http.StatusBadRequest, "q is required")
+		return
+	}
+
+	limit := defaultSearchLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxSearchLimit {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxSearchLimit))
+			return
+		}
+		limit = n
+	}
+
+	results, err := h.todos.SearchTodos(userID, terms, limit)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+// parseSearchTerms splits a search string into words and "quoted phrases".
+// An unterminated quote runs to the end of the input.
+func parseSearchTerms(q string) []store.SearchTerm {
+	var terms []store.SearchTerm
+	for {
+		q = strings.TrimSpace(q)
+		if q == "" {
+			return terms
+		}
+
+		if q[0] == '"' {
+			end := strings.IndexByte(q[1:], '"')
+			if end < 0 {
+				end = len(q) - 1
+			}
+			if text := strings.TrimSpace(q[1 : end+1]); text != "" {
+				terms = append(terms, store.SearchTerm{Text: text, Phrase: true})
+			}
+			q = q[min(end+2, len(q)):]
+			continue
+		}
+
+		end := strings.IndexAny(q, " \t\n\"")
+		if end < 0 {
+			end = len(q)
+		}
+		if text := strings.TrimRight(q[:end], "*"); text != "" {
+			terms = append(terms, store.SearchTerm{Text: text})
+		}
+		q = q[end:]
+	}
+}
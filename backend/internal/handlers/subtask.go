@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"todo-list-app/internal/middleware"
+	"todo-list-app/internal/models"
+	"todo-list-app/internal/store"
+)
+
+// maxTodoDepth is how many levels a todo tree may have, counting the
+// top-level todo
+const maxTodoDepth = 3
+
+// subtaskMode decides what completing a todo does to its open subtasks
+type subtaskMode string
+
+const (
+	// subtasksBlock refuses to complete a todo while a direct subtask is open
+	subtasksBlock subtaskMode = "block"
+	// subtasksCascade completes all subtasks along with the todo
+	subtasksCascade subtaskMode = "cascade"
+	// subtasksIndependent completes the todo and leaves its subtasks alone
+	subtasksIndependent subtaskMode = "independent"
+)
+
+// openSubtasksError is the error for completing a todo with open subtasks
+// in block mode
+func openSubtasksError(open int) string {
+	return fmt.Sprintf("Todo has %d open subtask(s); complete them first or pass subtasks=cascade", open)
+}
+
+// parseSubtaskMode reads the subtasks query parameter of ToggleTodo or the
+// subtasks field of a bulk complete
+func parseSubtaskMode(mode string) (subtaskMode, error) {
+	switch subtaskMode(mode) {
+	case "":
+		return subtasksBlock, nil
+	case subtasksBlock, subtasksCascade, subtasksIndependent:
+		return subtaskMode(mode), nil
+	default:
+		return "", fmt.Errorf("subtasks must be one of block, cascade, independent")
+	}
+}
+
+// checkParent validates making a todo a subtask of parentID and returns
+// the parent; todoID is 0 for a todo that is being created. Problems with
+// the request come back with status 400, database failures with status 500.
+func (h *TodoHandler) checkParent(userID, todoID, parentID int) (models.Todo, int, error) {
+	parent, err := h.todos.GetTodo(parentID)
+	if err != nil && err != store.ErrNotFound {
+		return parent, http.StatusInternalServerError, err
+	}
+	role := ""
+	if err == nil {
+		if role, err = todoRole(h.projects, userID, parent); err != nil {
+			return parent, http.StatusInternalServerError, err
+		}
+	}
+	if !hasRole(role, models.RoleEditor) {
+		return parent, http.StatusBadRequest, fmt.Errorf("parent_id does not refer to a todo you can edit")
+	}
+
+	ancestors, err := h.todos.GetAncestorIDs(parentID)
+	if err != nil {
+		return parent, http.StatusInternalServerError, err
+	}
+
+	height := 0
+	if todoID != 0 {
+		if parentID == todoID || slices.Contains(ancestors, todoID) {
+			return parent, http.StatusBadRequest, fmt.Errorf("a todo cannot be moved under itself or one of its subtasks")
+		}
+		if height, err = h.todos.SubtreeHeight(todoID); err != nil {
+			return parent, http.StatusInternalServerError, err
+		}
+	}
+
+	// The parent sits at depth len(ancestors)+1 and the moved tree adds
+	// its own height below the new subtask
+	if len(ancestors)+2+height > maxTodoDepth {
+		return parent, http.StatusBadRequest, fmt.Errorf("subtasks can be nested at most %d levels deep", maxTodoDepth)
+	}
+	return parent, http.StatusOK, nil
+}
+
+// sameID reports whether two optional IDs are equal
+func sameID(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// writeParentError reports an error returned by checkParent, checkProject
+// or checkAssignee
+func writeParentError(w http.ResponseWriter, status int, err error) {
+	if status == http.StatusInternalServerError {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	writeJSONError(w, status, err.Error())
+}
+
+// GetSubtasks lists the direct subtasks of a todo, oldest first
+func (h *TodoHandler) GetSubtasks(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	todoID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid todo ID", http.StatusBadRequest)
+		return
+	}
+
+	// Check the user's access to the todo
+	if _, ok := h.authorizeTodo(w, userID, todoID, models.RoleViewer); !ok {
+		return
+	}
+
+	subtasks, err := h.todos.ListSubtasks(todoID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subtasks)
+}
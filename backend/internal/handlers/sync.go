@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"todo-list-app/internal/middleware"
+	"todo-list-app/internal/models"
+)
+
+const (
+	defaultSyncLimit = 500
+	maxSyncLimit     = 1000
+)
+
+// syncToken is the decoded form of the opaque change token handed to
+// clients: how far into the user's change feed the client has read
+type syncToken struct {
+	UserID int `json:"u"`
+	Seq    int `json:"s"`
+}
+
+func encodeSyncToken(t syncToken) string {
+	data, _ := json.Marshal(t)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeSyncToken(s string) (syncToken, error) {
+	var t syncToken
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return t, err
+	}
+	err = json.Unmarshal(data, &t)
+	return t, err
+}
+
+// GetChanges returns the todos that changed since a change token, for
+// clients that keep a local copy. Without a token it returns every todo the
+// user can see, as created, along with a token to continue from.
+func (h *TodoHandler) GetChanges(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	since := 0
+	if value := r.URL.Query().Get("since"); value != "" {
+		token, err := decodeSyncToken(value)
+		if err != nil || token.UserID != userID || token.Seq < 0 {
+			writeJSONError(w, http.StatusBadRequest, "Invalid sync token")
+			return
+		}
+		since = token.Seq
+	}
+
+	limit := defaultSyncLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxSyncLimit {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxSyncLimit))
+			return
+		}
+		limit = n
+	}
+
+	// One extra entry tells whether another page follows
+	changes, err := h.sync.ListSyncChanges(userID, since, limit+1)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.SyncResponse{Created: []models.Todo{}, Updated: []models.Todo{}, Deleted: []int{}}
+	if len(changes) > limit {
+		changes, response.HasMore = changes[:limit], true
+	}
+	seq := since
+	for _, change := range changes {
+		seq = change.Seq
+		switch {
+		case change.Deleted:
+			// A todo that appeared after the token was never sent
+			if change.CreatedSeq <= since {
+				response.Deleted = append(response.Deleted, change.Todo.ID)
+			}
+		case change.CreatedSeq > since:
+			response.Created = append(response.Created, change.Todo)
+		default:
+			response.Updated = append(response.Updated, change.Todo)
+		}
+	}
+	response.Token = encodeSyncToken(syncToken{UserID: userID, Seq: seq})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
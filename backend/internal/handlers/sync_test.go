@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"todo-list-app/internal/models"
+)
+
+func TestSyncFeed(t *testing.T) {
+	ts := newTestServer(t)
+	token := ts.addUser("sync@example.com")
+	other := ts.addUser("elsewhere@example.com")
+
+	var kept, edited, deleted models.Todo
+	expect(t, ts.do(token, "POST", "/api/todos", `{"title":"Kept"}`), http.StatusCreated, &kept)
+	expect(t, ts.do(token, "POST", "/api/todos", `{"title":"Edited"}`), http.StatusCreated, &edited)
+	expect(t, ts.do(token, "POST", "/api/todos", `{"title":"Deleted"}`), http.StatusCreated, &deleted)
+	expect(t, ts.do(other, "POST", "/api/todos", `{"title":"Not mine"}`), http.StatusCreated, nil)
+
+	var initial models.SyncResponse
+	expect(t, ts.do(token, "GET", "/api/sync", ""), http.StatusOK, &initial)
+	if len(initial.Created) != 3 || len(initial.Updated) != 0 || len(initial.Deleted) != 0 || initial.HasMore {
+		t.Fatalf("initial sync = %+v", initial)
+	}
+
+	expect(t, ts.do(token, "PATCH", fmt.Sprintf("/api/todos/%d", edited.ID), `{"priority":3}`), http.StatusOK, nil)
+	expect(t, ts.do(token, "DELETE", fmt.Sprintf("/api/todos/%d", deleted.ID), ""), http.StatusOK, nil)
+	var added models.Todo
+	expect(t, ts.do(token, "POST", "/api/todos", `{"title":"Added"}`), http.StatusCreated, &added)
+
+	var changes models.SyncResponse
+	expect(t, ts.do(token, "GET", "/api/sync?since="+initial.Token, ""), http.StatusOK, &changes)
+	if len(changes.Created) != 1 || changes.Created[0].ID != added.ID {
+		t.Errorf("created = %+v", changes.Created)
+	}
+	if len(changes.Updated) != 1 || changes.Updated[0].ID != edited.ID || changes.Updated[0].Priority != 3 {
+		t.Errorf("updated = %+v", changes.Updated)
+	}
+	if fmt.Sprint(changes.Deleted) != fmt.Sprint([]int{deleted.ID}) {
+		t.Errorf("deleted = %v, want [%d]", changes.Deleted, deleted.ID)
+	}
+
+	var caughtUp models.SyncResponse
+	expect(t, ts.do(token, "GET", "/api/sync?since="+changes.Token, ""), http.StatusOK, &caughtUp)
+	if len(caughtUp.Created)+len(caughtUp.Updated)+len(caughtUp.Deleted) != 0 {
+		t.Errorf("nothing changed, got %+v", caughtUp)
+	}
+
+	var page models.SyncResponse
+	expect(t, ts.do(token, "GET", "/api/sync?limit=1", ""), http.StatusOK, &page)
+	if len(page.Created) != 1 || !page.HasMore {
+		t.Errorf("first page = %+v", page)
+	}
+	expect(t, ts.do(token, "GET", "/api/sync?since=bogus", ""), http.StatusBadRequest, nil)
+	expect(t, ts.do(other, "GET", "/api/sync?since="+initial.Token, ""), http.StatusBadRequest, nil)
+}
+
+func TestSyncPushConflicts(t *testing.T) {
+	ts := newTestServer(t)
+	token := ts.addUser("push@example.com")
+
+	var pushed models.SyncPushResponse
+	expect(t, ts.do(token, "POST", "/api/sync/push",
+		`{"operations":[{"op":"create","client_id":"tmp-1","fields":{"title":"Offline"}}]}`), http.StatusOK, &pushed)
+	if !pushed.Applied || pushed.Results[0].ClientID != "tmp-1" || pushed.Results[0].Todo == nil {
+		t.Fatalf("create push = %+v", pushed)
+	}
+	id := pushed.Results[0].Todo.ID
+	expect(t, ts.do(token, "PATCH", fmt.Sprintf("/api/todos/%d", id), `{"title":"Online"}`), http.StatusOK, nil)
+
+	// The title changed on the server after the client's edit, so the
+	// server keeps it; the priority only changed on the client
+	expect(t, ts.do(token, "POST", "/api/sync/push", fmt.Sprintf(
+		`{"operations":[{"op":"update","id":%d,"base_version":1,"client_time":"2000-01-01T00:00:00Z","fields":{"title":"Stale","priority":2}}]}`, id)),
+		http.StatusOK, &pushed)
+	result := pushed.Results[0]
+	if result.Todo == nil || result.Todo.Title != "Online" || result.Todo.Priority != 2 {
+		t.Fatalf("update push = %+v", result)
+	}
+	if len(result.Conflicts) != 1 || result.Conflicts[0].Field != "title" || result.Conflicts[0].Winner != models.SyncServerWins {
+		t.Errorf("conflicts = %+v", result.Conflicts)
+	}
+
+	// History keeps whole seconds, and a change within the same second as
+	// the server's loses to it
+	var history []models.TodoRevision
+	expect(t, ts.do(token, "GET", fmt.Sprintf("/api/todos/%d/history", id), ""), http.StatusOK, &history)
+	var titled time.Time
+	for _, revision := range history {
+		if _, ok := revision.Changes["title"]; ok {
+			titled = revision.CreatedAt
+		}
+	}
+	expect(t, ts.do(token, "POST", "/api/sync/push", fmt.Sprintf(
+		`{"operations":[{"op":"update","id":%d,"base_version":1,"client_time":%q,"fields":{"title":"Tied"}}]}`,
+		id, titled.Add(500*time.Millisecond).Format(time.RFC3339Nano))),
+		http.StatusOK, &pushed)
+	if result := pushed.Results[0]; result.Todo == nil || result.Todo.Title != "Online" {
+		t.Errorf("tied push = %+v", result)
+	}
+
+	// A base version the todo has not reached yet is refused
+	expect(t, ts.do(token, "POST", "/api/sync/push", fmt.Sprintf(
+		`{"operations":[{"op":"update","id":%d,"base_version":99,"client_time":"2099-01-01T00:00:00Z","fields":{"title":"Future"}}]}`, id)),
+		http.StatusConflict, &pushed)
+	if pushed.Results[0].Status != http.StatusBadRequest {
+		t.Errorf("push from the future = %+v", pushed)
+	}
+
+	// A failed operation leaves the whole push unapplied
+	expect(t, ts.do(token, "POST", "/api/sync/push", fmt.Sprintf(
+		`{"operations":[{"op":"delete","id":%d,"base_version":3,"client_time":"2099-01-01T00:00:00Z"},{"op":"create","fields":{}}]}`, id)),
+		http.StatusConflict, &pushed)
+	if pushed.Applied || pushed.Results[0].Status != http.StatusFailedDependency {
+		t.Errorf("failed push = %+v", pushed)
+	}
+	expect(t, ts.do(token, "GET", fmt.Sprintf("/api/todos/%d", id), ""), http.StatusOK, nil)
+
+	// Deleting a parent and its subtask together deletes both
+	var parent, child models.Todo
+	expect(t, ts.do(token, "POST", "/api/todos", `{"title":"Plan trip"}`), http.StatusCreated, &parent)
+	expect(t, ts.do(token, "POST", "/api/todos", fmt.Sprintf(`{"title":"Book hotel","parent_id":%d}`, parent.ID)),
+		http.StatusCreated, &child)
+	expect(t, ts.do(token, "GET", fmt.Sprintf("/api/todos/%d", parent.ID), ""), http.StatusOK, &parent)
+	expect(t, ts.do(token, "POST", "/api/sync/push", fmt.Sprintf(
+		`{"operations":[{"op":"delete","id":%d,"base_version":%d,"client_time":"2099-01-01T00:00:00Z"},`+
+			`{"op":"delete","id":%d,"base_version":%d,"client_time":"2099-01-01T00:00:00Z"}]}`,
+		parent.ID, parent.Version, child.ID, child.Version)),
+		http.StatusOK, &pushed)
+	if !pushed.Applied || pushed.Results[0].Status != http.StatusOK || pushed.Results[1].Status != http.StatusOK {
+		t.Errorf("subtree delete push = %+v", pushed)
+	}
+	expect(t, ts.do(token, "GET", fmt.Sprintf("/api/todos/%d", child.ID), ""), http.StatusNotFound, nil)
+}
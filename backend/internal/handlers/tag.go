@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"todo-list-app/internal/middleware"
+	"todo-list-app/internal/models"
+	"todo-list-app/internal/store"
+)
+
+const maxTagLength = 50
+
+type TagHandler struct {
+	tags store.TagStore
+}
+
+// NewTagHandler creates a new tag handler
+func NewTagHandler(tags store.TagStore) *TagHandler {
+	return &TagHandler{tags: tags}
+}
+
+// GetTags lists the user's tags with the number of todos using each
+func (h *TagHandler) GetTags(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	tags, err := h.tags.ListTags(userID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tags)
+}
+
+// CreateTag creates a new tag for the authenticated user
+func (h *TagHandler) CreateTag(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.CreateTagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	name, err := normalizeTagName(req.Name)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tag, err := h.tags.CreateTag(userID, name)
+	if err == store.ErrConflict {
+		writeJSONError(w, http.StatusConflict, "Tag already exists")
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to create tag", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(tag)
+}
+
+// UpdateTag renames a tag. Renaming onto another existing tag is rejected;
+// use MergeTag for that.
+func (h *TagHandler) UpdateTag(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	tagID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tag ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateTagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	name, err := normalizeTagName(req.Name)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tag, err := h.tags.RenameTag(tagID, userID, name)
+	switch err {
+	case nil:
+	case store.ErrConflict:
+		writeJSONError(w, http.StatusConflict, "Another tag already has this name; merge the tags instead")
+		return
+	case store.ErrNotFound:
+		http.Error(w, "Tag not found", http.StatusNotFound)
+		return
+	default:
+		http.Error(w, "Failed to update tag", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tag)
+}
+
+// MergeTag moves every todo tagged with the source tag onto the target tag
+// and deletes the source
+func (h *TagHandler) MergeTag(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	sourceID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tag ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.MergeTagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.TargetID == sourceID {
+		writeJSONError(w, http.StatusBadRequest, "Cannot merge a tag into itself")
+		return
+	}
+
+	tag, err := h.tags.MergeTags(sourceID, req.TargetID, userID)
+	if err == store.ErrNotFound {
+		http.Error(w, "Tag not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to merge tags", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tag)
+}
+
+// DeleteTag deletes a tag and unlinks it from every todo
+func (h *TagHandler) DeleteTag(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	tagID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tag ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.tags.DeleteTag(tagID, userID); err == store.ErrNotFound {
+		http.Error(w, "Tag not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to delete tag", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Tag deleted successfully"})
+}
+
+// normalizeTagName trims and validates a single tag name
+func normalizeTagName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("tag name is required")
+	}
+	if len([]rune(name)) > maxTagLength {
+		return "", fmt.Errorf("tag name must be at most %d characters", maxTagLength)
+	}
+	return name, nil
+}
+
+// normalizeTagNames validates a list of tag names and removes duplicates,
+// comparing case-insensitively like the tags table does
+func normalizeTagNames(names []string) ([]string, error) {
+	seen := make(map[string]bool)
+	result := []string{}
+	for _, raw := range names {
+		name, err := normalizeTagName(raw)
+		if err != nil {
+			return nil, err
+		}
+		key := strings.ToLower(name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, name)
+	}
+	return result, nil
+}
+
+// parseTagMode reads ?tag_mode=. In "any" mode a todo matches if it has at
+// least one of the tags; in "all" mode it needs every one.
+func parseTagMode(mode string) (matchAll bool, err error) {
+	switch mode {
+	case "", "any":
+		return false, nil
+	case "all":
+		return true, nil
+	default:
+		return false, fmt.Errorf("tag_mode must be one of any, all")
+	}
+}
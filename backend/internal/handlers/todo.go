@@ -1,26 +1,42 @@
 package handlers
 
 import (
-	"database/sql"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"todo-list-app/internal/middleware"
 	"todo-list-app/internal/models"
+	"todo-list-app/internal/store"
 )
 
 type TodoHandler struct {
-	db *sql.DB
+	todos       store.TodoStore
+	projects    store.ProjectStore
+	comments    store.CommentStore
+	attachments store.AttachmentStore
+	revisions   store.RevisionStore
+	sync        store.SyncStore
 }
 
 // NewTodoHandler creates a new todo handler
-func NewTodoHandler(db *sql.DB) *TodoHandler {
-	return &TodoHandler{db: db}
+func NewTodoHandler(todos store.TodoStore, projects store.ProjectStore, comments store.CommentStore, attachments store.AttachmentStore, revisions store.RevisionStore, sync store.SyncStore) *TodoHandler {
+	return &TodoHandler{todos: todos, projects: projects, comments: comments, attachments: attachments, revisions: revisions, sync: sync}
 }
 
-// GetTodos retrieves all todos for the authenticated user
+// writeJSONError writes an error message as a JSON body with the given status
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+// GetTodos retrieves a page of todos for the authenticated user
 func (h *TodoHandler) GetTodos(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserIDFromContext(r)
 	if !ok {
@@ -28,38 +44,133 @@ func (h *TodoHandler) GetTodos(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rows, err := h.db.Query(`
-		SELECT id, user_id, title, description, completed, priority, created_at, updated_at 
-		FROM todos 
-		WHERE user_id = ? 
-		ORDER BY created_at DESC
-	`, userID)
-	if err != nil {
-		http.Error(w, "Database error", http.StatusInternalServerError)
+	query := store.TodoQuery{UserID: userID}
+	if err := parseTodoScope(r.URL.Query(), userID, &query); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	defer rows.Close()
 
-	var todos []models.Todo
-	for rows.Next() {
-		var todo models.Todo
-		err := rows.Scan(
-			&todo.ID, &todo.UserID, &todo.Title, &todo.Description,
-			&todo.Completed, &todo.Priority, &todo.CreatedAt, &todo.UpdatedAt,
-		)
+	h.listTodos(w, r, query)
+}
+
+// parseTodoScope applies the filters of GetTodos that the project todo
+// listing does not take to query: ?project=inbox|<id> and
+// ?assignee=me|none|<id>
+func parseTodoScope(values url.Values, userID int, query *store.TodoQuery) error {
+	var err error
+	if project := values.Get("project"); project != "" {
+		if query.Project, err = parseProjectFilter(project); err != nil {
+			return err
+		}
+	}
+	if assignee := values.Get("assignee"); assignee != "" {
+		if query.Assignee, err = parseAssigneeFilter(assignee, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseTodoFilter applies the filters shared by the todo list endpoints to
+// query: ?completed=true|false, ?due= in the ?tz= timezone, and
+// ?tag=a&tag=b&tag_mode=any|all
+func parseTodoFilter(values url.Values, query *store.TodoQuery) error {
+	if completed := values.Get("completed"); completed != "" {
+		value, err := strconv.ParseBool(completed)
 		if err != nil {
-			http.Error(w, "Failed to scan todo", http.StatusInternalServerError)
-			return
+			return fmt.Errorf("completed must be true or false")
+		}
+		query.Completed = &value
+	}
+
+	// Due date views are computed in the caller's timezone
+	if due := values.Get("due"); due != "" {
+		loc, err := parseLocation(values.Get("tz"))
+		if err != nil {
+			return err
+		}
+		if query.Due, err = dueFilter(due, time.Now(), loc); err != nil {
+			return err
+		}
+	}
+
+	if names := values["tag"]; len(names) > 0 {
+		names, err := normalizeTagNames(names)
+		if err != nil {
+			return err
+		}
+		matchAll, err := parseTagMode(values.Get("tag_mode"))
+		if err != nil {
+			return err
 		}
-		todos = append(todos, todo)
+		query.Tags = names
+		query.MatchAllTags = matchAll
+	}
+	return nil
+}
+
+// listTodos applies the filter and paging parameters shared by the todo
+// list endpoints to query and writes the resulting page
+func (h *TodoHandler) listTodos(w http.ResponseWriter, r *http.Request, query store.TodoQuery) {
+	if err := parseTodoFilter(r.URL.Query(), &query); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Sorting and keyset pagination; due views default to soonest first
+	defaultSort := "created_at"
+	if r.URL.Query().Get("due") != "" {
+		defaultSort = "due_at"
+	}
+	page, err := parseTodoPage(
+		r.URL.Query().Get("sort"), r.URL.Query().Get("order"),
+		r.URL.Query().Get("limit"), r.URL.Query().Get("cursor"), defaultSort,
+	)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
 	}
+	page.apply(&query)
 
-	if todos == nil {
-		todos = []models.Todo{}
+	todos, err := h.todos.ListTodos(query)
+	if err == store.ErrInvalidCursor {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	} else if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(todos)
+	json.NewEncoder(w).Encode(page.result(todos))
+}
+
+// GetTodo retrieves a single todo. A matching If-None-Match gets 304.
+func (h *TodoHandler) GetTodo(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	todoID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid todo ID", http.StatusBadRequest)
+		return
+	}
+
+	todo, ok := h.authorizeTodo(w, userID, todoID, models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	if header := r.Header.Get("If-None-Match"); header != "" && etagMatches(header, todoETag(todo), true) {
+		w.Header().Set("ETag", todoETag(todo))
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	writeTodo(w, http.StatusOK, todo)
 }
 
 // CreateTodo creates a new todo for the authenticated user
@@ -76,55 +187,123 @@ func (h *TodoHandler) CreateTodo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate input
-	if req.Title == "" {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Title is required"})
+	todo, status, err := h.newTodo(userID, req)
+	if err != nil {
+		writeParentError(w, status, err)
+		return
+	}
+
+	result, err := h.applyTodoChange(userID, store.TodoChange{Todo: todo, Create: true})
+	if err != nil {
+		http.Error(w, "Failed to create todo", http.StatusInternalServerError)
 		return
 	}
 
+	writeTodo(w, http.StatusCreated, result.Todo)
+}
+
+// applyTodoChange saves a single change to a todo together with its
+// history, returning the error that stopped it
+func (h *TodoHandler) applyTodoChange(userID int, change store.TodoChange) (store.TodoChangeResult, error) {
+	results, err := h.todos.ApplyTodoChanges(userID, []store.TodoChange{change}, true)
+	if err != nil {
+		return store.TodoChangeResult{}, err
+	}
+	return results[0], results[0].Err
+}
+
+// newTodo validates a create request and returns the todo it describes,
+// not yet saved. Problems with the request come back with status 400 and
+// failed access checks with 403 or 404, database failures with status 500.
+func (h *TodoHandler) newTodo(userID int, req models.CreateTodoRequest) (models.Todo, int, error) {
+	if req.Title == "" {
+		return models.Todo{}, http.StatusBadRequest, fmt.Errorf("Title is required")
+	}
+
 	// Set default priority if not provided
 	if req.Priority == 0 {
 		req.Priority = 1
 	}
+	if err := validatePriority(req.Priority); err != nil {
+		return models.Todo{}, http.StatusBadRequest, err
+	}
 
-	// Create todo
-	result, err := h.db.Exec(`
-		INSERT INTO todos (user_id, title, description, priority) 
-		VALUES (?, ?, ?, ?)
-	`, userID, req.Title, req.Description, req.Priority)
+	dueAt, allDay, err := parseDueAt(req.DueAt, req.AllDay)
 	if err != nil {
-		http.Error(w, "Failed to create todo", http.StatusInternalServerError)
-		return
+		return models.Todo{}, http.StatusBadRequest, err
 	}
 
-	todoID, err := result.LastInsertId()
+	tags, err := normalizeTagNames(req.Tags)
 	if err != nil {
-		http.Error(w, "Failed to get todo ID", http.StatusInternalServerError)
-		return
+		return models.Todo{}, http.StatusBadRequest, err
 	}
 
-	// Get the created todo
-	var todo models.Todo
-	err = h.db.QueryRow(`
-		SELECT id, user_id, title, description, completed, priority, created_at, updated_at 
-		FROM todos WHERE id = ?
-	`, todoID).Scan(
-		&todo.ID, &todo.UserID, &todo.Title, &todo.Description,
-		&todo.Completed, &todo.Priority, &todo.CreatedAt, &todo.UpdatedAt,
-	)
-	if err != nil {
-		http.Error(w, "Failed to retrieve todo", http.StatusInternalServerError)
-		return
+	var recurrenceRule *string
+	if req.Recurrence != nil {
+		if recurrenceRule, err = parseRecurrence(*req.Recurrence); err != nil {
+			return models.Todo{}, http.StatusBadRequest, err
+		}
+	}
+	if recurrenceRule != nil && dueAt == nil {
+		return models.Todo{}, http.StatusBadRequest, fmt.Errorf("A repeating todo needs a due_at")
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(todo)
+	var timezone *string
+	if req.Timezone != nil {
+		if timezone, err = parseTimezone(*req.Timezone); err != nil {
+			return models.Todo{}, http.StatusBadRequest, err
+		}
+	}
+
+	// Optional project; 0 is the same as leaving it out
+	var projectID *int
+	if req.ProjectID != nil && *req.ProjectID != 0 {
+		if status, err := h.checkProject(userID, *req.ProjectID); err != nil {
+			return models.Todo{}, status, err
+		}
+		projectID = req.ProjectID
+	}
+
+	// Optional parent, making the new todo a subtask in the parent's project
+	var parentID *int
+	if req.ParentID != nil && *req.ParentID != 0 {
+		parent, status, err := h.checkParent(userID, 0, *req.ParentID)
+		if err != nil {
+			return models.Todo{}, status, err
+		}
+		if req.ProjectID != nil && !sameID(parent.ProjectID, projectID) {
+			return models.Todo{}, http.StatusBadRequest, fmt.Errorf("A subtask always belongs to its parent's project")
+		}
+		parentID, projectID = req.ParentID, parent.ProjectID
+	}
+
+	todo := models.Todo{
+		UserID:      userID,
+		ParentID:    parentID,
+		ProjectID:   projectID,
+		Title:       req.Title,
+		Description: req.Description,
+		Priority:    req.Priority,
+		DueAt:       dueAt,
+		AllDay:      allDay,
+		Recurrence:  recurrenceRule,
+		Timezone:    timezone,
+		Tags:        tags,
+	}
+
+	// Optional assignee; 0 is the same as leaving it out
+	if req.AssigneeID != nil && *req.AssigneeID != 0 {
+		if status, err := h.checkAssignee(todo, *req.AssigneeID); err != nil {
+			return models.Todo{}, status, err
+		}
+		assign(&todo, *req.AssigneeID, userID)
+	}
+	return todo, http.StatusOK, nil
 }
 
-// UpdateTodo updates an existing todo
+// UpdateTodo replaces the editable fields of a todo. title, description and
+// priority must all be given; the other fields keep their stored value when
+// left out. See PatchTodo for partial updates.
 func (h *TodoHandler) UpdateTodo(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserIDFromContext(r)
 	if !ok {
@@ -139,58 +318,182 @@ func (h *TodoHandler) UpdateTodo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var body json.RawMessage
+	var fields map[string]json.RawMessage
 	var req models.UpdateTodoRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil ||
+		json.Unmarshal(body, &fields) != nil || json.Unmarshal(body, &req) != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
-
-	// Check if todo belongs to user
-	var existingUserID int
-	err = h.db.QueryRow("SELECT user_id FROM todos WHERE id = ?", todoID).Scan(&existingUserID)
-	if err == sql.ErrNoRows {
-		http.Error(w, "Todo not found", http.StatusNotFound)
+	if missing := missingFields(fields, requiredTodoFields); len(missing) > 0 {
+		writeJSONError(w, http.StatusBadRequest, "Missing required fields: "+strings.Join(missing, ", "))
 		return
-	} else if err != nil {
-		http.Error(w, "Database error", http.StatusInternalServerError)
+	}
+
+	// Check the user's access to the todo
+	todo, ok := h.authorizeTodo(w, userID, todoID, models.RoleEditor)
+	if !ok || !checkIfMatch(w, r, todo) {
 		return
 	}
 
-	if existingUserID != userID {
-		http.Error(w, "Unauthorized", http.StatusForbidden)
+	// A due date left out is kept like the other optional fields; null
+	// clears it
+	stored := todoRequest(todo)
+	if _, ok := fields["due_at"]; !ok {
+		req.DueAt = stored.DueAt
+	}
+	if _, ok := fields["all_day"]; !ok {
+		req.AllDay = stored.AllDay
+	}
+
+	h.saveTodo(w, r, userID, todo, req)
+}
+
+// saveTodo validates req and applies it to todo, recording the change in
+// the todo's history, and writes the updated todo. It is shared by PUT and
+// PATCH, which differ only in how they build req. The save fails if the
+// todo changed since it was loaded.
+func (h *TodoHandler) saveTodo(w http.ResponseWriter, r *http.Request, userID int, todo models.Todo, req models.UpdateTodoRequest) {
+	if status, err := h.applyTodoRequest(userID, &todo, req); err != nil {
+		writeParentError(w, status, err)
 		return
 	}
 
-	// Update todo
-	_, err = h.db.Exec(`
-		UPDATE todos 
-		SET title = ?, description = ?, priority = ?, updated_at = CURRENT_TIMESTAMP 
-		WHERE id = ?
-	`, req.Title, req.Description, req.Priority, todoID)
-	if err != nil {
+	result, err := h.applyTodoChange(userID, store.TodoChange{Todo: todo, Update: true})
+	if err == store.ErrConflict {
+		writeTodoModified(w, r)
+		return
+	} else if err == store.ErrNotFound {
+		http.Error(w, "Todo not found", http.StatusNotFound)
+		return
+	} else if err != nil {
 		http.Error(w, "Failed to update todo", http.StatusInternalServerError)
 		return
 	}
 
-	// Get updated todo
-	var todo models.Todo
-	err = h.db.QueryRow(`
-		SELECT id, user_id, title, description, completed, priority, created_at, updated_at 
-		FROM todos WHERE id = ?
-	`, todoID).Scan(
-		&todo.ID, &todo.UserID, &todo.Title, &todo.Description,
-		&todo.Completed, &todo.Priority, &todo.CreatedAt, &todo.UpdatedAt,
-	)
+	writeTodo(w, http.StatusOK, result.Todo)
+}
+
+// applyTodoRequest validates an update request and applies it to todo,
+// without saving it. Problems with the request come back with status 400
+// and failed access checks with 403 or 404, database failures with status
+// 500.
+func (h *TodoHandler) applyTodoRequest(userID int, todo *models.Todo, req models.UpdateTodoRequest) (int, error) {
+	if req.Title == "" {
+		return http.StatusBadRequest, fmt.Errorf("Title is required")
+	}
+	if err := validatePriority(req.Priority); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	dueAt, allDay, err := parseDueAt(req.DueAt, req.AllDay)
 	if err != nil {
-		http.Error(w, "Failed to retrieve updated todo", http.StatusInternalServerError)
-		return
+		return http.StatusBadRequest, err
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(todo)
+	// nil tags leave the todo's tags unchanged
+	var tags []string
+	if req.Tags != nil {
+		if tags, err = normalizeTagNames(req.Tags); err != nil {
+			return http.StatusBadRequest, err
+		}
+	}
+
+	// nil recurrence keeps the rule; "" removes it
+	recurrence := todo.Recurrence
+	if req.Recurrence != nil {
+		if recurrence, err = parseRecurrence(*req.Recurrence); err != nil {
+			return http.StatusBadRequest, err
+		}
+	}
+	if recurrence != nil && dueAt == nil {
+		return http.StatusBadRequest, fmt.Errorf("A repeating todo needs a due_at")
+	}
+
+	// nil timezone keeps the todo's zone; "" goes back to UTC
+	timezone := todo.Timezone
+	if req.Timezone != nil {
+		if timezone, err = parseTimezone(*req.Timezone); err != nil {
+			return http.StatusBadRequest, err
+		}
+	}
+
+	// nil project_id keeps the todo's project; 0 moves it to the inbox of
+	// whoever created it, so only they may do that
+	projectID := todo.ProjectID
+	if req.ProjectID != nil {
+		if *req.ProjectID == 0 {
+			if todo.ProjectID != nil && todo.UserID != userID {
+				return http.StatusForbidden, fmt.Errorf("Only the todo's creator can move it to the inbox")
+			}
+			projectID = nil
+		} else if !sameID(todo.ProjectID, req.ProjectID) {
+			if status, err := h.checkProject(userID, *req.ProjectID); err != nil {
+				return status, err
+			}
+			projectID = req.ProjectID
+		}
+	}
+
+	// nil parent_id keeps the todo where it is; 0 moves it to the top level
+	parentID := todo.ParentID
+	var parent *models.Todo
+	if req.ParentID != nil {
+		if *req.ParentID == 0 {
+			parentID = nil
+		} else if !sameID(todo.ParentID, req.ParentID) {
+			newParent, status, err := h.checkParent(userID, todo.ID, *req.ParentID)
+			if err != nil {
+				return status, err
+			}
+			parentID, parent = req.ParentID, &newParent
+		}
+	}
+
+	// A subtask always lives in its parent's project. It follows a new
+	// parent unless a project was asked for explicitly, which must match.
+	if parentID != nil {
+		if parent == nil {
+			current, err := h.todos.GetTodo(*parentID)
+			if err != nil {
+				return http.StatusInternalServerError, err
+			}
+			parent = &current
+		}
+		if req.ProjectID != nil && !sameID(parent.ProjectID, projectID) {
+			return http.StatusBadRequest, fmt.Errorf("A subtask always belongs to its parent's project")
+		}
+		projectID = parent.ProjectID
+	}
+
+	// The todo only changes once the whole request is valid
+	updated := *todo
+	updated.Recurrence, updated.ProjectID, updated.ParentID = recurrence, projectID, parentID
+	updated.Timezone = timezone
+
+	// nil assignee_id keeps the assignment, unless the assignee cannot see
+	// the todo's new project; 0 unassigns the todo
+	if req.AssigneeID != nil {
+		if *req.AssigneeID != 0 {
+			if status, err := h.checkAssignee(updated, *req.AssigneeID); err != nil {
+				return status, err
+			}
+		}
+		assign(&updated, *req.AssigneeID, userID)
+	}
+
+	updated.Title = req.Title
+	updated.Description = req.Description
+	updated.Priority = req.Priority
+	updated.DueAt = dueAt
+	updated.AllDay = allDay
+	updated.Tags = tags
+	*todo = updated
+	return http.StatusOK, nil
 }
 
-// DeleteTodo deletes a todo
+// DeleteTodo moves a todo together with its subtasks to the trash
 func (h *TodoHandler) DeleteTodo(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserIDFromContext(r)
 	if !ok {
@@ -205,29 +508,31 @@ func (h *TodoHandler) DeleteTodo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if todo belongs to user and delete in one query
-	result, err := h.db.Exec("DELETE FROM todos WHERE id = ? AND user_id = ?", todoID, userID)
-	if err != nil {
-		http.Error(w, "Database error", http.StatusInternalServerError)
+	todo, ok := h.authorizeTodo(w, userID, todoID, models.RoleEditor)
+	if !ok || !checkIfMatch(w, r, todo) {
 		return
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		http.Error(w, "Failed to check deletion", http.StatusInternalServerError)
+	if _, err := h.applyTodoChange(userID, store.TodoChange{Todo: todo, Delete: true}); err == store.ErrNotFound {
+		http.Error(w, "Todo not found", http.StatusNotFound)
 		return
-	}
-
-	if rowsAffected == 0 {
-		http.Error(w, "Todo not found or unauthorized", http.StatusNotFound)
+	} else if err == store.ErrConflict {
+		writeTodoModified(w, r)
+		return
+	} else if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"message": "Todo deleted successfully"})
+	json.NewEncoder(w).Encode(map[string]string{"message": "Todo moved to trash"})
 }
 
-// ToggleTodo toggles the completed status of a todo
+// ToggleTodo toggles the completed status of a todo. ?subtasks= decides
+// what completing a todo with open subtasks does: block (the default)
+// refuses, cascade completes them too and independent leaves them open.
+// Reopening a todo never changes its subtasks. Completing an occurrence of
+// a repeating todo creates the next occurrence.
 func (h *TodoHandler) ToggleTodo(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserIDFromContext(r)
 	if !ok {
@@ -242,52 +547,51 @@ func (h *TodoHandler) ToggleTodo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get current status and verify ownership
-	var currentCompleted bool
-	var existingUserID int
-	err = h.db.QueryRow(
-		"SELECT completed, user_id FROM todos WHERE id = ?", 
-		todoID,
-	).Scan(&currentCompleted, &existingUserID)
-	if err == sql.ErrNoRows {
-		http.Error(w, "Todo not found", http.StatusNotFound)
-		return
-	} else if err != nil {
-		http.Error(w, "Database error", http.StatusInternalServerError)
+	mode, err := parseSubtaskMode(r.URL.Query().Get("subtasks"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if existingUserID != userID {
-		http.Error(w, "Unauthorized", http.StatusForbidden)
+	// Get current status and verify access
+	todo, ok := h.authorizeTodo(w, userID, todoID, models.RoleEditor)
+	if !ok || !checkIfMatch(w, r, todo) {
 		return
 	}
 
 	// Toggle status
-	newCompleted := !currentCompleted
-	_, err = h.db.Exec(`
-		UPDATE todos 
-		SET completed = ?, updated_at = CURRENT_TIMESTAMP 
-		WHERE id = ?
-	`, newCompleted, todoID)
-	if err != nil {
-		http.Error(w, "Failed to toggle todo", http.StatusInternalServerError)
+	completed := !todo.Completed
+	change := store.TodoChange{Todo: todo, Completed: &completed}
+	openSubtasks := todo.SubtasksTotal - todo.SubtasksDone
+	switch {
+	case todo.Completed || openSubtasks == 0 || mode == subtasksIndependent:
+	case mode == subtasksCascade:
+		change.Cascade = true
+	default:
+		writeJSONError(w, http.StatusConflict, openSubtasksError(openSubtasks))
 		return
 	}
+	if completed {
+		if change.Next, err = nextOccurrence(todo); err != nil {
+			http.Error(w, "Stored recurrence rule or timezone is invalid", http.StatusInternalServerError)
+			return
+		}
+	}
 
-	// Get updated todo
-	var todo models.Todo
-	err = h.db.QueryRow(`
-		SELECT id, user_id, title, description, completed, priority, created_at, updated_at 
-		FROM todos WHERE id = ?
-	`, todoID).Scan(
-		&todo.ID, &todo.UserID, &todo.Title, &todo.Description,
-		&todo.Completed, &todo.Priority, &todo.CreatedAt, &todo.UpdatedAt,
-	)
-	if err != nil {
-		http.Error(w, "Failed to retrieve updated todo", http.StatusInternalServerError)
+	result, err := h.applyTodoChange(userID, change)
+	if err == store.ErrNotFound {
+		http.Error(w, "Todo not found", http.StatusNotFound)
+		return
+	} else if err == store.ErrConflict {
+		writeTodoModified(w, r)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to toggle todo", http.StatusInternalServerError)
 		return
 	}
+	response := models.ToggledTodo{Todo: result.Todo, NextOccurrence: result.Next}
 
+	w.Header().Set("ETag", todoETag(response.Todo))
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(todo)
-}
\ No newline at end of file
+	json.NewEncoder(w).Encode(response)
+}
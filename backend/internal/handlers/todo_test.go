@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"todo-list-app/internal/models"
+)
+
+func TestTodoCRUD(t *testing.T) {
+	ts := newTestServer(t)
+	token := ts.addUser("crud@example.com")
+
+	var created models.Todo
+	expect(t, ts.do(token, "POST", "/api/todos", `{"title":"Buy milk","tags":["Home","errands"]}`), http.StatusCreated, &created)
+	if created.Title != "Buy milk" || created.Priority != 1 || created.Version != 1 {
+		t.Fatalf("created = %+v", created)
+	}
+	if fmt.Sprint(created.Tags) != "[errands Home]" {
+		t.Errorf("tags = %v, want [errands Home]", created.Tags)
+	}
+	path := fmt.Sprintf("/api/todos/%d", created.ID)
+
+	expect(t, ts.do(token, "POST", "/api/todos", `{"description":"no title"}`), http.StatusBadRequest, nil)
+	expect(t, ts.do(token, "PUT", path, `{"title":"Buy oat milk"}`), http.StatusBadRequest, nil)
+
+	var updated models.Todo
+	expect(t, ts.do(token, "PUT", path, `{"title":"Buy oat milk","description":"","priority":3}`), http.StatusOK, &updated)
+	if updated.Title != "Buy oat milk" || updated.Priority != 3 || len(updated.Tags) != 2 || updated.Version != 2 {
+		t.Errorf("updated = %+v", updated)
+	}
+
+	var patched models.Todo
+	expect(t, ts.do(token, "PATCH", path, `{"tags":null,"due_at":"2030-01-01T09:00:00Z"}`), http.StatusOK, &patched)
+	if patched.Title != "Buy oat milk" || len(patched.Tags) != 0 || patched.DueAt == nil {
+		t.Errorf("patched = %+v", patched)
+	}
+
+	// A PUT keeps the due date it leaves out, like the other optional fields
+	var kept models.Todo
+	expect(t, ts.do(token, "PUT", path, `{"title":"Buy oat milk","description":"","priority":3}`), http.StatusOK, &kept)
+	if kept.DueAt == nil || !kept.DueAt.Equal(*patched.DueAt) {
+		t.Errorf("after PUT without due_at = %+v", kept)
+	}
+
+	var toggled models.Todo
+	expect(t, ts.do(token, "PATCH", path+"/toggle", ""), http.StatusOK, &toggled)
+	if !toggled.Completed {
+		t.Errorf("toggled = %+v", toggled)
+	}
+
+	var page models.TodoPage
+	expect(t, ts.do(token, "GET", "/api/todos", ""), http.StatusOK, &page)
+	if len(page.Todos) != 1 || page.Todos[0].ID != created.ID || !page.Todos[0].Completed {
+		t.Errorf("listed %+v", page.Todos)
+	}
+
+	var history []models.TodoRevision
+	expect(t, ts.do(token, "GET", path+"/history", ""), http.StatusOK, &history)
+	var actions []string
+	for _, revision := range history {
+		actions = append(actions, revision.Action)
+	}
+	if fmt.Sprint(actions) != "[create update update toggle]" {
+		t.Errorf("history actions = %v", actions)
+	}
+
+	expect(t, ts.do(token, "DELETE", path, ""), http.StatusOK, nil)
+	expect(t, ts.do(token, "GET", path, ""), http.StatusNotFound, nil)
+	expect(t, ts.do(token, "DELETE", path, ""), http.StatusNotFound, nil)
+	expect(t, ts.do(token, "GET", "/api/todos", ""), http.StatusOK, &page)
+	if len(page.Todos) != 0 {
+		t.Errorf("listed after delete %+v", page.Todos)
+	}
+}
+
+func TestTodosArePrivate(t *testing.T) {
+	ts := newTestServer(t)
+	owner := ts.addUser("owner@example.com")
+	other := ts.addUser("other@example.com")
+
+	var todo models.Todo
+	expect(t, ts.do(owner, "POST", "/api/todos", `{"title":"Mine"}`), http.StatusCreated, &todo)
+	path := fmt.Sprintf("/api/todos/%d", todo.ID)
+
+	expect(t, ts.do(other, "GET", path, ""), http.StatusNotFound, nil)
+	expect(t, ts.do(other, "PATCH", path, `{"title":"Theirs"}`), http.StatusNotFound, nil)
+	expect(t, ts.do(other, "PATCH", path+"/toggle", ""), http.StatusNotFound, nil)
+	expect(t, ts.do(other, "DELETE", path, ""), http.StatusNotFound, nil)
+
+	var page models.TodoPage
+	expect(t, ts.do(other, "GET", "/api/todos", ""), http.StatusOK, &page)
+	if len(page.Todos) != 0 {
+		t.Errorf("other user sees %+v", page.Todos)
+	}
+	expect(t, ts.do("bogus", "GET", "/api/todos", ""), http.StatusUnauthorized, nil)
+}
+
+func TestTodoETags(t *testing.T) {
+	ts := newTestServer(t)
+	token := ts.addUser("etag@example.com")
+
+	rec := ts.do(token, "POST", "/api/todos", `{"title":"Draft"}`)
+	var todo models.Todo
+	expect(t, rec, http.StatusCreated, &todo)
+	if etag := rec.Header().Get("ETag"); etag != `"1"` {
+		t.Fatalf("ETag = %q, want \"1\"", etag)
+	}
+	path := fmt.Sprintf("/api/todos/%d", todo.ID)
+
+	expect(t, ts.do(token, "GET", path, "", "If-None-Match", `"1"`), http.StatusNotModified, nil)
+	expect(t, ts.do(token, "PATCH", path, `{"title":"First"}`, "If-Match", `"1"`), http.StatusOK, nil)
+
+	// Every write made against version 1 is now out of date
+	stale := []struct{ method, path, body string }{
+		{"PATCH", path, `{"title":"Second"}`},
+		{"PUT", path, `{"title":"Second","description":"","priority":1}`},
+		{"PATCH", path + "/toggle", ""},
+		{"DELETE", path, ""},
+	}
+	for _, write := range stale {
+		rec := ts.do(token, write.method, write.path, write.body, "If-Match", `"1"`)
+		expect(t, rec, http.StatusPreconditionFailed, nil)
+		if etag := rec.Header().Get("ETag"); etag != `"2"` {
+			t.Errorf("%s %s: ETag = %q, want \"2\"", write.method, write.path, etag)
+		}
+	}
+
+	expect(t, ts.do(token, "GET", path, ""), http.StatusOK, &todo)
+	if todo.Title != "First" || todo.Completed || todo.Version != 2 {
+		t.Errorf("todo after stale writes = %+v", todo)
+	}
+
+	// A new subtask changes the parent's counts, and so its tag
+	expect(t, ts.do(token, "POST", "/api/todos", fmt.Sprintf(`{"title":"Proofread","parent_id":%d}`, todo.ID)),
+		http.StatusCreated, nil)
+	rec = ts.do(token, "GET", path, "", "If-None-Match", `"2"`)
+	expect(t, rec, http.StatusOK, &todo)
+	if etag := rec.Header().Get("ETag"); etag != `"3"` || todo.SubtasksTotal != 1 {
+		t.Errorf("after adding a subtask: ETag = %q, todo = %+v", etag, todo)
+	}
+	expect(t, ts.do(token, "DELETE", path, "", "If-Match", `"3"`), http.StatusOK, nil)
+}
+
+func TestRecurrenceFollowsTimezone(t *testing.T) {
+	ts := newTestServer(t)
+	token := ts.addUser("standup@example.com")
+
+	expect(t, ts.do(token, "POST", "/api/todos", `{"title":"x","due_at":"2030-01-06T23:00:00Z","timezone":"Mars/Olympus"}`),
+		http.StatusBadRequest, nil)
+
+	// Monday 08:00 in Seoul is Sunday 23:00 in UTC
+	var todo models.Todo
+	expect(t, ts.do(token, "POST", "/api/todos",
+		`{"title":"Standup","due_at":"2030-01-06T23:00:00Z","timezone":"Asia/Seoul","recurrence":"FREQ=WEEKLY;BYDAY=MO,WE"}`),
+		http.StatusCreated, &todo)
+
+	var toggled models.ToggledTodo
+	expect(t, ts.do(token, "PATCH", fmt.Sprintf("/api/todos/%d/toggle", todo.ID), ""), http.StatusOK, &toggled)
+	next := toggled.NextOccurrence
+	if next == nil || next.DueAt == nil || next.Timezone == nil || *next.Timezone != "Asia/Seoul" {
+		t.Fatalf("next occurrence = %+v", next)
+	}
+	// Wednesday 08:00 in Seoul
+	if want := time.Date(2030, time.January, 8, 23, 0, 0, 0, time.UTC); !next.DueAt.Equal(want) {
+		t.Errorf("next due at %v, want %v", next.DueAt, want)
+	}
+}
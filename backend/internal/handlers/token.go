@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"todo-list-app/internal/middleware"
+	"todo-list-app/internal/models"
+	"todo-list-app/internal/store"
+)
+
+const (
+	maxTokenNameLength = 100
+	// tokenPrefixLength is how much of a secret is kept to identify the token
+	tokenPrefixLength = 12
+)
+
+type TokenHandler struct {
+	tokens store.TokenStore
+}
+
+// NewTokenHandler creates a new personal access token handler
+func NewTokenHandler(tokens store.TokenStore) *TokenHandler {
+	return &TokenHandler{tokens: tokens}
+}
+
+// GetTokens lists the user's personal access tokens without their secrets
+func (h *TokenHandler) GetTokens(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	tokens, err := h.tokens.ListTokens(userID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// CreateToken issues a new personal access token. The secret is part of
+// this response only; just its hash is stored.
+func (h *TokenHandler) CreateToken(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.CreateTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		writeJSONError(w, http.StatusBadRequest, "Token name is required")
+		return
+	}
+	if len([]rune(name)) > maxTokenNameLength {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("Token name must be at most %d characters", maxTokenNameLength))
+		return
+	}
+
+	scopes, err := normalizeScopes(req.Scopes)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresAt != nil {
+		t, err := time.Parse(time.RFC3339, *req.ExpiresAt)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "expires_at must be an RFC 3339 timestamp")
+			return
+		}
+		if !t.After(time.Now()) {
+			writeJSONError(w, http.StatusBadRequest, "expires_at must be in the future")
+			return
+		}
+		t = t.UTC()
+		expiresAt = &t
+	}
+
+	secret, err := middleware.GenerateToken()
+	if err != nil {
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+
+	token := models.APIToken{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    secret[:tokenPrefixLength],
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}
+	if err := h.tokens.CreateToken(&token, middleware.HashToken(secret)); err != nil {
+		http.Error(w, "Failed to create token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(models.CreatedAPIToken{APIToken: token, Token: secret})
+}
+
+// DeleteToken revokes a personal access token
+func (h *TokenHandler) DeleteToken(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	tokenID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid token ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.tokens.DeleteToken(tokenID, userID); err == store.ErrNotFound {
+		http.Error(w, "Token not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to revoke token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Token revoked successfully"})
+}
+
+// normalizeScopes validates the requested scopes and removes duplicates
+func normalizeScopes(requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		return nil, fmt.Errorf("at least one scope is required (%s)", strings.Join(middleware.TokenScopes, ", "))
+	}
+
+	var scopes []string
+	for _, scope := range requested {
+		scope = strings.TrimSpace(scope)
+		if !slices.Contains(middleware.TokenScopes, scope) {
+			return nil, fmt.Errorf("unknown scope %q (expected one of %s)", scope, strings.Join(middleware.TokenScopes, ", "))
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, nil
+}
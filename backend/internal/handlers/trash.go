@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"todo-list-app/internal/middleware"
+	"todo-list-app/internal/models"
+	"todo-list-app/internal/store"
+)
+
+// GetTrash lists the deleted todos the user can restore, most recently
+// deleted first
+func (h *TodoHandler) GetTrash(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	todos, err := h.todos.ListTrash(userID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(todos)
+}
+
+// EmptyTrash permanently deletes everything in the user's trash
+func (h *TodoHandler) EmptyTrash(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	deleted, err := h.todos.EmptyTrash(userID)
+	if err != nil {
+		http.Error(w, "Failed to empty trash", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Trash emptied", "deleted": deleted})
+}
+
+// RestoreTodo takes a todo out of the trash together with the subtasks
+// deleted along with it. Restoring needs the same role as deleting.
+func (h *TodoHandler) RestoreTodo(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	todoID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid todo ID", http.StatusBadRequest)
+		return
+	}
+
+	todo, err := h.todos.GetTrashedTodo(todoID)
+	if err == store.ErrNotFound {
+		http.Error(w, "Todo not found in trash", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	role, err := todoRole(h.projects, userID, todo)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if !writeRoleError(w, role, models.RoleEditor, "Todo not found in trash") {
+		return
+	}
+
+	restored, err := h.todos.RestoreTodo(todoID, userID)
+	if err == store.ErrNotFound {
+		http.Error(w, "Todo not found in trash", http.StatusNotFound)
+		return
+	} else if err == store.ErrConflict {
+		writeJSONError(w, http.StatusConflict, "The parent todo is in the trash; restore it first")
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to restore todo", http.StatusInternalServerError)
+		return
+	}
+
+	writeTodo(w, http.StatusOK, restored)
+}
@@ -2,25 +2,122 @@ package middleware
 
 import (
 	"context"
-	"encoding/json"
+	"log"
+	"net"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/sessions"
+	"todo-list-app/internal/models"
+	"todo-list-app/internal/store"
 )
 
-// SessionStore is the global session store
+// SessionStore is the global session store. It signs the session cookie,
+// which only carries the session secret; the session itself is kept by
+// sessionBackend so that it can be listed and revoked.
 var SessionStore *sessions.CookieStore
 
-// InitSessionStore initializes the session store with a secret key
-func InitSessionStore(secretKey string) {
+// sessionBackend persists login sessions
+var sessionBackend store.SessionStore
+
+const (
+	sessionCookieName = "auth-session"
+	sessionMaxAge     = 7 * 24 * time.Hour
+	// maxUserAgentLength caps how much of the User-Agent header is stored
+	maxUserAgentLength = 255
+)
+
+// InitSessionStore initializes the session store with a secret key and the
+// backend that persists sessions
+func InitSessionStore(secretKey string, backend store.SessionStore) {
 	SessionStore = sessions.NewCookieStore([]byte(secretKey))
 	SessionStore.Options = &sessions.Options{
 		Path:     "/",
-		MaxAge:   86400 * 7, // 7 days
+		MaxAge:   int(sessionMaxAge.Seconds()),
 		HttpOnly: true,
 		Secure:   false, // Set to true in production with HTTPS
 		SameSite: http.SameSiteLaxMode,
 	}
+	sessionBackend = backend
+}
+
+// StartSession logs the user in on this client: it stores a new session and
+// sets the cookie for it. A session the client already had is ended.
+func StartSession(w http.ResponseWriter, r *http.Request, userID int) error {
+	// A cookie that no longer decodes, e.g. after a secret change, is
+	// simply replaced
+	cookie, _ := SessionStore.New(r, sessionCookieName)
+	if previous, ok := currentSession(cookie); ok {
+		if err := sessionBackend.DeleteSession(previous.ID, previous.UserID); err != nil && err != store.ErrNotFound {
+			return err
+		}
+	}
+
+	secret, err := randomSecret()
+	if err != nil {
+		return err
+	}
+
+	userAgent := []rune(r.UserAgent())
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	session := models.Session{
+		UserID:    userID,
+		UserAgent: string(userAgent),
+		IPAddress: clientIP(r),
+		ExpiresAt: time.Now().Add(sessionMaxAge),
+	}
+	if err := sessionBackend.CreateSession(&session, HashToken(secret)); err != nil {
+		return err
+	}
+
+	cookie.Values = map[interface{}]interface{}{"session": secret}
+	cookie.Options.MaxAge = int(sessionMaxAge.Seconds())
+	return cookie.Save(r, w)
+}
+
+// EndSession logs this client out, ending its session and clearing the cookie
+func EndSession(w http.ResponseWriter, r *http.Request) error {
+	cookie, _ := SessionStore.New(r, sessionCookieName)
+	if session, ok := currentSession(cookie); ok {
+		if err := sessionBackend.DeleteSession(session.ID, session.UserID); err != nil && err != store.ErrNotFound {
+			return err
+		}
+	}
+
+	cookie.Values = map[interface{}]interface{}{}
+	cookie.Options.MaxAge = -1
+	return cookie.Save(r, w)
+}
+
+// currentSession returns the unexpired session a cookie refers to
+func currentSession(cookie *sessions.Session) (models.Session, bool) {
+	secret, ok := cookie.Values["session"].(string)
+	if !ok || secret == "" {
+		return models.Session{}, false
+	}
+
+	session, err := sessionBackend.GetSessionByHash(HashToken(secret))
+	if err != nil {
+		if err != store.ErrNotFound {
+			log.Printf("Warning: Failed to look up session: %v", err)
+		}
+		return models.Session{}, false
+	}
+	if !time.Now().Before(session.ExpiresAt) {
+		return models.Session{}, false
+	}
+	return session, true
+}
+
+// clientIP returns the address the request came from
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
 }
 
 // AuthMiddleware checks if the user is authenticated
@@ -32,24 +129,33 @@ func AuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		session, err := SessionStore.Get(r, "auth-session")
+		cookie, err := SessionStore.Get(r, sessionCookieName)
 		if err != nil {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusUnauthorized)
-			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid session"})
+			writeAuthError(w, http.StatusUnauthorized, "Invalid session")
 			return
 		}
 
-		userID, ok := session.Values["user_id"]
-		if !ok || userID == nil {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusUnauthorized)
-			json.NewEncoder(w).Encode(map[string]string{"error": "Authentication required"})
+		if _, ok := cookie.Values["session"]; !ok {
+			writeAuthError(w, http.StatusUnauthorized, "Authentication required")
 			return
 		}
 
-		// Add user ID to request context
-		ctx := context.WithValue(r.Context(), "user_id", userID)
+		session, ok := currentSession(cookie)
+		if !ok {
+			writeAuthError(w, http.StatusUnauthorized, "Session expired or revoked")
+			return
+		}
+
+		now := time.Now()
+		if now.Sub(session.LastSeenAt) >= lastUsedResolution {
+			if err := sessionBackend.TouchSession(session.ID, now); err != nil {
+				log.Printf("Warning: Failed to record session activity: %v", err)
+			}
+		}
+
+		// Add user and session IDs to request context
+		ctx := context.WithValue(r.Context(), "user_id", session.UserID)
+		ctx = context.WithValue(ctx, "session_id", session.ID)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -62,7 +168,8 @@ func CORSMiddleware(next http.Handler) http.Handler {
 			w.Header().Set("Access-Control-Allow-Origin", origin)
 		}
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, PATCH, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, If-Match, If-None-Match, Idempotency-Key")
+		w.Header().Set("Access-Control-Expose-Headers", "ETag, Idempotent-Replayed")
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 
 		if r.Method == "OPTIONS" {
@@ -78,4 +185,11 @@ func CORSMiddleware(next http.Handler) http.Handler {
 func GetUserIDFromContext(r *http.Request) (int, bool) {
 	userID, ok := r.Context().Value("user_id").(int)
 	return userID, ok
-}
\ No newline at end of file
+}
+
+// GetSessionIDFromContext retrieves the login session ID from the request
+// context. It is only set for requests authenticated by session cookie.
+func GetSessionIDFromContext(r *http.Request) (int, bool) {
+	sessionID, ok := r.Context().Value("session_id").(int)
+	return sessionID, ok
+}
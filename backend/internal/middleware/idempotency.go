@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"todo-list-app/internal/models"
+	"todo-list-app/internal/store"
+)
+
+const (
+	// idempotencyKeyHeader names the header a client sends to make a
+	// mutating request safe to retry
+	idempotencyKeyHeader = "Idempotency-Key"
+	// idempotencyKeyTTL is how long the response to a keyed request is kept
+	idempotencyKeyTTL = 24 * time.Hour
+	// maxIdempotencyKeyLength caps the length of an Idempotency-Key
+	maxIdempotencyKeyLength = 255
+	// maxIdempotentBody caps the request body read to hash a keyed request;
+	// it leaves room for the largest attachment upload
+	maxIdempotentBody = 16 << 20
+)
+
+// Idempotency makes POST, PUT, PATCH and DELETE requests that carry an
+// Idempotency-Key header safe to retry. The first request with a key runs
+// and its response is stored for the user; a retry with the same key gets
+// the stored response back with Idempotent-Replayed: true. Reusing a key for
+// a different request is answered with 422, and a retry that arrives while
+// the first request is still running with 409. Server errors are not
+// stored, so such a request can be retried with the same key. It must run
+// after the user is authenticated.
+func Idempotency(keys store.IdempotencyStore) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(idempotencyKeyHeader)
+			userID, ok := GetUserIDFromContext(r)
+			if key == "" || !ok || r.Method == "GET" || r.Method == "HEAD" || r.Method == "OPTIONS" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				writeAuthError(w, http.StatusBadRequest, "Idempotency-Key must be at most 255 characters")
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBody))
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					writeAuthError(w, http.StatusRequestEntityTooLarge, "Request body is too large")
+				} else {
+					writeAuthError(w, http.StatusBadRequest, "Failed to read request body")
+				}
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			record := models.IdempotencyRecord{
+				UserID:      userID,
+				Key:         key,
+				RequestHash: requestHash(r, body),
+				ExpiresAt:   time.Now().Add(idempotencyKeyTTL),
+			}
+			existing, err := keys.ReserveIdempotencyKey(&record)
+			if err == store.ErrConflict {
+				switch {
+				case existing.RequestHash != record.RequestHash:
+					writeAuthError(w, http.StatusUnprocessableEntity, "Idempotency-Key was already used for a different request")
+				case existing.Status == 0:
+					writeAuthError(w, http.StatusConflict, "A request with this Idempotency-Key is still in progress")
+				default:
+					replayResponse(w, existing)
+				}
+				return
+			} else if err != nil {
+				http.Error(w, "Database error", http.StatusInternalServerError)
+				return
+			}
+
+			recorder := &responseRecorder{ResponseWriter: w}
+			next.ServeHTTP(recorder, r)
+
+			if recorder.status == 0 {
+				recorder.WriteHeader(http.StatusOK)
+			}
+			if recorder.status >= 500 {
+				if err := keys.ReleaseIdempotencyKey(record.ID); err != nil {
+					log.Printf("Warning: Failed to release idempotency key: %v", err)
+				}
+				return
+			}
+			// CORS headers depend on the retry's origin and are set again
+			header := http.Header{}
+			for name, values := range recorder.header {
+				if name != "Set-Cookie" && !strings.HasPrefix(name, "Access-Control-") {
+					header[name] = values
+				}
+			}
+			if err := keys.CompleteIdempotencyKey(record.ID, recorder.status, header, recorder.body.Bytes()); err != nil {
+				log.Printf("Warning: Failed to store idempotent response: %v", err)
+			}
+		})
+	}
+}
+
+// requestHash identifies a request by its method, URL and body, so that a
+// key reused for another request can be told apart from a retry
+func requestHash(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	io.WriteString(hash, r.Method+" "+r.URL.RequestURI()+"\n")
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// replayResponse writes a stored response again
+func replayResponse(w http.ResponseWriter, record models.IdempotencyRecord) {
+	for name, values := range record.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(record.Status)
+	w.Write(record.Body)
+}
+
+// responseRecorder passes a response through while keeping a copy of its
+// status, headers and body
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if rec.status != 0 {
+		return
+	}
+	rec.status = status
+	rec.header = rec.ResponseWriter.Header().Clone()
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.WriteHeader(http.StatusOK)
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
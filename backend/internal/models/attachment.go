@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Attachment is a file attached to a todo. SHA256 is the key of the blob
+// holding its content, shared by every attachment with the same bytes.
+type Attachment struct {
+	ID          int       `json:"id" db:"id"`
+	TodoID      int       `json:"todo_id" db:"todo_id"`
+	UserID      int       `json:"user_id" db:"user_id"`
+	Filename    string    `json:"filename" db:"filename"`
+	ContentType string    `json:"content_type" db:"content_type"`
+	Size        int64     `json:"size" db:"size"`
+	SHA256      string    `json:"sha256" db:"sha256"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,55 @@
+package models
+
+// Bulk actions
+const (
+	BulkComplete    = "complete"
+	BulkUncomplete  = "uncomplete"
+	BulkDelete      = "delete"
+	BulkSetPriority = "set_priority"
+	BulkMove        = "move"
+	BulkAddTag      = "add_tag"
+	BulkRemoveTag   = "remove_tag"
+)
+
+// BulkAction is one step of a bulk operation. Priority goes with
+// set_priority, ProjectID with move (null or 0 moves to the inbox) and Tag
+// with add_tag and remove_tag. Subtasks goes with complete and takes the
+// values of ToggleTodo's subtasks parameter.
+type BulkAction struct {
+	Action    string `json:"action"`
+	Priority  int    `json:"priority"`
+	ProjectID *int   `json:"project_id"`
+	Tag       string `json:"tag"`
+	Subtasks  string `json:"subtasks"`
+}
+
+// BulkTodoRequest applies Actions, in order, to each todo listed in IDs or
+// matching Filter, a query string taking the filters of GET /api/todos such
+// as "completed=true&project=inbox". In Atomic mode either every todo is
+// changed or none is.
+type BulkTodoRequest struct {
+	IDs     []int        `json:"ids"`
+	Filter  *string      `json:"filter"`
+	Actions []BulkAction `json:"actions"`
+	Atomic  bool         `json:"atomic"`
+}
+
+// BulkTodoResult is the outcome for one todo of a bulk operation. Status is
+// the HTTP status the change would have had on its own. Todo is the todo
+// afterwards, left out once it has been deleted.
+type BulkTodoResult struct {
+	ID             int    `json:"id"`
+	Status         int    `json:"status"`
+	Error          string `json:"error,omitempty"`
+	Todo           *Todo  `json:"todo,omitempty"`
+	NextOccurrence *Todo  `json:"next_occurrence,omitempty"`
+}
+
+// BulkTodoResponse is the response to a bulk operation. Applied is false
+// when an atomic operation failed and nothing was changed.
+type BulkTodoResponse struct {
+	Applied   bool             `json:"applied"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Results   []BulkTodoResult `json:"results"`
+}
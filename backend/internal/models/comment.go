@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Comment is a message in the discussion of a todo. Body is Markdown and
+// is returned exactly as written; Edited is set once it has been changed.
+type Comment struct {
+	ID          int       `json:"id" db:"id"`
+	TodoID      int       `json:"todo_id" db:"todo_id"`
+	UserID      int       `json:"user_id" db:"user_id"`
+	AuthorEmail string    `json:"author_email" db:"author_email"`
+	Body        string    `json:"body" db:"body"`
+	Edited      bool      `json:"edited" db:"edited"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type CommentRequest struct {
+	Body string `json:"body"`
+}
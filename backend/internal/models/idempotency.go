@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// IdempotencyRecord is a request a user sent with an Idempotency-Key. Status
+// is 0 while the request is still running; afterwards the record holds the
+// response, which is replayed when the request is retried with the same key.
+type IdempotencyRecord struct {
+	ID          int                 `json:"id" db:"id"`
+	UserID      int                 `json:"user_id" db:"user_id"`
+	Key         string              `json:"key" db:"idempotency_key"`
+	RequestHash string              `json:"request_hash" db:"request_hash"`
+	Status      int                 `json:"status" db:"status"`
+	Header      map[string][]string `json:"header" db:"header"`
+	Body        []byte              `json:"body" db:"body"`
+	CreatedAt   time.Time           `json:"created_at" db:"created_at"`
+	ExpiresAt   time.Time           `json:"expires_at" db:"expires_at"`
+}
@@ -0,0 +1,77 @@
+package models
+
+import "time"
+
+// Roles a user can have on a project, from least to most access. Viewers
+// can read the project's todos, editors can also change them and owners can
+// also manage the project and its members. The user who created a project
+// is always an owner.
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+	RoleOwner  = "owner"
+)
+
+// Project groups todos, e.g. "Work" and "Home". Todos without a project
+// are in the inbox. TodoCount counts the project's open todos and Role is
+// the requesting user's role on the project.
+type Project struct {
+	ID        int       `json:"id" db:"id"`
+	UserID    int       `json:"user_id" db:"user_id"`
+	Role      string    `json:"role" db:"role"`
+	Name      string    `json:"name" db:"name"`
+	Color     string    `json:"color" db:"color"`
+	Archived  bool      `json:"archived" db:"archived"`
+	SortOrder int       `json:"sort_order" db:"sort_order"`
+	TodoCount int       `json:"todo_count" db:"todo_count"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Color is a #rrggbb hex color or empty. SortOrder defaults to placing the
+// project after the user's other projects.
+type CreateProjectRequest struct {
+	Name      string `json:"name"`
+	Color     string `json:"color"`
+	SortOrder *int   `json:"sort_order"`
+}
+
+// Omitted optional fields keep their current values
+type UpdateProjectRequest struct {
+	Name      string  `json:"name"`
+	Color     *string `json:"color"`
+	Archived  *bool   `json:"archived"`
+	SortOrder *int    `json:"sort_order"`
+}
+
+// ProjectMember is a user with access to a project. The project's creator
+// is listed as an owner.
+type ProjectMember struct {
+	ProjectID int       `json:"project_id" db:"project_id"`
+	UserID    int       `json:"user_id" db:"user_id"`
+	Email     string    `json:"email" db:"email"`
+	Role      string    `json:"role" db:"role"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// ProjectInvitation invites whoever registers or logs in with Email to
+// join a project with Role
+type ProjectInvitation struct {
+	ID          int       `json:"id" db:"id"`
+	ProjectID   int       `json:"project_id" db:"project_id"`
+	ProjectName string    `json:"project_name" db:"project_name"`
+	Email       string    `json:"email" db:"email"`
+	Role        string    `json:"role" db:"role"`
+	InvitedBy   int       `json:"invited_by" db:"invited_by"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// Role is viewer, editor or owner
+type InviteRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+type UpdateMemberRequest struct {
+	Role string `json:"role"`
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Revision actions
+const (
+	RevisionCreate  = "create"
+	RevisionUpdate  = "update"
+	RevisionToggle  = "toggle"
+	RevisionDelete  = "delete"
+	RevisionRestore = "restore"
+	RevisionRevert  = "revert"
+)
+
+// TodoState is the part of a todo its history keeps track of
+type TodoState struct {
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Priority    int        `json:"priority"`
+	Completed   bool       `json:"completed"`
+	DueAt       *time.Time `json:"due_at"`
+	AllDay      bool       `json:"all_day"`
+	Recurrence  *string    `json:"recurrence"`
+	Timezone    *string    `json:"timezone"`
+	Tags        []string   `json:"tags"`
+	ProjectID   *int       `json:"project_id"`
+	ParentID    *int       `json:"parent_id"`
+	AssigneeID  *int       `json:"assignee_id"`
+}
+
+// FieldChange is the value of a field before and after a change, as JSON
+type FieldChange struct {
+	From json.RawMessage `json:"from"`
+	To   json.RawMessage `json:"to"`
+}
+
+// TodoRevision is one entry in a todo's history. Revisions are numbered
+// from 1 per todo. UserID is whoever made the change; Changes maps each
+// field that changed to its old and new value, and State is the tracked
+// fields after the change. Version is the todo's version once the change
+// was saved; revisions recorded before todo_revisions had a version column
+// have 0. RevertedTo is set on reverts.
+type TodoRevision struct {
+	ID         int                    `json:"id" db:"id"`
+	TodoID     int                    `json:"todo_id" db:"todo_id"`
+	Revision   int                    `json:"revision" db:"revision"`
+	UserID     int                    `json:"user_id" db:"user_id"`
+	ActorEmail string                 `json:"actor_email" db:"actor_email"`
+	Action     string                 `json:"action" db:"action"`
+	Changes    map[string]FieldChange `json:"changes" db:"changes"`
+	State      TodoState              `json:"state" db:"state"`
+	Version    int                    `json:"version,omitempty" db:"version"`
+	RevertedTo *int                   `json:"reverted_to,omitempty" db:"reverted_to"`
+	CreatedAt  time.Time              `json:"created_at" db:"created_at"`
+}
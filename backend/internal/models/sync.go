@@ -0,0 +1,86 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// SyncResponse is a page of the user's change feed. Created holds todos the
+// client has not been sent before and Updated those changed since, Deleted
+// the IDs of todos that were deleted or can no longer be seen. Token is
+// passed back as since to continue; HasMore means another page follows
+// right away.
+type SyncResponse struct {
+	Created []Todo `json:"created"`
+	Updated []Todo `json:"updated"`
+	Deleted []int  `json:"deleted"`
+	Token   string `json:"token"`
+	HasMore bool   `json:"has_more"`
+}
+
+// Sync push operations
+const (
+	SyncCreate = "create"
+	SyncUpdate = "update"
+	SyncDelete = "delete"
+)
+
+// Winners of a sync conflict
+const (
+	SyncClientWins = "client"
+	SyncServerWins = "server"
+)
+
+// SyncOperation is one change a client made while offline, at ClientTime.
+// A create carries the new todo's fields as in POST /api/todos, plus
+// completed, and ClientID, the client's own name for the todo. An update
+// carries the changed fields as a JSON merge patch, plus completed. Updates
+// and deletes name the todo by ID and give the version the client last saw
+// as BaseVersion.
+type SyncOperation struct {
+	Op          string                     `json:"op"`
+	ID          int                        `json:"id"`
+	ClientID    string                     `json:"client_id"`
+	BaseVersion int                        `json:"base_version"`
+	ClientTime  time.Time                  `json:"client_time"`
+	Fields      map[string]json.RawMessage `json:"fields"`
+}
+
+// SyncPushRequest is the body of POST /api/sync/push, with the operations
+// in the order the client made them
+type SyncPushRequest struct {
+	Operations []SyncOperation `json:"operations"`
+}
+
+// SyncConflict is a field that both the client and, after the client's
+// base version, someone else changed. The later change wins; Winner is
+// client or server. A delete that lost to a later edit is reported as the
+// field deleted.
+type SyncConflict struct {
+	Field       string          `json:"field"`
+	ClientValue json.RawMessage `json:"client_value"`
+	ServerValue json.RawMessage `json:"server_value"`
+	ServerTime  time.Time       `json:"server_time"`
+	Winner      string          `json:"winner"`
+}
+
+// SyncPushResult is the outcome of one operation of a push. Status is the
+// HTTP status the operation would have had on its own. Todo is the todo
+// after the whole push, left out once it has been deleted.
+type SyncPushResult struct {
+	Op             string         `json:"op"`
+	ID             int            `json:"id,omitempty"`
+	ClientID       string         `json:"client_id,omitempty"`
+	Status         int            `json:"status"`
+	Error          string         `json:"error,omitempty"`
+	Todo           *Todo          `json:"todo,omitempty"`
+	NextOccurrence *Todo          `json:"next_occurrence,omitempty"`
+	Conflicts      []SyncConflict `json:"conflicts,omitempty"`
+}
+
+// SyncPushResponse is the response to a push. Applied is false when an
+// operation failed and nothing was changed.
+type SyncPushResponse struct {
+	Applied bool             `json:"applied"`
+	Results []SyncPushResult `json:"results"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+type Tag struct {
+	ID        int       `json:"id" db:"id"`
+	UserID    int       `json:"user_id" db:"user_id"`
+	Name      string    `json:"name" db:"name"`
+	TodoCount int       `json:"todo_count" db:"todo_count"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+type CreateTagRequest struct {
+	Name string `json:"name"`
+}
+
+type UpdateTagRequest struct {
+	Name string `json:"name"`
+}
+
+type MergeTagRequest struct {
+	TargetID int `json:"target_id"`
+}
@@ -2,25 +2,107 @@ package models
 
 import "time"
 
+// SubtasksTotal and SubtasksDone count the todo's direct subtasks and
+// CommentCount its comments. Recurrence is the RRULE of a repeating todo
+// and Timezone the IANA time zone it repeats in, UTC when nil.
+// AssignedBy and AssignedAt record who assigned the todo to AssigneeID and
+// when. DeletedAt is set while the todo is in the trash. Version goes up
+// with every change to the todo and is its ETag.
 type Todo struct {
-	ID          int       `json:"id" db:"id"`
-	UserID      int       `json:"user_id" db:"user_id"`
-	Title       string    `json:"title" db:"title"`
-	Description string    `json:"description" db:"description"`
-	Completed   bool      `json:"completed" db:"completed"`
-	Priority    int       `json:"priority" db:"priority"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	ID          int        `json:"id" db:"id"`
+	UserID      int        `json:"user_id" db:"user_id"`
+	ParentID    *int       `json:"parent_id" db:"parent_id"`
+	ProjectID   *int       `json:"project_id" db:"project_id"`
+	AssigneeID  *int       `json:"assignee_id" db:"assignee_id"`
+	AssignedBy  *int       `json:"assigned_by" db:"assigned_by"`
+	AssignedAt  *time.Time `json:"assigned_at" db:"assigned_at"`
+	Title       string     `json:"title" db:"title"`
+	Description string     `json:"description" db:"description"`
+	Completed   bool       `json:"completed" db:"completed"`
+	Priority    int        `json:"priority" db:"priority"`
+	DueAt       *time.Time `json:"due_at" db:"due_at"`
+	AllDay      bool       `json:"all_day" db:"all_day"`
+	Recurrence  *string    `json:"recurrence" db:"recurrence"`
+	Timezone    *string    `json:"timezone" db:"timezone"`
+	Tags        []string   `json:"tags" db:"-"`
+	Version     int        `json:"version" db:"version"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+	DeletedAt   *time.Time `json:"deleted_at" db:"deleted_at"`
+
+	SubtasksTotal int `json:"subtasks_total" db:"-"`
+	SubtasksDone  int `json:"subtasks_done" db:"-"`
+	CommentCount  int `json:"comment_count" db:"-"`
 }
 
+// DueAt accepts an RFC 3339 timestamp, or a plain YYYY-MM-DD date when
+// AllDay is set. All-day due dates are stored as midnight UTC of that date.
+// ParentID makes the new todo a subtask. Recurrence is an RRULE such as
+// "FREQ=WEEKLY;BYDAY=MO" and needs a due date; Timezone, such as
+// "Asia/Seoul", is the zone whose calendar it follows. Without ProjectID
+// the todo goes to the inbox; subtasks always share their parent's project.
+// AssigneeID must be able to see the todo.
 type CreateTodoRequest struct {
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	Priority    int    `json:"priority"`
+	ParentID    *int     `json:"parent_id"`
+	ProjectID   *int     `json:"project_id"`
+	AssigneeID  *int     `json:"assignee_id"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Priority    int      `json:"priority"`
+	DueAt       *string  `json:"due_at"`
+	AllDay      bool     `json:"all_day"`
+	Recurrence  *string  `json:"recurrence"`
+	Timezone    *string  `json:"timezone"`
+	Tags        []string `json:"tags"`
 }
 
+// UpdateTodoRequest is the body of PUT /api/todos/{id}; Title, Description
+// and Priority are required. Tags replaces the todo's tags when present;
+// omitting it leaves them as is and an empty list removes them all.
+// ParentID, ProjectID, AssigneeID, Recurrence and Timezone work the same
+// way, with 0 moving a subtask to the top level, a todo to the inbox or
+// unassigning it and "" ending a recurrence or going back to UTC. DueAt and
+// AllDay also keep their value when omitted; a null DueAt clears it.
 type UpdateTodoRequest struct {
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	Priority    int    `json:"priority"`
-}
\ No newline at end of file
+	ParentID    *int     `json:"parent_id"`
+	ProjectID   *int     `json:"project_id"`
+	AssigneeID  *int     `json:"assignee_id"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Priority    int      `json:"priority"`
+	DueAt       *string  `json:"due_at"`
+	AllDay      bool     `json:"all_day"`
+	Recurrence  *string  `json:"recurrence"`
+	Timezone    *string  `json:"timezone"`
+	Tags        []string `json:"tags"`
+}
+
+// TodoSearchResult is a todo matched by full-text search. Snippets are
+// HTML: the todo's text escaped, with matched terms wrapped in
+// <mark></mark>. Score is lower for better matches.
+type TodoSearchResult struct {
+	Todo
+	TitleSnippet       string  `json:"title_snippet"`
+	DescriptionSnippet string  `json:"description_snippet"`
+	Score              float64 `json:"score"`
+}
+
+// TodoPage is one page of GET /api/todos. NextCursor is null on the last page.
+type TodoPage struct {
+	Todos      []Todo  `json:"todos"`
+	NextCursor *string `json:"next_cursor"`
+}
+
+// TodoOccurrences previews the upcoming occurrences of a repeating todo
+type TodoOccurrences struct {
+	Recurrence  string      `json:"recurrence"`
+	Timezone    *string     `json:"timezone"`
+	Occurrences []time.Time `json:"occurrences"`
+}
+
+// ToggledTodo is the response to toggling a todo. NextOccurrence is the todo
+// created when completing an occurrence of a repeating todo.
+type ToggledTodo struct {
+	Todo
+	NextOccurrence *Todo `json:"next_occurrence,omitempty"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// APIToken is a personal access token. The secret itself is only returned
+// once, when the token is created; Prefix identifies it afterwards.
+type APIToken struct {
+	ID         int        `json:"id" db:"id"`
+	UserID     int        `json:"user_id" db:"user_id"`
+	Name       string     `json:"name" db:"name"`
+	Prefix     string     `json:"prefix" db:"prefix"`
+	Scopes     []string   `json:"scopes" db:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at" db:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at" db:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+// CreatedAPIToken is the response to creating a token, carrying the secret
+type CreatedAPIToken struct {
+	APIToken
+	Token string `json:"token"`
+}
+
+type CreateTokenRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// ExpiresAt is an RFC 3339 timestamp; omit it for a token that never expires
+	ExpiresAt *string `json:"expires_at"`
+}
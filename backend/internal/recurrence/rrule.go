@@ -0,0 +1,331 @@
+// Package recurrence implements the subset of RFC 5545 recurrence rules
+// used for repeating todos: FREQ (DAILY, WEEKLY, MONTHLY, YEARLY), INTERVAL,
+// BYDAY, COUNT and UNTIL. Weeks start on Monday and all calculations are
+// done in the location of the start time, so start must be in the time zone
+// whose calendar the rule follows: Monday 08:00 in Seoul is still Sunday in
+// UTC.
+package recurrence
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency is the base period of a rule
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// maxPeriods bounds the search for the next occurrence, so that rules which
+// can never match again (e.g. DAILY;INTERVAL=7;BYDAY=TU starting on a
+// Monday) end instead of looping forever
+const maxPeriods = 1000
+
+// WeekdayNum is a BYDAY entry. N selects the Nth (or, when negative, the Nth
+// from last) such weekday of the month; 0 selects every one.
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+// Rule is a parsed recurrence rule
+type Rule struct {
+	Freq     Frequency
+	Interval int
+	ByDay    []WeekdayNum
+	// Count is the number of occurrences left including the start; 0 means
+	// unlimited
+	Count int
+	// Until is the last moment an occurrence may fall on; nil means forever
+	Until *time.Time
+	// untilDate records that UNTIL was given as a plain date
+	untilDate bool
+}
+
+var weekdayCodes = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+// Parse parses an RRULE value such as "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH".
+// Names and values are case-insensitive and a leading "RRULE:" is accepted.
+func Parse(s string) (Rule, error) {
+	s = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(s)), "RRULE:")
+	if s == "" {
+		return Rule{}, fmt.Errorf("recurrence rule is empty")
+	}
+
+	rule := Rule{Interval: 1}
+	seen := map[string]bool{}
+	for _, part := range strings.Split(s, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		name = strings.TrimSpace(name)
+		value = strings.TrimSpace(value)
+		if !ok || value == "" {
+			return Rule{}, fmt.Errorf("invalid recurrence rule part %q", part)
+		}
+		if seen[name] {
+			return Rule{}, fmt.Errorf("recurrence rule repeats %s", name)
+		}
+		seen[name] = true
+
+		switch name {
+		case "FREQ":
+			switch Frequency(value) {
+			case Daily, Weekly, Monthly, Yearly:
+				rule.Freq = Frequency(value)
+			default:
+				return Rule{}, fmt.Errorf("FREQ must be one of DAILY, WEEKLY, MONTHLY, YEARLY")
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > 1000 {
+				return Rule{}, fmt.Errorf("INTERVAL must be a number between 1 and 1000")
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return Rule{}, fmt.Errorf("COUNT must be a positive number")
+			}
+			rule.Count = n
+		case "UNTIL":
+			until, isDate, err := parseUntil(value)
+			if err != nil {
+				return Rule{}, err
+			}
+			rule.Until, rule.untilDate = &until, isDate
+		case "BYDAY":
+			days, err := parseByDay(value)
+			if err != nil {
+				return Rule{}, err
+			}
+			rule.ByDay = days
+		default:
+			return Rule{}, fmt.Errorf("unsupported recurrence rule part %s", name)
+		}
+	}
+
+	if rule.Freq == "" {
+		return Rule{}, fmt.Errorf("recurrence rule needs FREQ")
+	}
+	if rule.Count > 0 && rule.Until != nil {
+		return Rule{}, fmt.Errorf("COUNT and UNTIL cannot be combined")
+	}
+	if rule.Freq == Yearly && len(rule.ByDay) > 0 {
+		return Rule{}, fmt.Errorf("BYDAY is not supported with FREQ=YEARLY")
+	}
+	for _, day := range rule.ByDay {
+		if day.N != 0 && rule.Freq != Monthly {
+			return Rule{}, fmt.Errorf("numbered BYDAY values like 1MO need FREQ=MONTHLY")
+		}
+	}
+	return rule, nil
+}
+
+// parseUntil accepts a date (20261231) or a UTC date-time (20261231T235959Z)
+func parseUntil(value string) (time.Time, bool, error) {
+	if t, err := time.Parse("20060102", value); err == nil {
+		// A date includes the whole day
+		return t.Add(24*time.Hour - time.Second), true, nil
+	}
+	if t, err := time.Parse("20060102T150405Z", value); err == nil {
+		return t, false, nil
+	}
+	return time.Time{}, false, fmt.Errorf("UNTIL must look like 20261231 or 20261231T235959Z")
+}
+
+// parseByDay parses a comma-separated list like "MO,WE" or "1MO,-1FR"
+func parseByDay(value string) ([]WeekdayNum, error) {
+	var days []WeekdayNum
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) < 2 {
+			return nil, fmt.Errorf("invalid BYDAY value %q", item)
+		}
+		day, ok := weekdayCodes[item[len(item)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("invalid BYDAY value %q", item)
+		}
+
+		n := 0
+		if prefix := item[:len(item)-2]; prefix != "" {
+			var err error
+			n, err = strconv.Atoi(prefix)
+			if err != nil || n == 0 || n < -5 || n > 5 {
+				return nil, fmt.Errorf("invalid BYDAY value %q", item)
+			}
+		}
+		days = append(days, WeekdayNum{N: n, Day: day})
+	}
+	return days, nil
+}
+
+// String renders the rule in canonical RRULE form, without the "RRULE:"
+// prefix
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, day := range r.ByDay {
+			days[i] = strings.ToUpper(day.Day.String()[:2])
+			if day.N != 0 {
+				days[i] = strconv.Itoa(day.N) + days[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		if r.untilDate {
+			parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+		} else {
+			parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+		}
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next returns the occurrence that follows start, which is taken to be an
+// occurrence itself, together with the rule that continues the series from
+// there. ok is false when the series ends at start.
+func (r Rule) Next(start time.Time) (next time.Time, rest Rule, ok bool) {
+	if r.Count == 1 {
+		return time.Time{}, r, false
+	}
+
+	next, ok = r.following(start)
+	if !ok || (r.Until != nil && next.After(*r.Until)) {
+		return time.Time{}, r, false
+	}
+
+	rest = r
+	if rest.Count > 0 {
+		rest.Count--
+	}
+	return next, rest, true
+}
+
+// Preview returns up to n occurrences following start
+func (r Rule) Preview(start time.Time, n int) []time.Time {
+	occurrences := []time.Time{}
+	for len(occurrences) < n {
+		next, rest, ok := r.Next(start)
+		if !ok {
+			break
+		}
+		occurrences = append(occurrences, next)
+		start, r = next, rest
+	}
+	return occurrences
+}
+
+// following finds the first time after start that the rule's pattern
+// matches, ignoring COUNT and UNTIL
+func (r Rule) following(start time.Time) (time.Time, bool) {
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, start.Hour(), start.Minute(), start.Second(), 0, start.Location())
+	}
+
+	for period := 0; period < maxPeriods; period++ {
+		step := period * r.Interval
+		var candidates []time.Time
+
+		switch r.Freq {
+		case Daily:
+			if period == 0 {
+				continue
+			}
+			day := at(start.Year(), start.Month(), start.Day()+step)
+			if len(r.ByDay) == 0 || r.hasWeekday(day.Weekday()) {
+				candidates = append(candidates, day)
+			}
+		case Weekly:
+			if len(r.ByDay) == 0 {
+				if period > 0 {
+					candidates = append(candidates, at(start.Year(), start.Month(), start.Day()+7*step))
+				}
+				break
+			}
+			// Days since Monday, so that weeks start on Monday
+			monday := start.Day() - (int(start.Weekday())+6)%7 + 7*step
+			for _, day := range r.ByDay {
+				candidates = append(candidates, at(start.Year(), start.Month(), monday+(int(day.Day)+6)%7))
+			}
+		case Monthly:
+			firstOfMonth := at(start.Year(), start.Month()+time.Month(step), 1)
+			if len(r.ByDay) == 0 {
+				// Months without the start's day of the month are skipped
+				day := at(firstOfMonth.Year(), firstOfMonth.Month(), start.Day())
+				if day.Month() == firstOfMonth.Month() {
+					candidates = append(candidates, day)
+				}
+				break
+			}
+			candidates = r.monthlyByDay(firstOfMonth)
+		case Yearly:
+			// Years without the start's date (February 29) are skipped
+			day := at(start.Year()+step, start.Month(), start.Day())
+			if day.Month() == start.Month() {
+				candidates = append(candidates, day)
+			}
+		}
+
+		sort.Slice(candidates, func(i, j int) bool { return candidates[i].Before(candidates[j]) })
+		for _, candidate := range candidates {
+			if candidate.After(start) {
+				return candidate, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// hasWeekday reports whether BYDAY includes the weekday
+func (r Rule) hasWeekday(day time.Weekday) bool {
+	for _, d := range r.ByDay {
+		if d.Day == day {
+			return true
+		}
+	}
+	return false
+}
+
+// monthlyByDay lists the days of firstOfMonth's month selected by BYDAY
+func (r Rule) monthlyByDay(firstOfMonth time.Time) []time.Time {
+	daysInMonth := firstOfMonth.AddDate(0, 1, -1).Day()
+
+	var days []time.Time
+	for _, want := range r.ByDay {
+		var matches []time.Time
+		for d := 1; d <= daysInMonth; d++ {
+			day := firstOfMonth.AddDate(0, 0, d-1)
+			if day.Weekday() == want.Day {
+				matches = append(matches, day)
+			}
+		}
+
+		switch {
+		case want.N == 0:
+			days = append(days, matches...)
+		case want.N > 0 && want.N <= len(matches):
+			days = append(days, matches[want.N-1])
+		case want.N < 0 && -want.N <= len(matches):
+			days = append(days, matches[len(matches)+want.N])
+		}
+	}
+	return days
+}
@@ -0,0 +1,111 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+)
+
+func mustParse(t *testing.T, s string) Rule {
+	t.Helper()
+	rule, err := Parse(s)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", s, err)
+	}
+	return rule
+}
+
+func TestParseCanonical(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"FREQ=DAILY", "FREQ=DAILY"},
+		{"rrule:freq=weekly;interval=1;byday=mo,we", "FREQ=WEEKLY;BYDAY=MO,WE"},
+		{"FREQ=MONTHLY;BYDAY=1MO,-1FR;COUNT=3", "FREQ=MONTHLY;BYDAY=1MO,-1FR;COUNT=3"},
+		{"FREQ=YEARLY;UNTIL=20261231", "FREQ=YEARLY;UNTIL=20261231"},
+	}
+	for _, tt := range tests {
+		if got := mustParse(t, tt.in).String(); got != tt.want {
+			t.Errorf("Parse(%q).String() = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;FREQ=WEEKLY",
+		"FREQ=DAILY;COUNT=2;UNTIL=20261231",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=YEARLY;BYDAY=MO",
+		"FREQ=DAILY;BYSETPOS=1",
+	} {
+		if _, err := Parse(in); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", in)
+		}
+	}
+}
+
+func TestPreview(t *testing.T) {
+	start := time.Date(2026, time.January, 31, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		rule string
+		want []string
+	}{
+		// Months without a 31st are skipped
+		{"FREQ=MONTHLY", []string{"2026-03-31", "2026-05-31", "2026-07-31"}},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,SA", []string{"2026-02-09", "2026-02-14", "2026-02-23"}},
+		{"FREQ=MONTHLY;BYDAY=-1FR", []string{"2026-02-27", "2026-03-27", "2026-04-24"}},
+		{"FREQ=DAILY;COUNT=3", []string{"2026-02-01", "2026-02-02"}},
+		{"FREQ=DAILY;UNTIL=20260202", []string{"2026-02-01", "2026-02-02"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, occurrence := range mustParse(t, tt.rule).Preview(start, 3) {
+			got = append(got, occurrence.Format("2006-01-02"))
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.rule, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: got %v, want %v", tt.rule, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestNextInTimeZone(t *testing.T) {
+	seoul, err := time.LoadLocation("Asia/Seoul")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	rule := mustParse(t, "FREQ=WEEKLY;BYDAY=MO,WE")
+
+	// Monday 08:00 in Seoul is Sunday 23:00 in UTC
+	start := time.Date(2026, time.May, 4, 8, 0, 0, 0, seoul)
+	got := rule.Preview(start, 3)
+	want := []time.Time{
+		time.Date(2026, time.May, 6, 8, 0, 0, 0, seoul),
+		time.Date(2026, time.May, 11, 8, 0, 0, 0, seoul),
+		time.Date(2026, time.May, 13, 8, 0, 0, 0, seoul),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if !got[i].Equal(want[i]) {
+			t.Errorf("occurrence %d = %v, want %v", i, got[i], want[i])
+		}
+		if got[i].In(seoul).Weekday() != want[i].Weekday() {
+			t.Errorf("occurrence %d falls on %v in Seoul", i, got[i].In(seoul).Weekday())
+		}
+	}
+
+	// The same instant expanded in UTC follows UTC's calendar instead
+	next, _, _ := rule.Next(start.UTC())
+	if want := time.Date(2026, time.May, 4, 23, 0, 0, 0, time.UTC); !next.Equal(want) {
+		t.Errorf("Next in UTC = %v, want %v", next, want)
+	}
+}
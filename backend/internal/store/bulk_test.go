@@ -0,0 +1,113 @@
+package store
+
+import (
+	"testing"
+
+	"todo-list-app/internal/models"
+)
+
+// batchErrors returns the error of each result of a batch
+func batchErrors(t *testing.T, s Store, actorID int, changes []TodoChange, atomic bool) []error {
+	t.Helper()
+	results, err := s.ApplyTodoChanges(actorID, changes, atomic)
+	if err != nil {
+		t.Fatalf("ApplyTodoChanges: %v", err)
+	}
+	if len(results) != len(changes) {
+		t.Fatalf("got %d results for %d changes", len(results), len(changes))
+	}
+	errs := make([]error, len(results))
+	for i, result := range results {
+		errs[i] = result.Err
+	}
+	return errs
+}
+
+func testBatches(t *testing.T, s Store) {
+	owner := mustCreateUser(t, s, "owner@example.com")
+
+	a := mustCreateTodo(t, s, models.Todo{UserID: owner, Title: "a"})
+	b := mustCreateTodo(t, s, models.Todo{UserID: owner, Title: "b"})
+	c := mustCreateTodo(t, s, models.Todo{UserID: owner, Title: "c"})
+	staleB := b
+	b.Priority = 2
+	b = mustApply(t, s, owner, TodoChange{Todo: b, Update: true}).Todo
+
+	// Outside atomic mode only the change that fails is undone
+	a.Title, staleB.Title = "a2", "b2"
+	errs := batchErrors(t, s, owner, []TodoChange{
+		{Todo: a, Update: true},
+		{Todo: staleB, Update: true},
+		{Todo: c, Delete: true},
+	}, false)
+	if errs[0] != nil || errs[1] != ErrConflict || errs[2] != nil {
+		t.Fatalf("non-atomic errors = %v", errs)
+	}
+	if got, _ := s.GetTodo(a.ID); got.Title != "a2" {
+		t.Errorf("a = %+v", got)
+	}
+	if got, _ := s.GetTodo(b.ID); got.Title != "b" || got.Version != b.Version {
+		t.Errorf("b = %+v", got)
+	}
+	if _, err := s.GetTodo(c.ID); err != ErrNotFound {
+		t.Errorf("c after delete: err = %v, want ErrNotFound", err)
+	}
+	if got := historyOf(t, s, b.ID); got != "[create update]" {
+		t.Errorf("history of b = %s", got)
+	}
+
+	// In atomic mode the first failure undoes the whole batch and stops it
+	a, _ = s.GetTodo(a.ID)
+	a.Title = "a3"
+	completed := true
+	errs = batchErrors(t, s, owner, []TodoChange{
+		{Todo: a, Update: true},
+		{Todo: staleB, Completed: &completed},
+		{Todo: b, Completed: &completed},
+	}, true)
+	if errs[0] != nil || errs[1] != ErrConflict || errs[2] != nil {
+		t.Fatalf("atomic errors = %v", errs)
+	}
+	if got, _ := s.GetTodo(a.ID); got.Title != "a2" || got.Version != a.Version {
+		t.Errorf("a after the atomic batch = %+v", got)
+	}
+	if got, _ := s.GetTodo(b.ID); got.Completed {
+		t.Errorf("b after the atomic batch = %+v", got)
+	}
+	if got := historyOf(t, s, a.ID); got != "[create update]" {
+		t.Errorf("history of a = %s", got)
+	}
+
+	// A change loaded before its todo went to the trash with its parent
+	// finds nothing
+	parent := mustCreateTodo(t, s, models.Todo{UserID: owner, Title: "parent"})
+	child := mustCreateTodo(t, s, models.Todo{UserID: owner, ParentID: &parent.ID, Title: "child"})
+	parent, _ = s.GetTodo(parent.ID)
+	errs = batchErrors(t, s, owner, []TodoChange{
+		{Todo: parent, Delete: true},
+		{Todo: child, Completed: &completed},
+	}, false)
+	if errs[0] != nil || errs[1] != ErrNotFound {
+		t.Errorf("subtree errors = %v", errs)
+	}
+	if trashed, err := s.GetTrashedTodo(child.ID); err != nil || trashed.Completed {
+		t.Errorf("trashed child = %+v, %v", trashed, err)
+	}
+
+	// Completing a subtask bumps its parent, but not past a change to the
+	// parent loaded before the batch
+	parent = mustCreateTodo(t, s, models.Todo{UserID: owner, Title: "parent"})
+	child = mustCreateTodo(t, s, models.Todo{UserID: owner, ParentID: &parent.ID, Title: "child"})
+	parent, _ = s.GetTodo(parent.ID)
+	parent.Title = "renamed parent"
+	errs = batchErrors(t, s, owner, []TodoChange{
+		{Todo: child, Completed: &completed},
+		{Todo: parent, Update: true},
+	}, true)
+	if errs[0] != nil || errs[1] != nil {
+		t.Errorf("parent and subtask errors = %v", errs)
+	}
+	if got, _ := s.GetTodo(parent.ID); got.Title != "renamed parent" || got.SubtasksDone != 1 {
+		t.Errorf("parent after the batch = %+v", got)
+	}
+}
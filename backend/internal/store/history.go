@@ -0,0 +1,141 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"maps"
+	"slices"
+
+	"todo-list-app/internal/models"
+)
+
+// StateOf returns the fields of a todo its history keeps track of
+func StateOf(todo models.Todo) models.TodoState {
+	tags := todo.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return models.TodoState{
+		Title:       todo.Title,
+		Description: todo.Description,
+		Priority:    todo.Priority,
+		Completed:   todo.Completed,
+		DueAt:       todo.DueAt,
+		AllDay:      todo.AllDay,
+		Recurrence:  todo.Recurrence,
+		Timezone:    todo.Timezone,
+		Tags:        tags,
+		ProjectID:   todo.ProjectID,
+		ParentID:    todo.ParentID,
+		AssigneeID:  todo.AssigneeID,
+	}
+}
+
+// StateFields returns the JSON encoding of each field of a state, by name
+func StateFields(state models.TodoState) (map[string]json.RawMessage, error) {
+	encoded, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// DiffStates returns the fields that differ between two states, compared
+// by their JSON encoding. A nil before is a todo that did not exist yet, so
+// every field that is set changes from null.
+func DiffStates(before *models.TodoState, after models.TodoState) (map[string]models.FieldChange, error) {
+	var old map[string]json.RawMessage
+	if before != nil {
+		var err error
+		if old, err = StateFields(*before); err != nil {
+			return nil, err
+		}
+	}
+	current, err := StateFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]models.FieldChange{}
+	for field, value := range current {
+		from, ok := old[field]
+		if !ok {
+			from = json.RawMessage("null")
+		}
+		if !bytes.Equal(from, value) {
+			changes[field] = models.FieldChange{From: from, To: value}
+		}
+	}
+	return changes, nil
+}
+
+// changeAction returns the action a change is recorded as in its todo's
+// history and the action for the subtasks it carries along
+func changeAction(change TodoChange) (string, string) {
+	switch {
+	case change.Create:
+		return models.RevisionCreate, models.RevisionUpdate
+	case change.Delete:
+		return models.RevisionDelete, models.RevisionDelete
+	case change.RevertedTo != nil:
+		return models.RevisionRevert, models.RevisionUpdate
+	case change.Update:
+		return models.RevisionUpdate, models.RevisionUpdate
+	}
+	return models.RevisionToggle, models.RevisionToggle
+}
+
+// newRevision returns the revision of a todo going from before to after,
+// or nil for an update or toggle that changed none of its tracked fields
+func newRevision(actorID int, action string, todoID int, before *models.TodoState, after models.TodoState, revertedTo *int) (*models.TodoRevision, error) {
+	changes, err := DiffStates(before, after)
+	if err != nil {
+		return nil, err
+	}
+	if len(changes) == 0 && (action == models.RevisionUpdate || action == models.RevisionToggle) {
+		return nil, nil
+	}
+	return &models.TodoRevision{
+		TodoID:     todoID,
+		UserID:     actorID,
+		Action:     action,
+		Changes:    changes,
+		State:      after,
+		RevertedTo: revertedTo,
+	}, nil
+}
+
+// recordRevisions records a change of the todo rootID in the history of
+// each todo in after, diffed against its state in before: the todo itself
+// as action and the subtasks it carried along as subtaskAction. A rootID
+// of 0 records every todo as subtaskAction. add saves each revision.
+func recordRevisions(add func(*models.TodoRevision) error, actorID, rootID int, action, subtaskAction string, before, after map[int]models.TodoState, revertedTo *int) error {
+	for _, id := range slices.Sorted(maps.Keys(after)) {
+		act, reverted := subtaskAction, (*int)(nil)
+		if id == rootID {
+			act, reverted = action, revertedTo
+		}
+		var old *models.TodoState
+		if state, ok := before[id]; ok {
+			old = &state
+		} else if id != rootID {
+			state := after[id]
+			old = &state
+		}
+
+		revision, err := newRevision(actorID, act, id, old, after[id], reverted)
+		if err != nil {
+			return err
+		}
+		if revision != nil {
+			if err := add(revision); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"todo-list-app/internal/models"
+)
+
+// historyOf returns the actions in a todo's history, oldest first
+func historyOf(t *testing.T, s Store, todoID int) string {
+	t.Helper()
+	revisions, err := s.ListRevisions(todoID)
+	if err != nil {
+		t.Fatalf("ListRevisions(%d): %v", todoID, err)
+	}
+	var actions []string
+	for i, revision := range revisions {
+		if revision.Revision != i+1 {
+			t.Errorf("todo %d: revision %d is numbered %d", todoID, i+1, revision.Revision)
+		}
+		actions = append(actions, revision.Action)
+	}
+	return fmt.Sprint(actions)
+}
+
+func testHistory(t *testing.T, s Store) {
+	owner := mustCreateUser(t, s, "owner@example.com")
+	editor := mustCreateUser(t, s, "editor@example.com")
+
+	todo := mustCreateTodo(t, s, models.Todo{UserID: owner, Title: "Draft", Tags: []string{"work"}})
+	todo.Title, todo.Tags = "Final", nil
+	todo = mustApply(t, s, editor, TodoChange{Todo: todo, Update: true}).Todo
+
+	// Saving the same fields again changes nothing worth recording
+	todo = mustApply(t, s, editor, TodoChange{Todo: todo, Update: true}).Todo
+	if got := historyOf(t, s, todo.ID); got != "[create update]" {
+		t.Fatalf("history = %s", got)
+	}
+
+	update, err := s.GetRevision(todo.ID, 2)
+	if err != nil {
+		t.Fatalf("GetRevision: %v", err)
+	}
+	if update.UserID != editor || update.ActorEmail != "editor@example.com" || update.State.Title != "Final" {
+		t.Errorf("update revision = %+v", update)
+	}
+	if len(update.Changes) != 1 || string(update.Changes["title"].From) != `"Draft"` || string(update.Changes["title"].To) != `"Final"` {
+		t.Errorf("update changes = %+v", update.Changes)
+	}
+	if _, err := s.GetRevision(todo.ID, 3); err != ErrNotFound {
+		t.Errorf("missing revision: err = %v, want ErrNotFound", err)
+	}
+
+	// A revert records the revision it went back to
+	first, err := s.GetRevision(todo.ID, 1)
+	if err != nil {
+		t.Fatalf("GetRevision: %v", err)
+	}
+	todo.Title = first.State.Title
+	todo = mustApply(t, s, owner, TodoChange{Todo: todo, Update: true, RevertedTo: &first.Revision}).Todo
+	revert, err := s.GetRevision(todo.ID, 3)
+	if err != nil || revert.Action != models.RevisionRevert || revert.RevertedTo == nil || *revert.RevertedTo != 1 || todo.Title != "Draft" {
+		t.Errorf("revert = %+v, %v; todo = %+v", revert, err, todo)
+	}
+
+	// Completing a repeating todo hands its rule over to the next
+	// occurrence, which starts a history of its own
+	due := time.Date(2030, time.January, 1, 9, 0, 0, 0, time.UTC)
+	rule := "FREQ=DAILY"
+	repeating := mustCreateTodo(t, s, models.Todo{UserID: owner, Title: "Water plants", DueAt: &due, Recurrence: &rule})
+	nextDue := due.AddDate(0, 0, 1)
+	completed := true
+	result := mustApply(t, s, owner, TodoChange{
+		Todo:      repeating,
+		Completed: &completed,
+		Next:      &models.Todo{UserID: owner, Title: "Water plants", Priority: 1, DueAt: &nextDue, Recurrence: &rule},
+	})
+	if result.Next == nil || result.Todo.Recurrence != nil || !result.Todo.Completed {
+		t.Fatalf("completed occurrence = %+v, next %+v", result.Todo, result.Next)
+	}
+	if got := historyOf(t, s, repeating.ID); got != "[create toggle]" {
+		t.Errorf("completed occurrence history = %s", got)
+	}
+	if got := historyOf(t, s, result.Next.ID); got != "[create]" {
+		t.Errorf("next occurrence history = %s", got)
+	}
+
+	// Reopening and completing again has no rule left to hand over
+	reopened := false
+	repeating = mustApply(t, s, owner, TodoChange{Todo: result.Todo, Completed: &reopened}).Todo
+	result = mustApply(t, s, owner, TodoChange{Todo: repeating, Completed: &completed, Next: result.Next})
+	if result.Next != nil {
+		t.Errorf("second occurrence spawned: %+v", result.Next)
+	}
+}
+
+func testSubtreeHistory(t *testing.T, s Store) {
+	owner := mustCreateUser(t, s, "owner@example.com")
+
+	parent := mustCreateTodo(t, s, models.Todo{UserID: owner, Title: "Move house"})
+	child := mustCreateTodo(t, s, models.Todo{UserID: owner, Title: "Pack", ParentID: &parent.ID})
+	grandchild := mustCreateTodo(t, s, models.Todo{UserID: owner, Title: "Buy boxes", ParentID: &child.ID})
+	done := mustCreateTodo(t, s, models.Todo{UserID: owner, Title: "Give notice", ParentID: &parent.ID})
+	completed := true
+	mustApply(t, s, owner, TodoChange{Todo: done, Completed: &completed})
+	parent, _ = s.GetTodo(parent.ID)
+
+	// Cascading records a toggle on every subtask it completes, and only
+	// on those
+	parent = mustApply(t, s, owner, TodoChange{Todo: parent, Completed: &completed, Cascade: true}).Todo
+	if !parent.Completed || parent.SubtasksDone != parent.SubtasksTotal {
+		t.Errorf("parent after cascade = %+v", parent)
+	}
+	for _, tt := range []struct {
+		todo models.Todo
+		want string
+	}{
+		{parent, "[create toggle]"},
+		{child, "[create toggle]"},
+		{grandchild, "[create toggle]"},
+		{done, "[create toggle]"},
+	} {
+		if got := historyOf(t, s, tt.todo.ID); got != tt.want {
+			t.Errorf("%s history = %s, want %s", tt.todo.Title, got, tt.want)
+		}
+	}
+
+	// Deleting a parent takes its whole subtree to the trash, where only
+	// the parent is listed
+	mustApply(t, s, owner, TodoChange{Todo: parent, Delete: true})
+	for _, id := range []int{parent.ID, child.ID, grandchild.ID, done.ID} {
+		if _, err := s.GetTodo(id); err != ErrNotFound {
+			t.Errorf("todo %d after deleting its parent: err = %v, want ErrNotFound", id, err)
+		}
+	}
+	trash, err := s.ListTrash(owner)
+	if err != nil || len(trash) != 1 || trash[0].ID != parent.ID {
+		t.Fatalf("ListTrash = %+v, %v", trash, err)
+	}
+	if _, err := s.RestoreTodo(child.ID, owner); err != ErrConflict {
+		t.Errorf("restoring a subtask of a trashed parent: err = %v, want ErrConflict", err)
+	}
+
+	restored, err := s.RestoreTodo(parent.ID, owner)
+	if err != nil || restored.SubtasksTotal != 2 {
+		t.Fatalf("RestoreTodo = %+v, %v", restored, err)
+	}
+	for _, id := range []int{parent.ID, child.ID, grandchild.ID, done.ID} {
+		if got := historyOf(t, s, id); got != "[create toggle delete restore]" {
+			t.Errorf("todo %d history = %s", id, got)
+		}
+	}
+}
@@ -0,0 +1,1002 @@
+package store
+
+import (
+	"html"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"todo-list-app/internal/blob"
+	"todo-list-app/internal/models"
+)
+
+// MemoryStore implements Store in process memory. It is meant for tests and
+// local experiments; nothing is persisted.
+type MemoryStore struct {
+	mu sync.RWMutex
+
+	users       map[int]models.User
+	todos       map[int]models.Todo
+	tags        map[int]models.Tag
+	todoTags    map[int]map[int]bool // todo ID -> set of tag IDs
+	tokens      map[int]memoryToken
+	sessions    map[int]memorySession
+	projects    map[int]models.Project
+	members     map[int]map[int]models.ProjectMember // project ID -> user ID -> member
+	invitations map[int]models.ProjectInvitation
+	comments    map[int]models.Comment
+	attachments map[int]models.Attachment
+	revisions   map[int]models.TodoRevision
+	idempotency map[int]models.IdempotencyRecord
+	syncChanges map[syncKey]SyncChange
+	syncSeqs    map[int]int // user ID -> last sequence number of their feed
+	blobs       blob.Store
+
+	nextUserID       int
+	nextTodoID       int
+	nextTagID        int
+	nextTokenID      int
+	nextSessionID    int
+	nextProjectID    int
+	nextInviteID     int
+	nextCommentID    int
+	nextAttachmentID int
+	nextRevisionID   int
+	nextIdempotentID int
+
+	// now returns the current time; timestamps are truncated to whole
+	// seconds to match what the SQL store keeps
+	now func() time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		users:       make(map[int]models.User),
+		todos:       make(map[int]models.Todo),
+		tags:        make(map[int]models.Tag),
+		todoTags:    make(map[int]map[int]bool),
+		tokens:      make(map[int]memoryToken),
+		sessions:    make(map[int]memorySession),
+		projects:    make(map[int]models.Project),
+		members:     make(map[int]map[int]models.ProjectMember),
+		invitations: make(map[int]models.ProjectInvitation),
+		comments:    make(map[int]models.Comment),
+		attachments: make(map[int]models.Attachment),
+		revisions:   make(map[int]models.TodoRevision),
+		idempotency: make(map[int]models.IdempotencyRecord),
+		syncChanges: make(map[syncKey]SyncChange),
+		syncSeqs:    make(map[int]int),
+		blobs:       blob.NewMemoryStore(),
+		now:         time.Now,
+	}
+}
+
+func (s *MemoryStore) timestamp() time.Time {
+	return s.now().UTC().Truncate(time.Second)
+}
+
+// withDetails returns a copy of a stored todo with its tag names, subtask
+// and comment counts filled in
+func (s *MemoryStore) withDetails(todo models.Todo) models.Todo {
+	todo.SubtasksTotal, todo.SubtasksDone = 0, 0
+	for _, child := range s.todos {
+		if child.ParentID != nil && *child.ParentID == todo.ID && child.DeletedAt == nil {
+			todo.SubtasksTotal++
+			if child.Completed {
+				todo.SubtasksDone++
+			}
+		}
+	}
+
+	todo.CommentCount = 0
+	for _, comment := range s.comments {
+		if comment.TodoID == todo.ID {
+			todo.CommentCount++
+		}
+	}
+
+	todo.Tags = []string{}
+	for tagID := range s.todoTags[todo.ID] {
+		todo.Tags = append(todo.Tags, s.tags[tagID].Name)
+	}
+	sortTagNames(todo.Tags)
+	return todo
+}
+
+// ListTodos returns the todos matching the query
+func (s *MemoryStore) ListTodos(q TodoQuery) ([]models.Todo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var after *memorySortKey
+	if q.After != nil {
+		key, err := cursorSortKey(q.Sort, *q.After)
+		if err != nil {
+			return nil, err
+		}
+		after = &key
+	}
+
+	todos := []models.Todo{}
+	for _, todo := range s.todos {
+		if todo.DeletedAt != nil || !s.canSee(todo, q.UserID) {
+			continue
+		}
+		if q.Project != nil && !inProject(todo, *q.Project) {
+			continue
+		}
+		if q.Assignee != nil && !assignedTo(todo, *q.Assignee) {
+			continue
+		}
+		if q.Completed != nil && todo.Completed != *q.Completed {
+			continue
+		}
+		if q.Due != nil && !matchesDue(todo, *q.Due) {
+			continue
+		}
+		if len(q.Tags) > 0 && !s.matchesTags(todo.ID, q.Tags, q.MatchAllTags) {
+			continue
+		}
+		if after != nil && compareSortKeys(todoSortKey(q.Sort, todo), *after, q.Desc) <= 0 {
+			continue
+		}
+		todos = append(todos, s.withDetails(todo))
+	}
+
+	sort.Slice(todos, func(i, j int) bool {
+		return compareSortKeys(todoSortKey(q.Sort, todos[i]), todoSortKey(q.Sort, todos[j]), q.Desc) < 0
+	})
+
+	if q.Limit > 0 && len(todos) > q.Limit {
+		todos = todos[:q.Limit]
+	}
+	return todos, nil
+}
+
+// canSee reports whether a todo is in the user's inbox or in a project the
+// user has a role on; the caller holds the lock
+func (s *MemoryStore) canSee(todo models.Todo, userID int) bool {
+	if todo.ProjectID == nil {
+		return todo.UserID == userID
+	}
+	_, ok := s.projectRole(*todo.ProjectID, userID)
+	return ok
+}
+
+// inProject reports whether a todo belongs to a project; project ID 0 is
+// the inbox
+func inProject(todo models.Todo, projectID int) bool {
+	if projectID == 0 {
+		return todo.ProjectID == nil
+	}
+	return todo.ProjectID != nil && *todo.ProjectID == projectID
+}
+
+// assignedTo reports whether a todo is assigned to a user; user ID 0
+// matches unassigned todos
+func assignedTo(todo models.Todo, userID int) bool {
+	if userID == 0 {
+		return todo.AssigneeID == nil
+	}
+	return todo.AssigneeID != nil && *todo.AssigneeID == userID
+}
+
+// unassignStale unassigns a todo whose assignee can no longer see it; the
+// caller holds the lock
+func (s *MemoryStore) unassignStale(todoID int) {
+	todo := s.todos[todoID]
+	if todo.AssigneeID != nil && !s.canSee(todo, *todo.AssigneeID) {
+		todo.AssigneeID, todo.AssignedBy, todo.AssignedAt = nil, nil, nil
+		todo.Version++
+		s.todos[todoID] = todo
+	}
+}
+
+// matchesDue reports whether a todo falls inside a due date filter
+func matchesDue(todo models.Todo, f DueFilter) bool {
+	if todo.DueAt == nil || (f.OpenOnly && todo.Completed) {
+		return false
+	}
+	from, to := f.TimedFrom, f.TimedTo
+	if todo.AllDay {
+		from, to = f.DateFrom, f.DateTo
+	}
+	if !from.IsZero() && todo.DueAt.Before(from) {
+		return false
+	}
+	if !to.IsZero() && !todo.DueAt.Before(to) {
+		return false
+	}
+	return true
+}
+
+// matchesTags applies the any/all tag filter to a todo's tags, whoever
+// created them
+func (s *MemoryStore) matchesTags(todoID int, names []string, matchAll bool) bool {
+	matched := 0
+	for _, name := range names {
+		for tagID := range s.todoTags[todoID] {
+			if strings.EqualFold(s.tags[tagID].Name, name) {
+				matched++
+				break
+			}
+		}
+	}
+	if matchAll {
+		return matched == len(names)
+	}
+	return matched > 0
+}
+
+// memorySortKey is a todo's position within a sort order
+type memorySortKey struct {
+	null   bool
+	number int
+	time   time.Time
+	text   string
+	id     int
+}
+
+func todoSortKey(sort TodoSort, todo models.Todo) memorySortKey {
+	key := memorySortKey{id: todo.ID}
+	switch sort {
+	case SortPriority:
+		key.number = todo.Priority
+	case SortUpdatedAt:
+		key.time = todo.UpdatedAt
+	case SortDueAt:
+		if todo.DueAt == nil {
+			key.null = true
+		} else {
+			key.time = *todo.DueAt
+		}
+	case SortTitle:
+		key.text = strings.ToLower(todo.Title)
+	default:
+		key.time = todo.CreatedAt
+	}
+	return key
+}
+
+func cursorSortKey(sort TodoSort, cursor TodoCursor) (memorySortKey, error) {
+	key := memorySortKey{id: cursor.ID}
+	if cursor.Value == nil {
+		if sort != SortDueAt {
+			return key, ErrInvalidCursor
+		}
+		key.null = true
+		return key, nil
+	}
+
+	var err error
+	switch sort {
+	case SortPriority:
+		key.number, err = strconv.Atoi(*cursor.Value)
+	case SortTitle:
+		key.text = strings.ToLower(*cursor.Value)
+	default:
+		key.time, err = parseTime(*cursor.Value)
+	}
+	if err != nil {
+		return key, ErrInvalidCursor
+	}
+	return key, nil
+}
+
+// compareSortKeys orders two keys, with NULLs last in either direction
+func compareSortKeys(a, b memorySortKey, desc bool) int {
+	if a.null != b.null {
+		if a.null {
+			return 1
+		}
+		return -1
+	}
+
+	c := 0
+	switch {
+	case a.number != b.number:
+		c = compareInts(a.number, b.number)
+	case !a.time.Equal(b.time):
+		if a.time.Before(b.time) {
+			c = -1
+		} else {
+			c = 1
+		}
+	case a.text != b.text:
+		c = strings.Compare(a.text, b.text)
+	default:
+		c = compareInts(a.id, b.id)
+	}
+	if desc {
+		return -c
+	}
+	return c
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// GetTodo returns a todo by ID unless it is in the trash
+func (s *MemoryStore) GetTodo(id int) (models.Todo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	todo, ok := s.todos[id]
+	if !ok || todo.DeletedAt != nil {
+		return models.Todo{}, ErrNotFound
+	}
+	return s.withDetails(todo), nil
+}
+
+// insertTodo stores a new todo with its tags; the caller holds the lock
+func (s *MemoryStore) insertTodo(todo models.Todo) models.Todo {
+	s.nextTodoID++
+	now := s.timestamp()
+	stored := todo
+	stored.ID = s.nextTodoID
+	stored.ParentID = copyID(todo.ParentID)
+	stored.ProjectID = copyID(todo.ProjectID)
+	stored.AssigneeID = copyID(todo.AssigneeID)
+	stored.AssignedBy = copyID(todo.AssignedBy)
+	stored.AssignedAt = copyTime(todo.AssignedAt)
+	stored.Recurrence = copyString(todo.Recurrence)
+	stored.Timezone = copyString(todo.Timezone)
+	stored.Completed = false
+	stored.Version = 1
+	stored.CreatedAt = now
+	stored.UpdatedAt = now
+	stored.Tags = nil
+	s.todos[stored.ID] = stored
+	s.setTodoTags(stored.UserID, stored.ID, todo.Tags)
+	s.touchParents(stored.ID)
+	s.syncTodos(stored.ID)
+
+	return s.withDetails(stored)
+}
+
+// spawnOccurrence clears the recurrence of a completed todo and creates
+// the next occurrence; the caller holds the lock
+func (s *MemoryStore) spawnOccurrence(completedID int, next *models.Todo) error {
+	completed, ok := s.todos[completedID]
+	if !ok || completed.Recurrence == nil {
+		return ErrConflict
+	}
+	completed.Recurrence = nil
+	completed.Version++
+	completed.UpdatedAt = s.timestamp()
+	s.todos[completedID] = completed
+	s.syncTodos(completedID)
+
+	*next = s.insertTodo(*next)
+	return nil
+}
+
+// updateTodo saves the editable fields of a todo and optionally its tags,
+// provided the todo is still at todo.Version; the caller holds the lock
+func (s *MemoryStore) updateTodo(todo *models.Todo) error {
+	stored, ok := s.todos[todo.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if stored.Version != todo.Version {
+		return ErrConflict
+	}
+
+	// A subtask follows its parent's project, even one the parent moved to
+	// after the subtask was loaded
+	if todo.ParentID != nil {
+		if parent, ok := s.todos[*todo.ParentID]; ok {
+			todo.ProjectID = copyID(parent.ProjectID)
+		}
+	}
+
+	// A parent the todo moves away from loses a subtask
+	synced := []int{stored.ID}
+	if stored.ParentID != nil {
+		synced = append(synced, *stored.ParentID)
+	}
+	if reparented := (stored.ParentID == nil) != (todo.ParentID == nil) ||
+		(stored.ParentID != nil && *stored.ParentID != *todo.ParentID); reparented {
+		if stored.ParentID != nil {
+			s.touchTodos(*stored.ParentID)
+		}
+		if todo.ParentID != nil {
+			s.touchTodos(*todo.ParentID)
+		}
+	}
+
+	stored.ParentID = copyID(todo.ParentID)
+	stored.AssigneeID = copyID(todo.AssigneeID)
+	stored.AssignedBy = copyID(todo.AssignedBy)
+	stored.AssignedAt = copyTime(todo.AssignedAt)
+	stored.Recurrence = copyString(todo.Recurrence)
+	stored.Timezone = copyString(todo.Timezone)
+	stored.Title = todo.Title
+	stored.Description = todo.Description
+	stored.Priority = todo.Priority
+	stored.DueAt = todo.DueAt
+	stored.AllDay = todo.AllDay
+	stored.Version++
+	stored.UpdatedAt = s.timestamp()
+	s.todos[stored.ID] = stored
+
+	if todo.Tags != nil {
+		s.setTodoTags(stored.UserID, stored.ID, todo.Tags)
+	}
+
+	// Subtasks always live in their parent's project
+	projectID := 0
+	if todo.ProjectID != nil {
+		projectID = *todo.ProjectID
+	}
+	subtree := s.subtreeIDs(stored.ID)
+	moved := false
+	for _, todoID := range subtree {
+		subtask := s.todos[todoID]
+		if !inProject(subtask, projectID) {
+			subtask.ProjectID = copyID(todo.ProjectID)
+			subtask.Version++
+			s.todos[todoID] = subtask
+			moved = true
+		}
+		s.unassignStale(todoID)
+	}
+	if moved {
+		synced = append(synced, subtree...)
+	}
+	s.syncTodos(synced...)
+	stored = s.todos[stored.ID]
+
+	*todo = s.withDetails(stored)
+	return nil
+}
+
+// setCompleted sets the completed flag of a todo, provided it is still at
+// version; the caller holds the lock
+func (s *MemoryStore) setCompleted(id, version int, completed bool) error {
+	stored, ok := s.todos[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if stored.Version != version {
+		return ErrConflict
+	}
+
+	stored.Completed = completed
+	stored.Version++
+	stored.UpdatedAt = s.timestamp()
+	s.todos[id] = stored
+	s.touchParents(id)
+	s.syncTodos(id)
+	return nil
+}
+
+// trashTodo moves a todo owned by the user and its subtasks to the trash,
+// provided the todo is still at version; the caller holds the lock
+func (s *MemoryStore) trashTodo(id, userID, version int) error {
+	todo, ok := s.todos[id]
+	if !ok || todo.DeletedAt != nil || todo.UserID != userID {
+		return ErrNotFound
+	}
+	if todo.Version != version {
+		return ErrConflict
+	}
+
+	now := s.timestamp()
+	subtree := s.subtreeIDs(id)
+	for _, todoID := range subtree {
+		if stored := s.todos[todoID]; stored.DeletedAt == nil {
+			stored.DeletedAt = copyTime(&now)
+			stored.Version++
+			s.todos[todoID] = stored
+		}
+	}
+	s.touchParents(subtree...)
+	s.syncTodos(subtree...)
+	return nil
+}
+
+// completeSubtree marks a todo and all of its subtasks completed, provided
+// the todo is still at version; the caller holds the lock
+func (s *MemoryStore) completeSubtree(id, version int) error {
+	if todo, ok := s.todos[id]; !ok || todo.DeletedAt != nil {
+		return ErrNotFound
+	} else if todo.Version != version {
+		return ErrConflict
+	}
+
+	now := s.timestamp()
+	subtree := s.subtreeIDs(id)
+	for _, todoID := range subtree {
+		if stored := s.todos[todoID]; !stored.Completed && stored.DeletedAt == nil {
+			stored.Completed = true
+			stored.Version++
+			stored.UpdatedAt = now
+			s.todos[todoID] = stored
+		}
+	}
+	s.touchParents(subtree...)
+	s.syncTodos(subtree...)
+	return nil
+}
+
+// touchTodos bumps the version of todos whose subtask or comment counts
+// changed, so that their entity tags change too; the caller holds the lock
+func (s *MemoryStore) touchTodos(ids ...int) {
+	for _, id := range ids {
+		if todo, ok := s.todos[id]; ok && todo.DeletedAt == nil {
+			todo.Version++
+			s.todos[id] = todo
+		}
+	}
+}
+
+// touchParents bumps the version of the parents of todos that were added,
+// moved, completed or deleted. Parents among the todos themselves are
+// left alone, as their own change bumped them already. The caller holds
+// the lock.
+func (s *MemoryStore) touchParents(ids ...int) {
+	var parents []int
+	for _, id := range ids {
+		parentID := s.todos[id].ParentID
+		if parentID != nil && !slices.Contains(ids, *parentID) && !slices.Contains(parents, *parentID) {
+			parents = append(parents, *parentID)
+		}
+	}
+	s.touchTodos(parents...)
+}
+
+// subtreeIDs returns the ID of a todo followed by the IDs of all of its
+// subtasks; the caller holds the lock
+func (s *MemoryStore) subtreeIDs(id int) []int {
+	ids := []int{id}
+	for i := 0; i < len(ids); i++ {
+		for _, child := range s.todos {
+			if child.ParentID != nil && *child.ParentID == ids[i] {
+				ids = append(ids, child.ID)
+			}
+		}
+	}
+	return ids
+}
+
+// ListSubtasks returns a todo's direct subtasks, oldest first
+func (s *MemoryStore) ListSubtasks(parentID int) ([]models.Todo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	todos := []models.Todo{}
+	for _, todo := range s.todos {
+		if todo.ParentID != nil && *todo.ParentID == parentID && todo.DeletedAt == nil {
+			todos = append(todos, s.withDetails(todo))
+		}
+	}
+	sort.Slice(todos, func(i, j int) bool {
+		if !todos[i].CreatedAt.Equal(todos[j].CreatedAt) {
+			return todos[i].CreatedAt.Before(todos[j].CreatedAt)
+		}
+		return todos[i].ID < todos[j].ID
+	})
+	return todos, nil
+}
+
+// GetAncestorIDs returns the IDs of a todo's ancestors, nearest first
+func (s *MemoryStore) GetAncestorIDs(id int) ([]int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := []int{}
+	todo, ok := s.todos[id]
+	for ok && todo.ParentID != nil {
+		ids = append(ids, *todo.ParentID)
+		todo, ok = s.todos[*todo.ParentID]
+	}
+	return ids, nil
+}
+
+// SubtreeHeight returns how many levels of subtasks a todo has below it
+func (s *MemoryStore) SubtreeHeight(id int) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.todos[id]; !ok {
+		return 0, ErrNotFound
+	}
+
+	height := 0
+	level := []int{id}
+	for {
+		var next []int
+		for _, todo := range s.todos {
+			if todo.ParentID != nil && slices.Contains(level, *todo.ParentID) {
+				next = append(next, todo.ID)
+			}
+		}
+		if len(next) == 0 {
+			return height, nil
+		}
+		height++
+		level = next
+	}
+}
+
+// copyString returns a copy of an optional string that shares no memory
+// with it
+func copyString(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	copied := *s
+	return &copied
+}
+
+// copyTime returns a copy of an optional time that shares no memory with it
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
+
+// copyID returns a copy of an optional ID that shares no memory with it
+func copyID(id *int) *int {
+	if id == nil {
+		return nil
+	}
+	copied := *id
+	return &copied
+}
+
+// SearchTodos matches words as prefixes of words in the title or
+// description and phrases as case-insensitive substrings. Scores follow the
+// SQL store's convention: lower is better, title hits count four times as
+// much as description hits and higher priority todos are boosted.
+func (s *MemoryStore) SearchTodos(userID int, terms []SearchTerm, limit int) ([]models.TodoSearchResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	results := []models.TodoSearchResult{}
+	for _, todo := range s.todos {
+		if todo.DeletedAt != nil || !s.canSee(todo, userID) {
+			continue
+		}
+
+		titleRanges := matchRanges(todo.Title, terms)
+		descriptionRanges := matchRanges(todo.Description, terms)
+		if !allTermsMatch(todo, terms) {
+			continue
+		}
+
+		hits := 4*len(titleRanges) + len(descriptionRanges)
+		results = append(results, models.TodoSearchResult{
+			Todo:               s.withDetails(todo),
+			TitleSnippet:       highlight(todo.Title, titleRanges),
+			DescriptionSnippet: highlight(todo.Description, descriptionRanges),
+			Score:              -float64(hits) * (1.0 + 0.25*float64(todo.Priority-1)),
+		})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score < results[j].Score
+		}
+		return results[i].ID > results[j].ID
+	})
+
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// allTermsMatch reports whether every term matches the title or description
+func allTermsMatch(todo models.Todo, terms []SearchTerm) bool {
+	for _, term := range terms {
+		single := []SearchTerm{term}
+		if len(matchRanges(todo.Title, single)) == 0 && len(matchRanges(todo.Description, single)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// matchRanges returns the rune ranges of text matched by any term
+func matchRanges(text string, terms []SearchTerm) [][2]int {
+	runes := []rune(strings.ToLower(text))
+	var ranges [][2]int
+	for _, term := range terms {
+		needle := []rune(strings.ToLower(term.Text))
+		if len(needle) == 0 {
+			continue
+		}
+		for i := 0; i+len(needle) <= len(runes); i++ {
+			if !term.Phrase && i > 0 && isWordRune(runes[i-1]) {
+				continue
+			}
+			if string(runes[i:i+len(needle)]) != string(needle) {
+				continue
+			}
+			end := i + len(needle)
+			if !term.Phrase {
+				// Extend a prefix match to the end of the word
+				for end < len(runes) && isWordRune(runes[end]) {
+					end++
+				}
+			}
+			ranges = append(ranges, [2]int{i, end})
+			i = end - 1
+		}
+	}
+	return ranges
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// highlight HTML-escapes text and wraps the given rune ranges of it in
+// <mark></mark>
+func highlight(text string, ranges [][2]int) string {
+	if len(ranges) == 0 {
+		return html.EscapeString(text)
+	}
+
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i][0] < ranges[j][0] })
+	runes := []rune(text)
+	var b strings.Builder
+	pos := 0
+	for _, r := range ranges {
+		if r[0] < pos {
+			continue
+		}
+		b.WriteString(html.EscapeString(string(runes[pos:r[0]])))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(string(runes[r[0]:r[1]])))
+		b.WriteString("</mark>")
+		pos = r[1]
+	}
+	b.WriteString(html.EscapeString(string(runes[pos:])))
+	return b.String()
+}
+
+// setTodoTags replaces a todo's tags, creating missing tags. Callers must
+// hold the write lock.
+func (s *MemoryStore) setTodoTags(userID, todoID int, names []string) {
+	links := make(map[int]bool)
+	for _, name := range names {
+		tagID, ok := s.findTag(userID, name)
+		if !ok {
+			s.nextTagID++
+			tagID = s.nextTagID
+			s.tags[tagID] = models.Tag{ID: tagID, UserID: userID, Name: name, CreatedAt: s.timestamp()}
+		}
+		links[tagID] = true
+	}
+	s.todoTags[todoID] = links
+}
+
+// findTag looks up a user's tag by name, case-insensitively
+func (s *MemoryStore) findTag(userID int, name string) (int, bool) {
+	for _, tag := range s.tags {
+		if tag.UserID == userID && strings.EqualFold(tag.Name, name) {
+			return tag.ID, true
+		}
+	}
+	return 0, false
+}
+
+// tagWithCount returns a copy of a tag with its todo count filled in
+func (s *MemoryStore) tagWithCount(tag models.Tag) models.Tag {
+	tag.TodoCount = 0
+	for todoID, links := range s.todoTags {
+		if links[tag.ID] && s.todos[todoID].DeletedAt == nil {
+			tag.TodoCount++
+		}
+	}
+	return tag
+}
+
+// ListTags returns the user's tags ordered by name
+func (s *MemoryStore) ListTags(userID int) ([]models.Tag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tags := []models.Tag{}
+	for _, tag := range s.tags {
+		if tag.UserID == userID {
+			tags = append(tags, s.tagWithCount(tag))
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return strings.ToLower(tags[i].Name) < strings.ToLower(tags[j].Name)
+	})
+	return tags, nil
+}
+
+// GetTag returns one of the user's tags
+func (s *MemoryStore) GetTag(id, userID int) (models.Tag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tag, ok := s.tags[id]
+	if !ok || tag.UserID != userID {
+		return models.Tag{}, ErrNotFound
+	}
+	return s.tagWithCount(tag), nil
+}
+
+// CreateTag creates a new tag
+func (s *MemoryStore) CreateTag(userID int, name string) (models.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.findTag(userID, name); ok {
+		return models.Tag{}, ErrConflict
+	}
+
+	s.nextTagID++
+	tag := models.Tag{ID: s.nextTagID, UserID: userID, Name: name, CreatedAt: s.timestamp()}
+	s.tags[tag.ID] = tag
+	return tag, nil
+}
+
+// RenameTag renames a tag unless another of the user's tags has the name
+func (s *MemoryStore) RenameTag(id, userID int, name string) (models.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tag, ok := s.tags[id]
+	if !ok || tag.UserID != userID {
+		return models.Tag{}, ErrNotFound
+	}
+	if existingID, ok := s.findTag(userID, name); ok && existingID != id {
+		return models.Tag{}, ErrConflict
+	}
+
+	before := s.taggedStates(id)
+	tag.Name = name
+	s.tags[id] = tag
+	s.touchTaggedTodos(id)
+	if err := s.recordChanges(userID, models.RevisionUpdate, before); err != nil {
+		return models.Tag{}, err
+	}
+	return s.tagWithCount(tag), nil
+}
+
+// MergeTags moves the source tag's todos onto the target tag and deletes it
+func (s *MemoryStore) MergeTags(sourceID, targetID, userID int) (models.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	source, ok := s.tags[sourceID]
+	target, ok2 := s.tags[targetID]
+	if !ok || !ok2 || source.UserID != userID || target.UserID != userID {
+		return models.Tag{}, ErrNotFound
+	}
+
+	before := s.taggedStates(sourceID)
+	s.touchTaggedTodos(sourceID)
+	for _, links := range s.todoTags {
+		if links[sourceID] {
+			delete(links, sourceID)
+			links[targetID] = true
+		}
+	}
+	delete(s.tags, sourceID)
+	if err := s.recordChanges(userID, models.RevisionUpdate, before); err != nil {
+		return models.Tag{}, err
+	}
+	return s.tagWithCount(target), nil
+}
+
+// DeleteTag deletes a tag and unlinks it from every todo
+func (s *MemoryStore) DeleteTag(id, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tag, ok := s.tags[id]
+	if !ok || tag.UserID != userID {
+		return ErrNotFound
+	}
+
+	before := s.taggedStates(id)
+	s.touchTaggedTodos(id)
+	for _, links := range s.todoTags {
+		delete(links, id)
+	}
+	delete(s.tags, id)
+	return s.recordChanges(userID, models.RevisionUpdate, before)
+}
+
+// taggedStates returns the tracked state of the todos linked to a tag,
+// whose history a change to the tag adds to; the caller holds the lock
+func (s *MemoryStore) taggedStates(tagID int) map[int]models.TodoState {
+	return s.todoStates(func(todo models.Todo) bool {
+		return s.todoTags[todo.ID][tagID]
+	})
+}
+
+// touchTaggedTodos bumps the version of the todos linked to a tag, whose
+// tag names are changing; the caller holds the lock
+func (s *MemoryStore) touchTaggedTodos(tagID int) {
+	var ids []int
+	for todoID, links := range s.todoTags {
+		if todo, ok := s.todos[todoID]; ok && links[tagID] {
+			todo.Version++
+			s.todos[todoID] = todo
+			ids = append(ids, todoID)
+		}
+	}
+	s.syncTodos(ids...)
+}
+
+// GetUserByEmail returns a user by email
+func (s *MemoryStore) GetUserByEmail(email string) (models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, user := range s.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return models.User{}, ErrNotFound
+}
+
+// GetUserByID returns a user by ID
+func (s *MemoryStore) GetUserByID(id int) (models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return models.User{}, ErrNotFound
+	}
+	return user, nil
+}
+
+// CreateUser creates a user with an already hashed password
+func (s *MemoryStore) CreateUser(email, passwordHash string) (models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, user := range s.users {
+		if user.Email == email {
+			return models.User{}, ErrConflict
+		}
+	}
+
+	s.nextUserID++
+	user := models.User{ID: s.nextUserID, Email: email, Password: passwordHash, CreatedAt: s.timestamp()}
+	s.users[user.ID] = user
+	return user, nil
+}
+
+// UpdatePassword replaces a user's password hash and ends all of the
+// user's sessions
+func (s *MemoryStore) UpdatePassword(id int, passwordHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	user.Password = passwordHash
+	s.users[id] = user
+	s.deleteUserSessions(id)
+	return nil
+}
@@ -0,0 +1,121 @@
+package store
+
+import (
+	"io"
+	"sort"
+
+	"todo-list-app/internal/blob"
+	"todo-list-app/internal/models"
+)
+
+// UseBlobStore replaces the in-memory blob store that attachment content is
+// kept in
+func (s *MemoryStore) UseBlobStore(blobs blob.Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blobs = blobs
+}
+
+// ListAttachments returns a todo's attachments, oldest first
+func (s *MemoryStore) ListAttachments(todoID int) ([]models.Attachment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	attachments := []models.Attachment{}
+	for _, attachment := range s.attachments {
+		if attachment.TodoID == todoID {
+			attachments = append(attachments, attachment)
+		}
+	}
+	sort.Slice(attachments, func(i, j int) bool {
+		if !attachments[i].CreatedAt.Equal(attachments[j].CreatedAt) {
+			return attachments[i].CreatedAt.Before(attachments[j].CreatedAt)
+		}
+		return attachments[i].ID < attachments[j].ID
+	})
+	return attachments, nil
+}
+
+// GetAttachment loads an attachment
+func (s *MemoryStore) GetAttachment(id int) (models.Attachment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	attachment, ok := s.attachments[id]
+	if !ok {
+		return models.Attachment{}, ErrNotFound
+	}
+	return attachment, nil
+}
+
+// CreateAttachment stores the content and records the attachment. The lock
+// is held throughout so that a shared blob cannot be released in between.
+func (s *MemoryStore) CreateAttachment(attachment *models.Attachment, content io.Reader) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, size, err := s.blobs.Put(content)
+	if err != nil {
+		return err
+	}
+
+	s.nextAttachmentID++
+	stored := *attachment
+	stored.ID = s.nextAttachmentID
+	stored.Size = size
+	stored.SHA256 = key
+	stored.CreatedAt = s.timestamp()
+	s.attachments[stored.ID] = stored
+
+	*attachment = stored
+	return nil
+}
+
+// OpenAttachment opens an attachment's blob
+func (s *MemoryStore) OpenAttachment(attachment models.Attachment) (io.ReadSeekCloser, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	content, err := s.blobs.Open(attachment.SHA256)
+	if err == blob.ErrNotFound {
+		return nil, ErrNotFound
+	}
+	return content, err
+}
+
+// DeleteAttachment deletes an attachment and, if nothing else uses it, its
+// blob
+func (s *MemoryStore) DeleteAttachment(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attachment, ok := s.attachments[id]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(s.attachments, id)
+	s.releaseBlob(attachment.SHA256)
+	return nil
+}
+
+// deleteAttachments removes all attachments on a todo and releases their
+// blobs; the caller holds the lock
+func (s *MemoryStore) deleteAttachments(todoID int) {
+	for attachmentID, attachment := range s.attachments {
+		if attachment.TodoID == todoID {
+			delete(s.attachments, attachmentID)
+			s.releaseBlob(attachment.SHA256)
+		}
+	}
+}
+
+// releaseBlob removes a blob once no attachment refers to it; the caller
+// holds the lock
+func (s *MemoryStore) releaseBlob(key string) {
+	for _, attachment := range s.attachments {
+		if attachment.SHA256 == key {
+			return
+		}
+	}
+	s.blobs.Delete(key)
+}
@@ -0,0 +1,155 @@
+package store
+
+import (
+	"maps"
+
+	"todo-list-app/internal/models"
+)
+
+// memorySnapshot is the part of a MemoryStore a batch of todo changes can
+// touch, kept to undo changes that fail
+type memorySnapshot struct {
+	todos          map[int]models.Todo
+	todoTags       map[int]map[int]bool
+	tags           map[int]models.Tag
+	revisions      map[int]models.TodoRevision
+	syncChanges    map[syncKey]SyncChange
+	syncSeqs       map[int]int
+	nextTodoID     int
+	nextTagID      int
+	nextRevisionID int
+}
+
+// snapshot copies the state todo changes can touch; the caller holds the
+// lock. Tag link sets are replaced rather than modified by todo changes, so
+// they are shared.
+func (s *MemoryStore) snapshot() memorySnapshot {
+	return memorySnapshot{
+		todos:          maps.Clone(s.todos),
+		todoTags:       maps.Clone(s.todoTags),
+		tags:           maps.Clone(s.tags),
+		revisions:      maps.Clone(s.revisions),
+		syncChanges:    maps.Clone(s.syncChanges),
+		syncSeqs:       maps.Clone(s.syncSeqs),
+		nextTodoID:     s.nextTodoID,
+		nextTagID:      s.nextTagID,
+		nextRevisionID: s.nextRevisionID,
+	}
+}
+
+// restore undoes everything since the snapshot was taken; the caller holds
+// the lock
+func (s *MemoryStore) restore(snapshot memorySnapshot) {
+	s.todos = snapshot.todos
+	s.todoTags = snapshot.todoTags
+	s.tags = snapshot.tags
+	s.revisions = snapshot.revisions
+	s.syncChanges = snapshot.syncChanges
+	s.syncSeqs = snapshot.syncSeqs
+	s.nextTodoID = snapshot.nextTodoID
+	s.nextTagID = snapshot.nextTagID
+	s.nextRevisionID = snapshot.nextRevisionID
+}
+
+// ApplyTodoChanges applies a batch of changes together with their history,
+// undoing a failed change on its own or, in atomic mode, the whole batch
+func (s *MemoryStore) ApplyTodoChanges(actorID int, changes []TodoChange, atomic bool) ([]TodoChangeResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results := make([]TodoChangeResult, len(changes))
+	batch := s.snapshot()
+	for i, change := range changes {
+		before := batch
+		if !atomic {
+			before = s.snapshot()
+		}
+
+		// Only the batch's own earlier changes can have bumped the version
+		// of a todo since the batch began, such as by completing a subtask
+		// of it
+		if loaded, ok := batch.todos[change.Todo.ID]; ok && !change.Create && loaded.Version == change.Todo.Version {
+			if stored, ok := s.todos[change.Todo.ID]; ok {
+				change.Todo.Version = stored.Version
+			}
+		}
+
+		result, err := s.applyTodoChange(actorID, change)
+		results[i] = result
+		if err != nil {
+			results[i].Err = err
+			s.restore(before)
+			if atomic {
+				return results, nil
+			}
+		}
+	}
+	return results, nil
+}
+
+// applyTodoChange applies one change of a batch, after checking that its
+// todo is still at the version it was loaded at, and records it in the
+// history of the todos it changed; the caller holds the lock
+func (s *MemoryStore) applyTodoChange(actorID int, change TodoChange) (TodoChangeResult, error) {
+	var result TodoChangeResult
+	todo := change.Todo
+	action, subtaskAction := changeAction(change)
+
+	var before map[int]models.TodoState
+	if change.Create {
+		todo = s.insertTodo(todo)
+	} else if stored, ok := s.todos[todo.ID]; !ok || stored.DeletedAt != nil {
+		return result, ErrNotFound
+	} else if stored.Version != todo.Version {
+		return result, ErrConflict
+	} else {
+		before = s.subtreeStates(todo.ID)
+	}
+
+	if change.Delete {
+		result.Todo = todo
+		if err := s.trashTodo(todo.ID, todo.UserID, todo.Version); err != nil {
+			return result, err
+		}
+		return result, recordRevisions(s.addRevision, actorID, todo.ID, action, subtaskAction, before, before, nil)
+	}
+
+	if change.Update {
+		if err := s.updateTodo(&todo); err != nil {
+			return result, err
+		}
+	}
+	if change.Completed != nil {
+		var err error
+		if change.Cascade && *change.Completed {
+			err = s.completeSubtree(todo.ID, todo.Version)
+		} else {
+			err = s.setCompleted(todo.ID, todo.Version, *change.Completed)
+		}
+		if err != nil {
+			return result, err
+		}
+	}
+	if change.Next != nil {
+		// Without a rule left to hand over there is simply no occurrence
+		next := *change.Next
+		if err := s.spawnOccurrence(todo.ID, &next); err == nil {
+			result.Next = &next
+		}
+	}
+
+	after := s.subtreeStates(todo.ID)
+	if err := recordRevisions(s.addRevision, actorID, todo.ID, action, subtaskAction, before, after, change.RevertedTo); err != nil {
+		return result, err
+	}
+	if result.Next != nil {
+		revision, err := newRevision(actorID, models.RevisionCreate, result.Next.ID, nil, StateOf(*result.Next), nil)
+		if err != nil {
+			return result, err
+		}
+		s.addRevision(revision)
+	}
+
+	result.Todo = s.withDetails(s.todos[todo.ID])
+	return result, nil
+}
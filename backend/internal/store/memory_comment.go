@@ -0,0 +1,109 @@
+package store
+
+import (
+	"sort"
+
+	"todo-list-app/internal/models"
+)
+
+// commentWithAuthor returns a copy of a stored comment with its author's
+// email filled in; the caller holds the lock
+func (s *MemoryStore) commentWithAuthor(comment models.Comment) models.Comment {
+	comment.AuthorEmail = s.users[comment.UserID].Email
+	return comment
+}
+
+// ListComments returns a todo's comments, oldest first
+func (s *MemoryStore) ListComments(todoID int) ([]models.Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	comments := []models.Comment{}
+	for _, comment := range s.comments {
+		if comment.TodoID == todoID {
+			comments = append(comments, s.commentWithAuthor(comment))
+		}
+	}
+	sort.Slice(comments, func(i, j int) bool {
+		if !comments[i].CreatedAt.Equal(comments[j].CreatedAt) {
+			return comments[i].CreatedAt.Before(comments[j].CreatedAt)
+		}
+		return comments[i].ID < comments[j].ID
+	})
+	return comments, nil
+}
+
+// GetComment loads a comment
+func (s *MemoryStore) GetComment(id int) (models.Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	comment, ok := s.comments[id]
+	if !ok {
+		return models.Comment{}, ErrNotFound
+	}
+	return s.commentWithAuthor(comment), nil
+}
+
+// CreateComment stores a new comment
+func (s *MemoryStore) CreateComment(comment *models.Comment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextCommentID++
+	now := s.timestamp()
+	stored := *comment
+	stored.ID = s.nextCommentID
+	stored.Edited = false
+	stored.CreatedAt = now
+	stored.UpdatedAt = now
+	s.comments[stored.ID] = stored
+	s.touchTodos(stored.TodoID)
+	s.syncTodos(stored.TodoID)
+
+	*comment = s.commentWithAuthor(stored)
+	return nil
+}
+
+// UpdateComment replaces a comment's body and marks it edited
+func (s *MemoryStore) UpdateComment(comment *models.Comment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.comments[comment.ID]
+	if !ok {
+		return ErrNotFound
+	}
+
+	stored.Body = comment.Body
+	stored.Edited = true
+	stored.UpdatedAt = s.timestamp()
+	s.comments[stored.ID] = stored
+
+	*comment = s.commentWithAuthor(stored)
+	return nil
+}
+
+// DeleteComment deletes a comment
+func (s *MemoryStore) DeleteComment(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	comment, ok := s.comments[id]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(s.comments, id)
+	s.touchTodos(comment.TodoID)
+	s.syncTodos(comment.TodoID)
+	return nil
+}
+
+// deleteComments removes all comments on a todo; the caller holds the lock
+func (s *MemoryStore) deleteComments(todoID int) {
+	for commentID, comment := range s.comments {
+		if comment.TodoID == todoID {
+			delete(s.comments, commentID)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package store
+
+import (
+	"maps"
+	"slices"
+	"time"
+
+	"todo-list-app/internal/models"
+)
+
+// ReserveIdempotencyKey claims a key for a request about to run, or returns
+// the record already stored under it with ErrConflict
+func (s *MemoryStore) ReserveIdempotencyKey(record *models.IdempotencyRecord) (models.IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.timestamp()
+	for id, stored := range s.idempotency {
+		if stored.UserID != record.UserID {
+			continue
+		}
+		if !stored.ExpiresAt.After(now) {
+			delete(s.idempotency, id)
+		} else if stored.Key == record.Key {
+			return copyIdempotencyRecord(stored), ErrConflict
+		}
+	}
+
+	s.nextIdempotentID++
+	stored := *record
+	stored.ID = s.nextIdempotentID
+	stored.Status = 0
+	stored.Header = nil
+	stored.Body = nil
+	stored.CreatedAt = now
+	stored.ExpiresAt = record.ExpiresAt.UTC().Truncate(time.Second)
+	s.idempotency[stored.ID] = stored
+
+	*record = stored
+	return stored, nil
+}
+
+// CompleteIdempotencyKey stores the response to a reserved key's request
+func (s *MemoryStore) CompleteIdempotencyKey(id, status int, header map[string][]string, body []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.idempotency[id]
+	if !ok {
+		return ErrNotFound
+	}
+	stored.Status = status
+	stored.Header = maps.Clone(header)
+	stored.Body = slices.Clone(body)
+	s.idempotency[id] = stored
+	return nil
+}
+
+// ReleaseIdempotencyKey deletes a reserved key so the request can be retried
+func (s *MemoryStore) ReleaseIdempotencyKey(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.idempotency, id)
+	return nil
+}
+
+// copyIdempotencyRecord returns a record that shares no memory with the
+// stored one
+func copyIdempotencyRecord(record models.IdempotencyRecord) models.IdempotencyRecord {
+	record.Header = maps.Clone(record.Header)
+	record.Body = slices.Clone(record.Body)
+	return record
+}
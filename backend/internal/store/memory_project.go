@@ -0,0 +1,349 @@
+package store
+
+import (
+	"sort"
+	"strings"
+
+	"todo-list-app/internal/models"
+)
+
+// projectRole returns the user's role on a project; the caller holds the
+// lock
+func (s *MemoryStore) projectRole(projectID, userID int) (string, bool) {
+	project, ok := s.projects[projectID]
+	if !ok {
+		return "", false
+	}
+	if project.UserID == userID {
+		return models.RoleOwner, true
+	}
+	member, ok := s.members[projectID][userID]
+	return member.Role, ok
+}
+
+// projectFor returns a copy of a stored project as seen by the user, with
+// the user's role and its open todo count filled in; the caller holds the
+// lock
+func (s *MemoryStore) projectFor(project models.Project, userID int) models.Project {
+	project.Role, _ = s.projectRole(project.ID, userID)
+	project.TodoCount = 0
+	for _, todo := range s.todos {
+		if !todo.Completed && todo.DeletedAt == nil && inProject(todo, project.ID) {
+			project.TodoCount++
+		}
+	}
+	return project
+}
+
+// ListProjects returns the projects the user has a role on, in sort order
+func (s *MemoryStore) ListProjects(userID int, includeArchived bool) ([]models.Project, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	projects := []models.Project{}
+	for _, project := range s.projects {
+		if _, ok := s.projectRole(project.ID, userID); ok && (includeArchived || !project.Archived) {
+			projects = append(projects, s.projectFor(project, userID))
+		}
+	}
+	sort.Slice(projects, func(i, j int) bool {
+		if projects[i].SortOrder != projects[j].SortOrder {
+			return projects[i].SortOrder < projects[j].SortOrder
+		}
+		if a, b := strings.ToLower(projects[i].Name), strings.ToLower(projects[j].Name); a != b {
+			return a < b
+		}
+		return projects[i].ID < projects[j].ID
+	})
+	return projects, nil
+}
+
+// GetProject loads a project the user has a role on
+func (s *MemoryStore) GetProject(id, userID int) (models.Project, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.projectRole(id, userID); !ok {
+		return models.Project{}, ErrNotFound
+	}
+	return s.projectFor(s.projects[id], userID), nil
+}
+
+// CreateProject creates a new project
+func (s *MemoryStore) CreateProject(project *models.Project) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextProjectID++
+	now := s.timestamp()
+	stored := *project
+	stored.ID = s.nextProjectID
+	stored.CreatedAt = now
+	stored.UpdatedAt = now
+	s.projects[stored.ID] = stored
+
+	*project = s.projectFor(stored, stored.UserID)
+	return nil
+}
+
+// UpdateProject saves the editable fields of a project
+func (s *MemoryStore) UpdateProject(project *models.Project) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.projects[project.ID]
+	if !ok {
+		return ErrNotFound
+	}
+
+	stored.Name = project.Name
+	stored.Color = project.Color
+	stored.Archived = project.Archived
+	stored.SortOrder = project.SortOrder
+	stored.UpdatedAt = s.timestamp()
+	s.projects[stored.ID] = stored
+
+	// The requesting user's role is unchanged
+	role := project.Role
+	*project = s.projectFor(stored, stored.UserID)
+	project.Role = role
+	return nil
+}
+
+// DeleteProject deletes a project, moving its todos to the inbox and, if
+// requested, to the trash. Both are recorded in the todos' history as
+// changes by actorID.
+func (s *MemoryStore) DeleteProject(id, actorID int, deleteTodos bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.projects[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.projects, id)
+	delete(s.members, id)
+	for inviteID, invitation := range s.invitations {
+		if invitation.ProjectID == id {
+			delete(s.invitations, inviteID)
+		}
+	}
+
+	before := s.todoStates(func(todo models.Todo) bool { return inProject(todo, id) })
+	trashed := map[int]models.TodoState{}
+	now := s.timestamp()
+	var ids []int
+	for todoID, todo := range s.todos {
+		if !inProject(todo, id) {
+			continue
+		}
+		if deleteTodos && todo.DeletedAt == nil {
+			todo.DeletedAt = copyTime(&now)
+			trashed[todoID] = before[todoID]
+			delete(before, todoID)
+		}
+		todo.ProjectID = nil
+		todo.Version++
+		todo.UpdatedAt = now
+		s.todos[todoID] = todo
+		s.unassignStale(todoID)
+		ids = append(ids, todoID)
+	}
+	s.syncTodos(ids...)
+
+	// Todos going to the trash are recorded as deleted, the others as
+	// moved to the inbox
+	if err := s.recordChanges(actorID, models.RevisionDelete, trashed); err != nil {
+		return err
+	}
+	return s.recordChanges(actorID, models.RevisionUpdate, before)
+}
+
+// ListMembers returns the project's creator followed by its members
+func (s *MemoryStore) ListMembers(projectID int) ([]models.ProjectMember, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	project, ok := s.projects[projectID]
+	if !ok {
+		return []models.ProjectMember{}, nil
+	}
+
+	members := []models.ProjectMember{}
+	for _, member := range s.members[projectID] {
+		member.Email = s.users[member.UserID].Email
+		members = append(members, member)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if !members[i].CreatedAt.Equal(members[j].CreatedAt) {
+			return members[i].CreatedAt.Before(members[j].CreatedAt)
+		}
+		return members[i].UserID < members[j].UserID
+	})
+
+	creator := models.ProjectMember{
+		ProjectID: projectID,
+		UserID:    project.UserID,
+		Email:     s.users[project.UserID].Email,
+		Role:      models.RoleOwner,
+		CreatedAt: project.CreatedAt,
+	}
+	return append([]models.ProjectMember{creator}, members...), nil
+}
+
+// SetMemberRole changes the role of a member
+func (s *MemoryStore) SetMemberRole(projectID, userID int, role string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	member, ok := s.members[projectID][userID]
+	if !ok {
+		return ErrNotFound
+	}
+	member.Role = role
+	s.members[projectID][userID] = member
+	return nil
+}
+
+// DeleteMember removes a member from a project and unassigns them from its
+// todos, recording that in the todos' history as a change by actorID
+func (s *MemoryStore) DeleteMember(projectID, userID, actorID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.members[projectID][userID]; !ok {
+		return ErrNotFound
+	}
+	delete(s.members[projectID], userID)
+	before := s.todoStates(func(todo models.Todo) bool {
+		return inProject(todo, projectID) && todo.AssigneeID != nil
+	})
+	var unassigned []int
+	for todoID, todo := range s.todos {
+		if inProject(todo, projectID) {
+			if assignedTo(todo, userID) {
+				unassigned = append(unassigned, todoID)
+			}
+			s.unassignStale(todoID)
+		}
+	}
+	s.syncTodos(unassigned...)
+	s.syncProjectTodos(projectID, userID)
+	return s.recordChanges(actorID, models.RevisionUpdate, before)
+}
+
+// invitationWithName returns a copy of a stored invitation with its
+// project's name filled in; the caller holds the lock
+func (s *MemoryStore) invitationWithName(invitation models.ProjectInvitation) models.ProjectInvitation {
+	invitation.ProjectName = s.projects[invitation.ProjectID].Name
+	return invitation
+}
+
+// listInvitations returns the invitations matching a condition, oldest
+// first; the caller holds the lock
+func (s *MemoryStore) listInvitations(match func(models.ProjectInvitation) bool) []models.ProjectInvitation {
+	invitations := []models.ProjectInvitation{}
+	for _, invitation := range s.invitations {
+		if match(invitation) {
+			invitations = append(invitations, s.invitationWithName(invitation))
+		}
+	}
+	sort.Slice(invitations, func(i, j int) bool {
+		if !invitations[i].CreatedAt.Equal(invitations[j].CreatedAt) {
+			return invitations[i].CreatedAt.Before(invitations[j].CreatedAt)
+		}
+		return invitations[i].ID < invitations[j].ID
+	})
+	return invitations
+}
+
+// ListProjectInvitations returns a project's open invitations
+func (s *MemoryStore) ListProjectInvitations(projectID int) ([]models.ProjectInvitation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.listInvitations(func(invitation models.ProjectInvitation) bool {
+		return invitation.ProjectID == projectID
+	}), nil
+}
+
+// ListUserInvitations returns the open invitations for an email address
+func (s *MemoryStore) ListUserInvitations(email string) ([]models.ProjectInvitation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.listInvitations(func(invitation models.ProjectInvitation) bool {
+		return invitation.Email == email
+	}), nil
+}
+
+// GetInvitation loads an open invitation
+func (s *MemoryStore) GetInvitation(id int) (models.ProjectInvitation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	invitation, ok := s.invitations[id]
+	if !ok {
+		return models.ProjectInvitation{}, ErrNotFound
+	}
+	return s.invitationWithName(invitation), nil
+}
+
+// CreateInvitation stores a new invitation
+func (s *MemoryStore) CreateInvitation(invitation *models.ProjectInvitation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.invitations {
+		if existing.ProjectID == invitation.ProjectID && existing.Email == invitation.Email {
+			return ErrConflict
+		}
+	}
+
+	s.nextInviteID++
+	stored := *invitation
+	stored.ID = s.nextInviteID
+	stored.CreatedAt = s.timestamp()
+	s.invitations[stored.ID] = stored
+
+	*invitation = s.invitationWithName(stored)
+	return nil
+}
+
+// AcceptInvitation turns an invitation into a membership
+func (s *MemoryStore) AcceptInvitation(id, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	invitation, ok := s.invitations[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if _, ok := s.projectRole(invitation.ProjectID, userID); ok {
+		return ErrConflict
+	}
+
+	if s.members[invitation.ProjectID] == nil {
+		s.members[invitation.ProjectID] = make(map[int]models.ProjectMember)
+	}
+	s.members[invitation.ProjectID][userID] = models.ProjectMember{
+		ProjectID: invitation.ProjectID,
+		UserID:    userID,
+		Role:      invitation.Role,
+		CreatedAt: s.timestamp(),
+	}
+	delete(s.invitations, id)
+	s.syncProjectTodos(invitation.ProjectID, userID)
+	return nil
+}
+
+// DeleteInvitation revokes or declines an invitation
+func (s *MemoryStore) DeleteInvitation(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.invitations[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.invitations, id)
+	return nil
+}
@@ -0,0 +1,108 @@
+package store
+
+import (
+	"sort"
+
+	"todo-list-app/internal/models"
+)
+
+// revisionWithActor returns a copy of a stored revision with the actor's
+// email filled in; the caller holds the lock
+func (s *MemoryStore) revisionWithActor(revision models.TodoRevision) models.TodoRevision {
+	revision.ActorEmail = s.users[revision.UserID].Email
+	return revision
+}
+
+// ListRevisions returns a todo's history, oldest first
+func (s *MemoryStore) ListRevisions(todoID int) ([]models.TodoRevision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	revisions := []models.TodoRevision{}
+	for _, revision := range s.revisions {
+		if revision.TodoID == todoID {
+			revisions = append(revisions, s.revisionWithActor(revision))
+		}
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Revision < revisions[j].Revision
+	})
+	return revisions, nil
+}
+
+// GetRevision loads one revision of a todo
+func (s *MemoryStore) GetRevision(todoID, revision int) (models.TodoRevision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, stored := range s.revisions {
+		if stored.TodoID == todoID && stored.Revision == revision {
+			return s.revisionWithActor(stored), nil
+		}
+	}
+	return models.TodoRevision{}, ErrNotFound
+}
+
+// addRevision appends a revision, numbering it after the todo's latest and
+// taking the todo's current version; the caller holds the lock
+func (s *MemoryStore) addRevision(revision *models.TodoRevision) error {
+	number := 1
+	for _, stored := range s.revisions {
+		if stored.TodoID == revision.TodoID && stored.Revision >= number {
+			number = stored.Revision + 1
+		}
+	}
+
+	s.nextRevisionID++
+	stored := *revision
+	stored.ID = s.nextRevisionID
+	stored.Revision = number
+	stored.Version = s.todos[revision.TodoID].Version
+	stored.CreatedAt = s.timestamp()
+	s.revisions[stored.ID] = stored
+	return nil
+}
+
+// subtreeStates returns the tracked state of a todo and of its subtasks,
+// at every level, leaving out those in the trash; the caller holds the lock
+func (s *MemoryStore) subtreeStates(id int) map[int]models.TodoState {
+	states := map[int]models.TodoState{}
+	for _, todoID := range s.subtreeIDs(id) {
+		if todo := s.todos[todoID]; todo.DeletedAt == nil {
+			states[todoID] = StateOf(s.withDetails(todo))
+		}
+	}
+	return states
+}
+
+// todoStates returns the tracked state of the todos keep selects, by ID;
+// the caller holds the lock
+func (s *MemoryStore) todoStates(keep func(models.Todo) bool) map[int]models.TodoState {
+	states := map[int]models.TodoState{}
+	for id, todo := range s.todos {
+		if keep(todo) {
+			states[id] = StateOf(s.withDetails(todo))
+		}
+	}
+	return states
+}
+
+// recordChanges records action by actorID in the history of each todo in
+// before, diffed against its state now. Updates are only recorded where a
+// tracked field changed. The caller holds the lock.
+func (s *MemoryStore) recordChanges(actorID int, action string, before map[int]models.TodoState) error {
+	after := s.todoStates(func(todo models.Todo) bool {
+		_, ok := before[todo.ID]
+		return ok
+	})
+	return recordRevisions(s.addRevision, actorID, 0, "", action, before, after, nil)
+}
+
+// deleteRevisions removes a todo's history; the caller holds the lock
+func (s *MemoryStore) deleteRevisions(todoID int) {
+	for id, revision := range s.revisions {
+		if revision.TodoID == todoID {
+			delete(s.revisions, id)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package store
+
+import (
+	"sort"
+	"time"
+
+	"todo-list-app/internal/models"
+)
+
+// memorySession is a stored session together with the hash of its secret
+type memorySession struct {
+	session models.Session
+	hash    string
+}
+
+// ListSessions returns the user's unexpired sessions, most recently seen first
+func (s *MemoryStore) ListSessions(userID int, now time.Time) ([]models.Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sessions := []models.Session{}
+	for _, stored := range s.sessions {
+		if stored.session.UserID == userID && stored.session.ExpiresAt.After(now) {
+			sessions = append(sessions, stored.session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].LastSeenAt.Equal(sessions[j].LastSeenAt) {
+			return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+		}
+		return sessions[i].ID > sessions[j].ID
+	})
+	return sessions, nil
+}
+
+// CreateSession stores a session under the hash of its secret and removes
+// the user's expired sessions
+func (s *MemoryStore) CreateSession(session *models.Session, hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.timestamp()
+	for id, stored := range s.sessions {
+		if stored.session.UserID == session.UserID && !stored.session.ExpiresAt.After(now) {
+			delete(s.sessions, id)
+		}
+	}
+
+	s.nextSessionID++
+	stored := *session
+	stored.ID = s.nextSessionID
+	stored.CreatedAt = now
+	stored.LastSeenAt = now
+	stored.ExpiresAt = session.ExpiresAt.UTC().Truncate(time.Second)
+	stored.Current = false
+	s.sessions[stored.ID] = memorySession{session: stored, hash: hash}
+
+	*session = stored
+	return nil
+}
+
+// GetSessionByHash returns the session whose secret has the given hash
+func (s *MemoryStore) GetSessionByHash(hash string) (models.Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, stored := range s.sessions {
+		if stored.hash == hash {
+			return stored.session, nil
+		}
+	}
+	return models.Session{}, ErrNotFound
+}
+
+// TouchSession records when a session was last seen
+func (s *MemoryStore) TouchSession(id int, seenAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.sessions[id]
+	if !ok {
+		return ErrNotFound
+	}
+	stored.session.LastSeenAt = seenAt.UTC().Truncate(time.Second)
+	s.sessions[id] = stored
+	return nil
+}
+
+// DeleteSession ends one of the user's sessions
+func (s *MemoryStore) DeleteSession(id, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.sessions[id]
+	if !ok || stored.session.UserID != userID {
+		return ErrNotFound
+	}
+	delete(s.sessions, id)
+	return nil
+}
+
+// DeleteUserSessions ends all of the user's sessions
+func (s *MemoryStore) DeleteUserSessions(userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deleteUserSessions(userID)
+	return nil
+}
+
+// deleteUserSessions removes the user's sessions; the caller holds the lock
+func (s *MemoryStore) deleteUserSessions(userID int) {
+	for id, stored := range s.sessions {
+		if stored.session.UserID == userID {
+			delete(s.sessions, id)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package store
+
+import (
+	"slices"
+	"sort"
+)
+
+// syncKey identifies a todo in a user's change feed
+type syncKey struct {
+	userID int
+	todoID int
+}
+
+// syncTodos adds the todos to the change feed of every user who can see
+// them, together with their parents, and a tombstone to the feed of every
+// user who was sent one of them before but can no longer see it; the caller
+// holds the lock
+func (s *MemoryStore) syncTodos(ids ...int) {
+	s.syncFeeds(0, ids)
+}
+
+// syncProjectTodos adds every todo of a project to one user's change feed,
+// after the user joined or left the project; the caller holds the lock
+func (s *MemoryStore) syncProjectTodos(projectID, userID int) {
+	var ids []int
+	for _, todo := range s.todos {
+		if inProject(todo, projectID) {
+			ids = append(ids, todo.ID)
+		}
+	}
+	s.syncFeeds(userID, ids)
+}
+
+// syncFeeds does the work of syncTodos for one user's feed, or for every
+// user's if userID is 0; the caller holds the lock
+func (s *MemoryStore) syncFeeds(userID int, ids []int) {
+	synced := slices.Clone(ids)
+	for _, id := range ids {
+		if todo, ok := s.todos[id]; ok && todo.ParentID != nil {
+			synced = append(synced, *todo.ParentID)
+		}
+	}
+	slices.Sort(synced)
+	synced = slices.Compact(synced)
+
+	feeds := make(map[int]map[int]bool) // user ID -> todo ID -> deleted
+	add := func(feedUserID, todoID int, deleted bool) {
+		if userID != 0 && feedUserID != userID {
+			return
+		}
+		if feeds[feedUserID] == nil {
+			feeds[feedUserID] = make(map[int]bool)
+		}
+		if _, seen := feeds[feedUserID][todoID]; !seen {
+			feeds[feedUserID][todoID] = deleted
+		}
+	}
+
+	for _, todoID := range synced {
+		todo, ok := s.todos[todoID]
+		if !ok || todo.DeletedAt != nil {
+			continue
+		}
+		if todo.ProjectID == nil {
+			add(todo.UserID, todoID, false)
+			continue
+		}
+		if project, ok := s.projects[*todo.ProjectID]; ok {
+			add(project.UserID, todoID, false)
+		}
+		for memberID := range s.members[*todo.ProjectID] {
+			add(memberID, todoID, false)
+		}
+	}
+	for key, change := range s.syncChanges {
+		if !change.Deleted && slices.Contains(synced, key.todoID) {
+			add(key.userID, key.todoID, true)
+		}
+	}
+
+	for feedUserID, feed := range feeds {
+		todoIDs := make([]int, 0, len(feed))
+		for todoID := range feed {
+			todoIDs = append(todoIDs, todoID)
+		}
+		slices.Sort(todoIDs)
+
+		for _, todoID := range todoIDs {
+			s.syncSeqs[feedUserID]++
+			seq := s.syncSeqs[feedUserID]
+			key := syncKey{feedUserID, todoID}
+			change, ok := s.syncChanges[key]
+			if !ok || change.Deleted {
+				change.CreatedSeq = seq
+			}
+			change.Todo.ID = todoID
+			change.Seq = seq
+			change.Deleted = feed[todoID]
+			s.syncChanges[key] = change
+		}
+	}
+}
+
+// ListSyncChanges returns the entries of the user's change feed after
+// sequence number since, in order
+func (s *MemoryStore) ListSyncChanges(userID, since, limit int) ([]SyncChange, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	changes := []SyncChange{}
+	for key, change := range s.syncChanges {
+		if key.userID == userID && change.Seq > since {
+			changes = append(changes, change)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Seq < changes[j].Seq })
+	if len(changes) > limit {
+		changes = changes[:limit]
+	}
+
+	for i, change := range changes {
+		if change.Deleted {
+			continue
+		}
+		todo := s.todos[change.Todo.ID]
+		changes[i].Todo = s.withDetails(todo)
+	}
+	return changes, nil
+}
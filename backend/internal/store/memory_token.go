@@ -0,0 +1,97 @@
+package store
+
+import (
+	"sort"
+	"time"
+
+	"todo-list-app/internal/models"
+)
+
+// memoryToken is a stored token together with the hash of its secret
+type memoryToken struct {
+	token models.APIToken
+	hash  string
+}
+
+// copyToken returns a token that shares no memory with the stored one
+func copyToken(token models.APIToken) models.APIToken {
+	token.Scopes = append([]string{}, token.Scopes...)
+	return token
+}
+
+// ListTokens returns the user's tokens, newest first
+func (s *MemoryStore) ListTokens(userID int) ([]models.APIToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tokens := []models.APIToken{}
+	for _, stored := range s.tokens {
+		if stored.token.UserID == userID {
+			tokens = append(tokens, copyToken(stored.token))
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		if !tokens[i].CreatedAt.Equal(tokens[j].CreatedAt) {
+			return tokens[i].CreatedAt.After(tokens[j].CreatedAt)
+		}
+		return tokens[i].ID > tokens[j].ID
+	})
+	return tokens, nil
+}
+
+// CreateToken stores a token under the hash of its secret
+func (s *MemoryStore) CreateToken(token *models.APIToken, hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextTokenID++
+	stored := copyToken(*token)
+	stored.ID = s.nextTokenID
+	stored.LastUsedAt = nil
+	stored.CreatedAt = s.timestamp()
+	s.tokens[stored.ID] = memoryToken{token: stored, hash: hash}
+
+	*token = copyToken(stored)
+	return nil
+}
+
+// GetTokenByHash returns the token whose secret has the given hash
+func (s *MemoryStore) GetTokenByHash(hash string) (models.APIToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, stored := range s.tokens {
+		if stored.hash == hash {
+			return copyToken(stored.token), nil
+		}
+	}
+	return models.APIToken{}, ErrNotFound
+}
+
+// TouchToken records when a token was last used
+func (s *MemoryStore) TouchToken(id int, usedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.tokens[id]
+	if !ok {
+		return ErrNotFound
+	}
+	usedAt = usedAt.UTC().Truncate(time.Second)
+	stored.token.LastUsedAt = &usedAt
+	s.tokens[id] = stored
+	return nil
+}
+
+// DeleteToken revokes one of the user's tokens
+func (s *MemoryStore) DeleteToken(id, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.tokens[id]
+	if !ok || stored.token.UserID != userID {
+		return ErrNotFound
+	}
+	delete(s.tokens, id)
+	return nil
+}
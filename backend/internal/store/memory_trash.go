@@ -0,0 +1,133 @@
+package store
+
+import (
+	"sort"
+	"time"
+
+	"todo-list-app/internal/models"
+)
+
+// inTrashOf reports whether a trashed todo shows in the user's trash: its
+// parent, if any, is not trashed and the user can restore it; the caller
+// holds the lock
+func (s *MemoryStore) inTrashOf(todo models.Todo, userID int) bool {
+	if todo.DeletedAt == nil {
+		return false
+	}
+	if todo.ParentID != nil && s.todos[*todo.ParentID].DeletedAt != nil {
+		return false
+	}
+	if todo.ProjectID == nil {
+		return todo.UserID == userID
+	}
+	role, _ := s.projectRole(*todo.ProjectID, userID)
+	return role == models.RoleEditor || role == models.RoleOwner
+}
+
+// ListTrash returns the trashed todos the user can restore, most recently
+// deleted first
+func (s *MemoryStore) ListTrash(userID int) ([]models.Todo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	todos := []models.Todo{}
+	for _, todo := range s.todos {
+		if s.inTrashOf(todo, userID) {
+			todos = append(todos, s.withDetails(todo))
+		}
+	}
+	sort.Slice(todos, func(i, j int) bool {
+		if !todos[i].DeletedAt.Equal(*todos[j].DeletedAt) {
+			return todos[i].DeletedAt.After(*todos[j].DeletedAt)
+		}
+		return todos[i].ID > todos[j].ID
+	})
+	return todos, nil
+}
+
+// GetTrashedTodo returns a todo in the trash
+func (s *MemoryStore) GetTrashedTodo(id int) (models.Todo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	todo, ok := s.todos[id]
+	if !ok || todo.DeletedAt == nil {
+		return models.Todo{}, ErrNotFound
+	}
+	return s.withDetails(todo), nil
+}
+
+// RestoreTodo takes a todo and the subtasks deleted with it out of the
+// trash, recording the restore in their history
+func (s *MemoryStore) RestoreTodo(id, actorID int) (models.Todo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	todo, ok := s.todos[id]
+	if !ok || todo.DeletedAt == nil {
+		return models.Todo{}, ErrNotFound
+	}
+	if todo.ParentID != nil && s.todos[*todo.ParentID].DeletedAt != nil {
+		return models.Todo{}, ErrConflict
+	}
+
+	deletedAt := *todo.DeletedAt
+	subtree := s.subtreeIDs(id)
+	states := map[int]models.TodoState{}
+	for _, todoID := range subtree {
+		if stored := s.todos[todoID]; stored.DeletedAt != nil && stored.DeletedAt.Equal(deletedAt) {
+			stored.DeletedAt = nil
+			stored.Version++
+			s.todos[todoID] = stored
+			states[todoID] = StateOf(s.withDetails(stored))
+		}
+	}
+	s.touchParents(subtree...)
+	s.syncTodos(subtree...)
+	if err := recordRevisions(s.addRevision, actorID, id, models.RevisionRestore, models.RevisionRestore, states, states, nil); err != nil {
+		return models.Todo{}, err
+	}
+	return s.withDetails(s.todos[id]), nil
+}
+
+// EmptyTrash permanently deletes the user's trash
+func (s *MemoryStore) EmptyTrash(userID int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ids []int
+	for _, todo := range s.todos {
+		if s.inTrashOf(todo, userID) {
+			ids = append(ids, s.subtreeIDs(todo.ID)...)
+		}
+	}
+	s.purgeTodos(ids)
+	return len(ids), nil
+}
+
+// PurgeTrash permanently deletes the todos trashed before the cutoff
+func (s *MemoryStore) PurgeTrash(before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ids []int
+	for _, todo := range s.todos {
+		if todo.DeletedAt != nil && todo.DeletedAt.Before(before) {
+			ids = append(ids, todo.ID)
+		}
+	}
+	s.purgeTodos(ids)
+	return len(ids), nil
+}
+
+// purgeTodos permanently deletes todos with their tag links, comments,
+// history and attachments; the caller holds the lock
+func (s *MemoryStore) purgeTodos(ids []int) {
+	for _, todoID := range ids {
+		delete(s.todos, todoID)
+		delete(s.todoTags, todoID)
+		s.deleteComments(todoID)
+		s.deleteRevisions(todoID)
+		s.deleteAttachments(todoID)
+	}
+}
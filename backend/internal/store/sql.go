@@ -0,0 +1,977 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"html"
+	"log"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"todo-list-app/internal/blob"
+	"todo-list-app/internal/database"
+	"todo-list-app/internal/models"
+)
+
+// SQLStore implements Store on top of the schema managed by the database
+// package. Queries are written with ? placeholders and SQLite syntax where
+// the dialects agree; the rest goes through database.Dialect.
+type SQLStore struct {
+	db            *database.DB
+	dialect       database.Dialect
+	searchEnabled bool
+
+	// blobs keeps attachment content; blobMu serializes storing and
+	// releasing blobs so that a blob shared by two attachments is never
+	// removed while one of them is being created
+	blobs  blob.Store
+	blobMu sync.Mutex
+}
+
+// NewSQLStore creates a store backed by an initialized database
+func NewSQLStore(db *database.DB) *SQLStore {
+	searchEnabled, err := database.HasTable(db, "todos_fts")
+	if err != nil {
+		log.Printf("Warning: Failed to check for search index: %v", err)
+	}
+	return &SQLStore{db: db, dialect: db.Dialect, searchEnabled: searchEnabled}
+}
+
+// queryer is satisfied by both *database.DB and *database.Tx
+type queryer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+	Insert(query string, args ...interface{}) (int, error)
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// todoColumns lists the columns read by scanTodo, in order
+const todoColumns = "id, user_id, parent_id, project_id, assignee_id, assigned_by, assigned_at, title, description, completed, priority, due_at, all_day, recurrence, timezone, version, created_at, updated_at, deleted_at"
+
+// scanTodo scans a row selected with todoColumns into a todo. Any extra
+// destinations receive the columns selected after todoColumns.
+func scanTodo(row rowScanner, extra ...interface{}) (models.Todo, error) {
+	var todo models.Todo
+	dest := []interface{}{
+		&todo.ID, &todo.UserID, &todo.ParentID, &todo.ProjectID,
+		&todo.AssigneeID, &todo.AssignedBy, &todo.AssignedAt, &todo.Title, &todo.Description,
+		&todo.Completed, &todo.Priority, &todo.DueAt, &todo.AllDay,
+		&todo.Recurrence, &todo.Timezone, &todo.Version, &todo.CreatedAt, &todo.UpdatedAt, &todo.DeletedAt,
+	}
+	err := row.Scan(append(dest, extra...)...)
+	return todo, err
+}
+
+// nullableTime formats an optional time for storage, keeping NULL as NULL
+func nullableTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return formatTime(*t)
+}
+
+// unassignStale unassigns the todos matching where whose assignee can no
+// longer see them
+func unassignStale(q queryer, where string, args ...interface{}) error {
+	_, err := q.Exec(`
+		UPDATE todos SET assignee_id = NULL, assigned_by = NULL, assigned_at = NULL, version = version + 1
+		WHERE `+where+` AND assignee_id IS NOT NULL
+			AND NOT ((project_id IS NULL AND assignee_id = user_id) OR assignee_id IN (
+				SELECT user_id FROM projects WHERE id = todos.project_id
+				UNION SELECT user_id FROM project_members WHERE project_id = todos.project_id))
+	`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to unassign todos: %w", err)
+	}
+	return nil
+}
+
+// visibleClause builds the WHERE condition that restricts todos to those
+// a user can see: their inbox and the todos of projects they have a role
+// on, leaving out the trash
+func visibleClause(table string, userID int) (string, []interface{}) {
+	clause := fmt.Sprintf(`%[1]s.deleted_at IS NULL AND ((%[1]s.project_id IS NULL AND %[1]s.user_id = ?) OR %[1]s.project_id IN (
+		SELECT id FROM projects WHERE user_id = ?
+		UNION SELECT project_id FROM project_members WHERE user_id = ?))`, table)
+	return clause, []interface{}{userID, userID, userID}
+}
+
+// ListTodos returns the todos matching the query
+func (s *SQLStore) ListTodos(q TodoQuery) ([]models.Todo, error) {
+	clause, args := visibleClause("todos", q.UserID)
+	query := "SELECT " + todoColumns + " FROM todos WHERE " + clause
+
+	if q.Project != nil {
+		if *q.Project == 0 {
+			query += " AND project_id IS NULL"
+		} else {
+			query += " AND project_id = ?"
+			args = append(args, *q.Project)
+		}
+	}
+
+	if q.Assignee != nil {
+		if *q.Assignee == 0 {
+			query += " AND assignee_id IS NULL"
+		} else {
+			query += " AND assignee_id = ?"
+			args = append(args, *q.Assignee)
+		}
+	}
+
+	if q.Completed != nil {
+		query += " AND completed = ?"
+		args = append(args, *q.Completed)
+	}
+
+	if q.Due != nil {
+		clause, clauseArgs := dueClause(*q.Due)
+		query += " AND " + clause
+		args = append(args, clauseArgs...)
+	}
+
+	if len(q.Tags) > 0 {
+		clause, clauseArgs := tagClause(s.dialect, q.Tags, q.MatchAllTags)
+		query += " AND " + clause
+		args = append(args, clauseArgs...)
+	}
+
+	column, param, nullable, bind := sortColumn(s.dialect, q.Sort)
+	if q.After != nil {
+		clause, clauseArgs, err := keysetClause(column, param, nullable, bind, q.Desc, *q.After)
+		if err != nil {
+			return nil, err
+		}
+		query += " AND " + clause
+		args = append(args, clauseArgs...)
+	}
+
+	dir := "ASC"
+	if q.Desc {
+		dir = "DESC"
+	}
+	if nullable {
+		query += fmt.Sprintf(" ORDER BY %s IS NULL, %s %s, id %s", column, column, dir, dir)
+	} else {
+		query += fmt.Sprintf(" ORDER BY %s %s, id %s", column, dir, dir)
+	}
+
+	if q.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, q.Limit)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	todos := []models.Todo{}
+	for rows.Next() {
+		todo, err := scanTodo(rows)
+		if err != nil {
+			return nil, err
+		}
+		todos = append(todos, todo)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if err := loadTodoDetails(s.db, todos); err != nil {
+		return nil, err
+	}
+	return todos, nil
+}
+
+// dueClause builds the WHERE condition for a due date filter
+func dueClause(f DueFilter) (string, []interface{}) {
+	var args []interface{}
+	timed := "all_day = FALSE"
+	if !f.TimedFrom.IsZero() {
+		timed += " AND due_at >= ?"
+		args = append(args, formatTime(f.TimedFrom))
+	}
+	if !f.TimedTo.IsZero() {
+		timed += " AND due_at < ?"
+		args = append(args, formatTime(f.TimedTo))
+	}
+	date := "all_day = TRUE"
+	if !f.DateFrom.IsZero() {
+		date += " AND due_at >= ?"
+		args = append(args, formatTime(f.DateFrom))
+	}
+	if !f.DateTo.IsZero() {
+		date += " AND due_at < ?"
+		args = append(args, formatTime(f.DateTo))
+	}
+
+	clause := "due_at IS NOT NULL AND ((" + timed + ") OR (" + date + "))"
+	if f.OpenOnly {
+		clause = "completed = FALSE AND " + clause
+	}
+	return clause, args
+}
+
+// tagClause builds the WHERE condition for a tag filter. Without matchAll a
+// todo matches if it has at least one of the tags; with it, every one. A
+// todo only carries tags of the user who created it, so names are matched
+// whoever that is.
+func tagClause(d database.Dialect, names []string, matchAll bool) (string, []interface{}) {
+	var args []interface{}
+	for _, name := range names {
+		args = append(args, name)
+	}
+
+	subquery := `
+		SELECT tt.todo_id FROM todo_tags tt
+		JOIN tags t ON t.id = tt.tag_id
+		WHERE ` + d.Fold("t.name") + ` IN (` + foldedPlaceholders(d, len(names)) + `)`
+
+	if matchAll {
+		args = append(args, len(names))
+		return "id IN (" + subquery + " GROUP BY tt.todo_id HAVING COUNT(DISTINCT t.id) = ?)", args
+	}
+	return "id IN (" + subquery + ")", args
+}
+
+// sortColumn maps a sort to its SQL expression, the expression cursor
+// values are compared through, whether it can be NULL and how cursor values
+// are bound for comparison
+func sortColumn(d database.Dialect, sort TodoSort) (string, string, bool, func(string) (interface{}, error)) {
+	bindString := func(v string) (interface{}, error) { return v, nil }
+	switch sort {
+	case SortPriority:
+		return "priority", "?", false, func(v string) (interface{}, error) { return strconv.Atoi(v) }
+	case SortUpdatedAt:
+		return "updated_at", "?", false, bindString
+	case SortDueAt:
+		return "due_at", "?", true, bindString
+	case SortTitle:
+		return d.Fold("title"), d.Fold("?"), false, bindString
+	default:
+		return "created_at", "?", false, bindString
+	}
+}
+
+// keysetClause selects the rows after the cursor. NULL sort keys always
+// come last, whichever the direction.
+func keysetClause(column, param string, nullable bool, bind func(string) (interface{}, error), desc bool, after TodoCursor) (string, []interface{}, error) {
+	cmp := ">"
+	if desc {
+		cmp = "<"
+	}
+
+	if after.Value == nil {
+		if !nullable {
+			return "", nil, ErrInvalidCursor
+		}
+		return fmt.Sprintf("(%s IS NULL AND id %s ?)", column, cmp), []interface{}{after.ID}, nil
+	}
+
+	value, err := bind(*after.Value)
+	if err != nil {
+		return "", nil, ErrInvalidCursor
+	}
+
+	clause := fmt.Sprintf("(%s %s %s OR (%s = %s AND id %s ?))", column, cmp, param, column, param, cmp)
+	if nullable {
+		clause = fmt.Sprintf("(%s IS NULL OR %s)", column, clause)
+	}
+	return clause, []interface{}{value, value, after.ID}, nil
+}
+
+// GetTodo loads a single todo by ID, including its tags. Todos in the
+// trash are not found.
+func (s *SQLStore) GetTodo(id int) (models.Todo, error) {
+	return getTodo(s.db, id)
+}
+
+func getTodo(q queryer, id int) (models.Todo, error) {
+	todo, err := scanTodo(q.QueryRow("SELECT "+todoColumns+" FROM todos WHERE id = ? AND deleted_at IS NULL", id))
+	if err == sql.ErrNoRows {
+		return todo, ErrNotFound
+	} else if err != nil {
+		return todo, err
+	}
+
+	todos := []models.Todo{todo}
+	if err := loadTodoDetails(q, todos); err != nil {
+		return todo, err
+	}
+	return todos[0], nil
+}
+
+// insertTodo inserts a todo with its tags and returns it as stored
+func insertTodo(q queryer, d database.Dialect, todo *models.Todo) (models.Todo, error) {
+	todoID, err := q.Insert(`
+		INSERT INTO todos (user_id, parent_id, project_id, assignee_id, assigned_by, assigned_at,
+			title, description, priority, due_at, all_day, recurrence, timezone)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, todo.UserID, todo.ParentID, todo.ProjectID, todo.AssigneeID, todo.AssignedBy, nullableTime(todo.AssignedAt),
+		todo.Title, todo.Description, todo.Priority, nullableTime(todo.DueAt), todo.AllDay, todo.Recurrence, todo.Timezone)
+	if err != nil {
+		return models.Todo{}, fmt.Errorf("failed to insert todo: %w", err)
+	}
+
+	if err := setTodoTags(q, d, todo.UserID, todoID, todo.Tags); err != nil {
+		return models.Todo{}, err
+	}
+	if err := touchParents(q, todoID); err != nil {
+		return models.Todo{}, err
+	}
+	if err := syncTodos(q, todoID); err != nil {
+		return models.Todo{}, err
+	}
+	return getTodo(q, todoID)
+}
+
+// spawnOccurrence clears the recurrence of a completed todo and creates
+// the next occurrence. It returns ErrConflict if the todo has no rule left
+// to hand over.
+func spawnOccurrence(q queryer, d database.Dialect, completedID int, next *models.Todo) (models.Todo, error) {
+	// Clearing the rule first means a todo that is reopened and completed
+	// again does not spawn a second occurrence
+	result, err := q.Exec(`
+		UPDATE todos SET recurrence = NULL, version = version + 1, updated_at = `+d.Now()+`
+		WHERE id = ? AND recurrence IS NOT NULL
+	`, completedID)
+	if err != nil {
+		return models.Todo{}, fmt.Errorf("failed to clear recurrence: %w", err)
+	}
+	if rowsAffected, err := result.RowsAffected(); err != nil {
+		return models.Todo{}, err
+	} else if rowsAffected == 0 {
+		return models.Todo{}, ErrConflict
+	}
+	if err := syncTodos(q, completedID); err != nil {
+		return models.Todo{}, err
+	}
+
+	return insertTodo(q, d, next)
+}
+
+// updateTodo saves the editable fields of a todo and optionally its tags,
+// provided the todo is still at todo.Version, and reloads it
+func updateTodo(q queryer, d database.Dialect, todo *models.Todo) error {
+	// A parent the todo moves away from loses a subtask
+	synced, err := queryIDs(q, "SELECT id FROM todos WHERE id = ? OR id IN (SELECT parent_id FROM todos WHERE id = ?)",
+		todo.ID, todo.ID)
+	if err != nil {
+		return err
+	}
+	var parentID sql.NullInt64
+	if err := q.QueryRow("SELECT parent_id FROM todos WHERE id = ?", todo.ID).Scan(&parentID); err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	// A subtask follows its parent's project, even one the parent moved to
+	// after the subtask was loaded
+	if todo.ParentID != nil {
+		err := q.QueryRow("SELECT project_id FROM todos WHERE id = ?", *todo.ParentID).Scan(&todo.ProjectID)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+	}
+
+	result, err := q.Exec(`
+		UPDATE todos
+		SET parent_id = ?, project_id = ?, assignee_id = ?, assigned_by = ?, assigned_at = ?,
+			title = ?, description = ?, priority = ?, due_at = ?, all_day = ?, recurrence = ?, timezone = ?,
+			version = version + 1, updated_at = `+d.Now()+`
+		WHERE id = ? AND version = ?
+	`, todo.ParentID, todo.ProjectID, todo.AssigneeID, todo.AssignedBy, nullableTime(todo.AssignedAt),
+		todo.Title, todo.Description, todo.Priority, nullableTime(todo.DueAt), todo.AllDay, todo.Recurrence, todo.Timezone,
+		todo.ID, todo.Version)
+	if err != nil {
+		return fmt.Errorf("failed to update todo: %w", err)
+	}
+
+	if err := checkVersioned(q, result, todo.ID); err != nil {
+		return err
+	}
+	if reparented := parentID.Valid != (todo.ParentID != nil) ||
+		(parentID.Valid && int(parentID.Int64) != *todo.ParentID); reparented {
+		if parentID.Valid {
+			if err := touchTodos(q, parentID.Int64); err != nil {
+				return err
+			}
+		}
+		if err := touchParents(q, todo.ID); err != nil {
+			return err
+		}
+	}
+
+	if todo.Tags != nil {
+		if err := setTodoTags(q, d, todo.UserID, todo.ID, todo.Tags); err != nil {
+			return err
+		}
+	}
+
+	// Subtasks always live in their parent's project
+	ids, err := subtreeIDs(q, todo.ID)
+	if err != nil {
+		return err
+	}
+	moved, args := "project_id IS NOT NULL", []interface{}{todo.ProjectID}
+	if todo.ProjectID != nil {
+		moved, args = "(project_id IS NULL OR project_id <> ?)", append(args, *todo.ProjectID)
+	}
+	result, err = q.Exec(
+		"UPDATE todos SET project_id = ?, version = version + 1 WHERE "+moved+" AND id IN ("+placeholders(len(ids))+")",
+		append(args, ids...)...,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to move subtasks: %w", err)
+	}
+	if rowsAffected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rowsAffected > 0 {
+		synced = append(synced, ids...)
+	}
+	if err := unassignStale(q, "id IN ("+placeholders(len(ids))+")", ids...); err != nil {
+		return err
+	}
+	if err := syncTodos(q, synced...); err != nil {
+		return err
+	}
+
+	updated, err := getTodo(q, todo.ID)
+	if err != nil {
+		return err
+	}
+	*todo = updated
+	return nil
+}
+
+// setCompleted sets the completed flag of a todo, provided it is still at
+// version
+func setCompleted(q queryer, d database.Dialect, id, version int, completed bool) error {
+	result, err := q.Exec(`
+		UPDATE todos
+		SET completed = ?, version = version + 1, updated_at = `+d.Now()+`
+		WHERE id = ? AND version = ?
+	`, completed, id, version)
+	if err != nil {
+		return fmt.Errorf("failed to toggle todo: %w", err)
+	}
+
+	if err := checkVersioned(q, result, id); err != nil {
+		return err
+	}
+	if err := touchParents(q, id); err != nil {
+		return err
+	}
+	return syncTodos(q, id)
+}
+
+// checkVersioned tells why an update guarded by a todo's version changed
+// nothing: ErrConflict if the todo is still there, ErrNotFound otherwise
+func checkVersioned(q queryer, result sql.Result, id int) error {
+	if rowsAffected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rowsAffected > 0 {
+		return nil
+	}
+
+	var exists int
+	if err := q.QueryRow("SELECT COUNT(*) FROM todos WHERE id = ?", id).Scan(&exists); err != nil {
+		return err
+	} else if exists > 0 {
+		return ErrConflict
+	}
+	return ErrNotFound
+}
+
+// completeSubtree marks a todo and all of its subtasks completed, provided
+// the todo is still at version
+func completeSubtree(q queryer, d database.Dialect, id, version int) error {
+	ids, err := subtreeIDs(q, id)
+	if err != nil {
+		return err
+	} else if len(ids) == 0 {
+		return ErrNotFound
+	}
+
+	result, err := q.Exec(`
+		UPDATE todos
+		SET completed = TRUE, version = version + 1, updated_at = `+d.Now()+`
+		WHERE id = ? AND version = ? AND deleted_at IS NULL
+	`, id, version)
+	if err != nil {
+		return fmt.Errorf("failed to complete todo: %w", err)
+	}
+	if err := checkVersioned(q, result, id); err != nil {
+		return err
+	}
+	if _, err := q.Exec(`
+		UPDATE todos
+		SET completed = TRUE, version = version + 1, updated_at = `+d.Now()+`
+		WHERE completed = FALSE AND deleted_at IS NULL AND id IN (`+placeholders(len(ids))+`)
+	`, ids...); err != nil {
+		return fmt.Errorf("failed to complete subtasks: %w", err)
+	}
+	if err := touchParents(q, ids...); err != nil {
+		return err
+	}
+	return syncTodos(q, ids...)
+}
+
+// trashTodo moves a todo owned by the user to the trash along with the
+// subtasks that are not there yet, provided the todo is still at version
+func trashTodo(q queryer, id, userID, version int) error {
+	var ownerID int
+	err := q.QueryRow("SELECT user_id FROM todos WHERE id = ? AND deleted_at IS NULL", id).Scan(&ownerID)
+	if err == sql.ErrNoRows || (err == nil && ownerID != userID) {
+		return ErrNotFound
+	} else if err != nil {
+		return err
+	}
+
+	ids, err := subtreeIDs(q, id)
+	if err != nil {
+		return err
+	}
+
+	// Everything deleted together shares one timestamp, which is how
+	// RestoreTodo finds it again. The todo itself goes first so that a
+	// concurrent change to it fails the whole delete.
+	deletedAt := formatTime(time.Now())
+	result, err := q.Exec(
+		"UPDATE todos SET deleted_at = ?, version = version + 1 WHERE id = ? AND version = ? AND deleted_at IS NULL",
+		deletedAt, id, version,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to move todo to the trash: %w", err)
+	}
+	if err := checkVersioned(q, result, id); err != nil {
+		return err
+	}
+	if _, err := q.Exec(
+		"UPDATE todos SET deleted_at = ?, version = version + 1 WHERE deleted_at IS NULL AND id IN ("+placeholders(len(ids))+")",
+		append([]interface{}{deletedAt}, ids...)...,
+	); err != nil {
+		return fmt.Errorf("failed to move todo to the trash: %w", err)
+	}
+	if err := touchParents(q, ids...); err != nil {
+		return err
+	}
+	return syncTodos(q, ids...)
+}
+
+// touchTodos bumps the version of todos whose subtask or comment counts
+// changed, so that their entity tags change too
+func touchTodos(q queryer, ids ...interface{}) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if _, err := q.Exec(
+		"UPDATE todos SET version = version + 1 WHERE deleted_at IS NULL AND id IN ("+placeholders(len(ids))+")", ids...,
+	); err != nil {
+		return fmt.Errorf("failed to update todos: %w", err)
+	}
+	return nil
+}
+
+// touchParents bumps the version of the parents of todos that were added,
+// moved, completed or deleted. Parents among the todos themselves are
+// left alone, as their own change bumped them already.
+func touchParents(q queryer, ids ...interface{}) error {
+	parents, err := queryIDs(q, `
+		SELECT DISTINCT parent_id FROM todos
+		WHERE parent_id IS NOT NULL AND id IN (`+placeholders(len(ids))+`)
+			AND parent_id NOT IN (`+placeholders(len(ids))+`)
+	`, slices.Concat(ids, ids)...)
+	if err != nil {
+		return err
+	}
+	return touchTodos(q, parents...)
+}
+
+// subtreeIDs returns the ID of a todo followed by the IDs of all of its
+// subtasks, at every level. It is empty if the todo does not exist.
+func subtreeIDs(q queryer, id int) ([]interface{}, error) {
+	rows, err := q.Query(`
+		WITH RECURSIVE subtree(id) AS (
+			SELECT id FROM todos WHERE id = ?
+			UNION ALL
+			SELECT t.id FROM todos t JOIN subtree s ON t.parent_id = s.id
+		)
+		SELECT id FROM subtree
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []interface{}
+	for rows.Next() {
+		var todoID int
+		if err := rows.Scan(&todoID); err != nil {
+			return nil, err
+		}
+		ids = append(ids, todoID)
+	}
+	return ids, rows.Err()
+}
+
+// ListSubtasks returns a todo's direct subtasks, oldest first
+func (s *SQLStore) ListSubtasks(parentID int) ([]models.Todo, error) {
+	rows, err := s.db.Query(
+		"SELECT "+todoColumns+" FROM todos WHERE parent_id = ? AND deleted_at IS NULL ORDER BY created_at, id",
+		parentID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	todos := []models.Todo{}
+	for rows.Next() {
+		todo, err := scanTodo(rows)
+		if err != nil {
+			return nil, err
+		}
+		todos = append(todos, todo)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if err := loadTodoDetails(s.db, todos); err != nil {
+		return nil, err
+	}
+	return todos, nil
+}
+
+// GetAncestorIDs returns the IDs of a todo's ancestors, nearest first
+func (s *SQLStore) GetAncestorIDs(id int) ([]int, error) {
+	rows, err := s.db.Query(`
+		WITH RECURSIVE ancestors(id, parent_id, depth) AS (
+			SELECT id, parent_id, 0 FROM todos WHERE id = ?
+			UNION ALL
+			SELECT t.id, t.parent_id, a.depth + 1 FROM todos t JOIN ancestors a ON t.id = a.parent_id
+		)
+		SELECT id FROM ancestors WHERE depth > 0 ORDER BY depth
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var ancestorID int
+		if err := rows.Scan(&ancestorID); err != nil {
+			return nil, err
+		}
+		ids = append(ids, ancestorID)
+	}
+	return ids, rows.Err()
+}
+
+// SubtreeHeight returns how many levels of subtasks a todo has below it
+func (s *SQLStore) SubtreeHeight(id int) (int, error) {
+	var height sql.NullInt64
+	err := s.db.QueryRow(`
+		WITH RECURSIVE subtree(id, depth) AS (
+			SELECT id, 0 FROM todos WHERE id = ?
+			UNION ALL
+			SELECT t.id, s.depth + 1 FROM todos t JOIN subtree s ON t.parent_id = s.id
+		)
+		SELECT MAX(depth) FROM subtree
+	`, id).Scan(&height)
+	if err != nil {
+		return 0, err
+	} else if !height.Valid {
+		return 0, ErrNotFound
+	}
+	return int(height.Int64), nil
+}
+
+// SearchTodos searches the FTS5 index, or falls back to LIKE matching on
+// Postgres and when SQLite was built without FTS5
+func (s *SQLStore) SearchTodos(userID int, terms []SearchTerm, limit int) ([]models.TodoSearchResult, error) {
+	var results []models.TodoSearchResult
+	var err error
+	if s.searchEnabled {
+		results, err = s.searchFTS(userID, terms, limit)
+	} else {
+		results, err = s.searchLike(userID, terms, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	todos := make([]models.Todo, len(results))
+	for i := range results {
+		todos[i] = results[i].Todo
+	}
+	if err := loadTodoDetails(s.db, todos); err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].Todo = todos[i]
+	}
+	return results, nil
+}
+
+// ftsQuery renders search terms as an FTS5 MATCH expression. Every term is
+// quoted so user input can never be parsed as FTS5 operators.
+func ftsQuery(terms []SearchTerm) string {
+	parts := make([]string, len(terms))
+	for i, term := range terms {
+		quoted := `"` + strings.ReplaceAll(term.Text, `"`, `""`) + `"`
+		if !term.Phrase {
+			quoted += "*"
+		}
+		parts[i] = quoted
+	}
+	return strings.Join(parts, " ")
+}
+
+// FTS5 marks matched terms with these private use characters; markMatches
+// turns them into <mark></mark> once the rest of the text is escaped
+const (
+	matchStart = "\ue000"
+	matchEnd   = "\ue001"
+)
+
+// markMatches makes a snippet from FTS5 safe to use as HTML. A marker
+// character in the todo's own text can at worst add a stray <mark>.
+func markMatches(snippet string) string {
+	return strings.NewReplacer(matchStart, "<mark>", matchEnd, "</mark>").Replace(html.EscapeString(snippet))
+}
+
+// searchFTS ranks matches by bm25, weighting title hits above description
+// hits and boosting higher priority todos
+func (s *SQLStore) searchFTS(userID int, terms []SearchTerm, limit int) ([]models.TodoSearchResult, error) {
+	visible, visibleArgs := visibleClause("todos", userID)
+	args := append([]interface{}{matchStart, matchEnd, matchStart, matchEnd, ftsQuery(terms)}, visibleArgs...)
+	rows, err := s.db.Query(`
+		SELECT `+todoColumns+`, s.title_snippet, s.description_snippet,
+			s.score * (1.0 + 0.25 * (todos.priority - 1)) AS rank
+		FROM todos
+		JOIN (
+			SELECT rowid,
+				highlight(todos_fts, 0, ?, ?) AS title_snippet,
+				COALESCE(snippet(todos_fts, 1, ?, ?, '…', 12), '') AS description_snippet,
+				bm25(todos_fts, 4.0, 1.0) AS score
+			FROM todos_fts
+			WHERE todos_fts MATCH ?
+		) s ON s.rowid = todos.id
+		WHERE `+visible+`
+		ORDER BY rank, todos.id DESC
+		LIMIT ?
+	`, append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []models.TodoSearchResult{}
+	for rows.Next() {
+		var result models.TodoSearchResult
+		todo, err := scanTodo(rows, &result.TitleSnippet, &result.DescriptionSnippet, &result.Score)
+		if err != nil {
+			return nil, err
+		}
+		result.Todo = todo
+		result.TitleSnippet = markMatches(result.TitleSnippet)
+		result.DescriptionSnippet = markMatches(result.DescriptionSnippet)
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// likeEscaper escapes the LIKE wildcards in a search term, so that they
+// match themselves
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// searchLike matches terms as substrings, returns no highlighting and
+// orders by priority
+func (s *SQLStore) searchLike(userID int, terms []SearchTerm, limit int) ([]models.TodoSearchResult, error) {
+	clause, args := visibleClause("todos", userID)
+	query := "SELECT " + todoColumns + " FROM todos WHERE " + clause
+	like := s.dialect.Like()
+	for _, term := range terms {
+		pattern := "%" + likeEscaper.Replace(term.Text) + "%"
+		query += " AND (title " + like + ` ? ESCAPE '\' OR description ` + like + ` ? ESCAPE '\')`
+		args = append(args, pattern, pattern)
+	}
+	query += " ORDER BY priority DESC, created_at DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []models.TodoSearchResult{}
+	for rows.Next() {
+		todo, err := scanTodo(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, models.TodoSearchResult{
+			Todo:               todo,
+			TitleSnippet:       html.EscapeString(todo.Title),
+			DescriptionSnippet: html.EscapeString(todo.Description),
+		})
+	}
+	return results, rows.Err()
+}
+
+// setTodoTags replaces the tags on a todo, creating any tags the user does
+// not have yet
+func setTodoTags(q queryer, d database.Dialect, userID, todoID int, names []string) error {
+	if _, err := q.Exec("DELETE FROM todo_tags WHERE todo_id = ?", todoID); err != nil {
+		return fmt.Errorf("failed to clear tags: %w", err)
+	}
+
+	for _, name := range names {
+		if _, err := q.Exec(
+			"INSERT INTO tags (user_id, name) VALUES (?, ?) ON CONFLICT DO NOTHING",
+			userID, name,
+		); err != nil {
+			return fmt.Errorf("failed to create tag: %w", err)
+		}
+
+		tagID, err := findTagID(q, d, userID, name)
+		if err != nil {
+			return fmt.Errorf("failed to find tag: %w", err)
+		}
+
+		if _, err := q.Exec(
+			"INSERT INTO todo_tags (todo_id, tag_id) VALUES (?, ?) ON CONFLICT (todo_id, tag_id) DO NOTHING",
+			todoID, tagID,
+		); err != nil {
+			return fmt.Errorf("failed to link tag: %w", err)
+		}
+	}
+	return nil
+}
+
+// loadTodoDetails fills in the tags, subtask and comment counts of each todo
+func loadTodoDetails(q queryer, todos []models.Todo) error {
+	if err := loadTodoTags(q, todos); err != nil {
+		return err
+	}
+	if err := loadSubtaskCounts(q, todos); err != nil {
+		return err
+	}
+	return loadCommentCounts(q, todos)
+}
+
+// loadSubtaskCounts fills in the subtask progress of each todo with a
+// single query
+func loadSubtaskCounts(q queryer, todos []models.Todo) error {
+	if len(todos) == 0 {
+		return nil
+	}
+
+	index := make(map[int]int, len(todos))
+	args := make([]interface{}, len(todos))
+	for i := range todos {
+		todos[i].SubtasksTotal, todos[i].SubtasksDone = 0, 0
+		index[todos[i].ID] = i
+		args[i] = todos[i].ID
+	}
+
+	rows, err := q.Query(`
+		SELECT parent_id, COUNT(*), SUM(CASE WHEN completed THEN 1 ELSE 0 END)
+		FROM todos
+		WHERE parent_id IN (`+placeholders(len(todos))+`) AND deleted_at IS NULL
+		GROUP BY parent_id
+	`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var parentID, total, done int
+		if err := rows.Scan(&parentID, &total, &done); err != nil {
+			return err
+		}
+		if i, ok := index[parentID]; ok {
+			todos[i].SubtasksTotal, todos[i].SubtasksDone = total, done
+		}
+	}
+	return rows.Err()
+}
+
+// loadTodoTags fills in the Tags field of each todo with a single query
+func loadTodoTags(q queryer, todos []models.Todo) error {
+	if len(todos) == 0 {
+		return nil
+	}
+
+	index := make(map[int]int, len(todos))
+	args := make([]interface{}, len(todos))
+	for i := range todos {
+		todos[i].Tags = []string{}
+		index[todos[i].ID] = i
+		args[i] = todos[i].ID
+	}
+
+	rows, err := q.Query(`
+		SELECT tt.todo_id, t.name
+		FROM todo_tags tt
+		JOIN tags t ON t.id = tt.tag_id
+		WHERE tt.todo_id IN (`+placeholders(len(todos))+`)
+	`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var todoID int
+		var name string
+		if err := rows.Scan(&todoID, &name); err != nil {
+			return err
+		}
+		if i, ok := index[todoID]; ok {
+			todos[i].Tags = append(todos[i].Tags, name)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range todos {
+		sortTagNames(todos[i].Tags)
+	}
+	return nil
+}
+
+// sortTagNames orders tag names case-insensitively
+func sortTagNames(names []string) {
+	sort.Slice(names, func(a, b int) bool {
+		return strings.ToLower(names[a]) < strings.ToLower(names[b])
+	})
+}
+
+// placeholders returns n comma-separated bind placeholders
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// foldedPlaceholders returns n comma-separated case-insensitive placeholders
+func foldedPlaceholders(d database.Dialect, n int) string {
+	folded := d.Fold("?") + ", "
+	return strings.TrimSuffix(strings.Repeat(folded, n), ", ")
+}
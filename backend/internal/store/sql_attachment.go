@@ -0,0 +1,186 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+
+	"todo-list-app/internal/blob"
+	"todo-list-app/internal/models"
+)
+
+// errNoBlobStore is returned for attachment content when the store was
+// set up without a blob store
+var errNoBlobStore = errors.New("no blob store configured")
+
+const attachmentColumns = "id, todo_id, user_id, filename, content_type, size, sha256, created_at"
+
+// UseBlobStore sets where attachment content is kept
+func (s *SQLStore) UseBlobStore(blobs blob.Store) {
+	s.blobs = blobs
+}
+
+func scanAttachment(row rowScanner) (models.Attachment, error) {
+	var attachment models.Attachment
+	err := row.Scan(
+		&attachment.ID, &attachment.TodoID, &attachment.UserID, &attachment.Filename,
+		&attachment.ContentType, &attachment.Size, &attachment.SHA256, &attachment.CreatedAt,
+	)
+	return attachment, err
+}
+
+// ListAttachments returns a todo's attachments, oldest first
+func (s *SQLStore) ListAttachments(todoID int) ([]models.Attachment, error) {
+	rows, err := s.db.Query(
+		"SELECT "+attachmentColumns+" FROM attachments WHERE todo_id = ? ORDER BY created_at, id", todoID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := []models.Attachment{}
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, rows.Err()
+}
+
+// GetAttachment loads an attachment
+func (s *SQLStore) GetAttachment(id int) (models.Attachment, error) {
+	attachment, err := scanAttachment(s.db.QueryRow("SELECT "+attachmentColumns+" FROM attachments WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return attachment, ErrNotFound
+	}
+	return attachment, err
+}
+
+// CreateAttachment stores the content and records the attachment. The
+// blob lock keeps releaseBlobs from removing a shared blob between the two.
+func (s *SQLStore) CreateAttachment(attachment *models.Attachment, content io.Reader) error {
+	if s.blobs == nil {
+		return errNoBlobStore
+	}
+
+	s.blobMu.Lock()
+	defer s.blobMu.Unlock()
+
+	key, size, err := s.blobs.Put(content)
+	if err != nil {
+		return err
+	}
+
+	attachmentID, err := s.db.Insert(`
+		INSERT INTO attachments (todo_id, user_id, filename, content_type, size, sha256)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, attachment.TodoID, attachment.UserID, attachment.Filename, attachment.ContentType, size, key)
+	if err != nil {
+		s.releaseBlobsLocked([]string{key})
+		return fmt.Errorf("failed to create attachment: %w", err)
+	}
+
+	created, err := s.GetAttachment(attachmentID)
+	if err != nil {
+		return err
+	}
+	*attachment = created
+	return nil
+}
+
+// OpenAttachment opens an attachment's blob
+func (s *SQLStore) OpenAttachment(attachment models.Attachment) (io.ReadSeekCloser, error) {
+	if s.blobs == nil {
+		return nil, errNoBlobStore
+	}
+	content, err := s.blobs.Open(attachment.SHA256)
+	if err == blob.ErrNotFound {
+		return nil, ErrNotFound
+	}
+	return content, err
+}
+
+// DeleteAttachment deletes an attachment and, if nothing else uses it, its
+// blob
+func (s *SQLStore) DeleteAttachment(id int) error {
+	attachment, err := s.GetAttachment(id)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.db.Exec("DELETE FROM attachments WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete attachment: %w", err)
+	}
+	if rowsAffected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	s.releaseBlobs([]string{attachment.SHA256})
+	return nil
+}
+
+// deleteAttachments deletes the attachments whose todo_id matches the
+// condition and returns the blob keys they used, so that the caller can
+// release them once its transaction has committed
+func deleteAttachments(q queryer, todoCondition string, args ...interface{}) ([]string, error) {
+	rows, err := q.Query("SELECT DISTINCT sha256 FROM attachments WHERE todo_id "+todoCondition, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if _, err := q.Exec("DELETE FROM attachments WHERE todo_id "+todoCondition, args...); err != nil {
+		return nil, fmt.Errorf("failed to delete attachments: %w", err)
+	}
+	return keys, nil
+}
+
+// releaseBlobs removes the blobs that no attachment refers to any more.
+// The attachments are already gone, so failures are only logged; a blob
+// left behind wastes space but breaks nothing.
+func (s *SQLStore) releaseBlobs(keys []string) {
+	if s.blobs == nil || len(keys) == 0 {
+		return
+	}
+	s.blobMu.Lock()
+	defer s.blobMu.Unlock()
+	s.releaseBlobsLocked(keys)
+}
+
+// releaseBlobsLocked is releaseBlobs for a caller holding the blob lock
+func (s *SQLStore) releaseBlobsLocked(keys []string) {
+	for _, key := range keys {
+		var count int
+		if err := s.db.QueryRow("SELECT COUNT(*) FROM attachments WHERE sha256 = ?", key).Scan(&count); err != nil {
+			log.Printf("Warning: Failed to check blob %s: %v", key, err)
+			continue
+		}
+		if count > 0 {
+			continue
+		}
+		if err := s.blobs.Delete(key); err != nil {
+			log.Printf("Warning: Failed to delete blob %s: %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package store
+
+import (
+	"database/sql"
+
+	"todo-list-app/internal/database"
+	"todo-list-app/internal/models"
+)
+
+// ApplyTodoChanges applies a batch of changes in one transaction, together
+// with their history. Outside atomic mode each change runs inside a
+// savepoint, so a failed change can be undone on its own.
+func (s *SQLStore) ApplyTodoChanges(actorID int, changes []TodoChange, atomic bool) ([]TodoChangeResult, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	loaded, err := batchVersions(tx, s.dialect, changes)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]TodoChangeResult, len(changes))
+	for i, change := range changes {
+		if !atomic {
+			if _, err := tx.Exec("SAVEPOINT todo_change"); err != nil {
+				return nil, err
+			}
+		}
+		if err := rebaseChange(tx, &change, loaded); err != nil {
+			return nil, err
+		}
+
+		results[i], err = applyTodoChange(tx, s.dialect, actorID, change)
+		if err != nil && err != ErrNotFound && err != ErrConflict {
+			return nil, err
+		}
+		if err != nil {
+			results[i].Err = err
+			if atomic {
+				return results, nil
+			}
+			if _, err := tx.Exec("ROLLBACK TO SAVEPOINT todo_change"); err != nil {
+				return nil, err
+			}
+		}
+		if !atomic {
+			if _, err := tx.Exec("RELEASE SAVEPOINT todo_change"); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// batchVersions returns the version of each todo a batch changes as the
+// batch begins, locking the todos until it ends
+func batchVersions(q queryer, d database.Dialect, changes []TodoChange) (map[int]int, error) {
+	var ids []interface{}
+	for _, change := range changes {
+		if !change.Create {
+			ids = append(ids, change.Todo.ID)
+		}
+	}
+	versions := make(map[int]int, len(ids))
+	if len(ids) == 0 {
+		return versions, nil
+	}
+
+	rows, err := q.Query("SELECT id, version FROM todos WHERE id IN ("+placeholders(len(ids))+")"+d.ForUpdate(), ids...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, version int
+		if err := rows.Scan(&id, &version); err != nil {
+			return nil, err
+		}
+		versions[id] = version
+	}
+	return versions, rows.Err()
+}
+
+// rebaseChange moves a change loaded at the version its todo had when the
+// batch began to the todo's current version. Only the batch's own earlier
+// changes can have bumped it since, such as by completing a subtask of it.
+func rebaseChange(q queryer, change *TodoChange, loaded map[int]int) error {
+	if version, ok := loaded[change.Todo.ID]; change.Create || !ok || version != change.Todo.Version {
+		return nil
+	}
+	err := q.QueryRow("SELECT version FROM todos WHERE id = ?", change.Todo.ID).Scan(&change.Todo.Version)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	return err
+}
+
+// applyTodoChange applies one change of a batch, after checking that its
+// todo is still at the version it was loaded at, and records it in the
+// history of the todos it changed
+func applyTodoChange(q queryer, d database.Dialect, actorID int, change TodoChange) (TodoChangeResult, error) {
+	var result TodoChangeResult
+	todo := change.Todo
+	action, subtaskAction := changeAction(change)
+
+	var before map[int]models.TodoState
+	var err error
+	if change.Create {
+		if todo, err = insertTodo(q, d, &change.Todo); err != nil {
+			return result, err
+		}
+	} else {
+		var version int
+		err = q.QueryRow("SELECT version FROM todos WHERE id = ? AND deleted_at IS NULL", todo.ID).Scan(&version)
+		if err == sql.ErrNoRows {
+			return result, ErrNotFound
+		} else if err != nil {
+			return result, err
+		} else if version != todo.Version {
+			return result, ErrConflict
+		}
+		if before, err = subtreeStates(q, todo.ID); err != nil {
+			return result, err
+		}
+	}
+
+	if change.Delete {
+		result.Todo = todo
+		if err := trashTodo(q, todo.ID, todo.UserID, todo.Version); err != nil {
+			return result, err
+		}
+		return result, recordRevisions(revisionWriter(q), actorID, todo.ID, action, subtaskAction, before, before, nil)
+	}
+
+	if change.Update {
+		if err := updateTodo(q, d, &todo); err != nil {
+			return result, err
+		}
+	}
+	if change.Completed != nil {
+		if change.Cascade && *change.Completed {
+			err = completeSubtree(q, d, todo.ID, todo.Version)
+		} else {
+			err = setCompleted(q, d, todo.ID, todo.Version, *change.Completed)
+		}
+		if err != nil {
+			return result, err
+		}
+	}
+	if change.Next != nil {
+		// Without a rule left to hand over there is simply no occurrence
+		next, err := spawnOccurrence(q, d, todo.ID, change.Next)
+		if err == nil {
+			result.Next = &next
+		} else if err != ErrConflict {
+			return result, err
+		}
+	}
+
+	after, err := subtreeStates(q, todo.ID)
+	if err != nil {
+		return result, err
+	}
+	if err := recordRevisions(revisionWriter(q), actorID, todo.ID, action, subtaskAction, before, after, change.RevertedTo); err != nil {
+		return result, err
+	}
+	if result.Next != nil {
+		revision, err := newRevision(actorID, models.RevisionCreate, result.Next.ID, nil, StateOf(*result.Next), nil)
+		if err != nil {
+			return result, err
+		}
+		if err := addRevision(q, revision); err != nil {
+			return result, err
+		}
+	}
+
+	result.Todo, err = getTodo(q, todo.ID)
+	return result, err
+}
@@ -0,0 +1,174 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+
+	"todo-list-app/internal/models"
+)
+
+// commentSelect selects comments together with their author's email
+const commentSelect = `
+	SELECT c.id, c.todo_id, c.user_id, u.email, c.body, c.edited, c.created_at, c.updated_at
+	FROM comments c JOIN users u ON u.id = c.user_id`
+
+func scanComment(row rowScanner) (models.Comment, error) {
+	var comment models.Comment
+	err := row.Scan(
+		&comment.ID, &comment.TodoID, &comment.UserID, &comment.AuthorEmail,
+		&comment.Body, &comment.Edited, &comment.CreatedAt, &comment.UpdatedAt,
+	)
+	return comment, err
+}
+
+// ListComments returns a todo's comments, oldest first
+func (s *SQLStore) ListComments(todoID int) ([]models.Comment, error) {
+	rows, err := s.db.Query(commentSelect+" WHERE c.todo_id = ? ORDER BY c.created_at, c.id", todoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []models.Comment{}
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+	return comments, rows.Err()
+}
+
+// GetComment loads a comment
+func (s *SQLStore) GetComment(id int) (models.Comment, error) {
+	comment, err := scanComment(s.db.QueryRow(commentSelect+" WHERE c.id = ?", id))
+	if err == sql.ErrNoRows {
+		return comment, ErrNotFound
+	}
+	return comment, err
+}
+
+// CreateComment stores a new comment
+func (s *SQLStore) CreateComment(comment *models.Comment) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	commentID, err := tx.Insert(
+		"INSERT INTO comments (todo_id, user_id, body) VALUES (?, ?, ?)",
+		comment.TodoID, comment.UserID, comment.Body,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create comment: %w", err)
+	}
+
+	// The todo's comment count changed
+	if err := touchTodos(tx, comment.TodoID); err != nil {
+		return err
+	}
+	if err := syncTodos(tx, comment.TodoID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	created, err := s.GetComment(commentID)
+	if err != nil {
+		return err
+	}
+	*comment = created
+	return nil
+}
+
+// UpdateComment replaces a comment's body and marks it edited
+func (s *SQLStore) UpdateComment(comment *models.Comment) error {
+	result, err := s.db.Exec(
+		"UPDATE comments SET body = ?, edited = TRUE, updated_at = "+s.dialect.Now()+" WHERE id = ?",
+		comment.Body, comment.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update comment: %w", err)
+	}
+
+	if rowsAffected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	updated, err := s.GetComment(comment.ID)
+	if err != nil {
+		return err
+	}
+	*comment = updated
+	return nil
+}
+
+// DeleteComment deletes a comment
+func (s *SQLStore) DeleteComment(id int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var todoID int
+	err = tx.QueryRow("SELECT todo_id FROM comments WHERE id = ?", id).Scan(&todoID)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	} else if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM comments WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete comment: %w", err)
+	}
+	if err := touchTodos(tx, todoID); err != nil {
+		return err
+	}
+	if err := syncTodos(tx, todoID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// loadCommentCounts fills in the comment count of each todo with a single
+// query
+func loadCommentCounts(q queryer, todos []models.Todo) error {
+	if len(todos) == 0 {
+		return nil
+	}
+
+	index := make(map[int]int, len(todos))
+	args := make([]interface{}, len(todos))
+	for i := range todos {
+		todos[i].CommentCount = 0
+		index[todos[i].ID] = i
+		args[i] = todos[i].ID
+	}
+
+	rows, err := q.Query(`
+		SELECT todo_id, COUNT(*) FROM comments
+		WHERE todo_id IN (`+placeholders(len(todos))+`)
+		GROUP BY todo_id
+	`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var todoID, count int
+		if err := rows.Scan(&todoID, &count); err != nil {
+			return err
+		}
+		if i, ok := index[todoID]; ok {
+			todos[i].CommentCount = count
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,115 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"todo-list-app/internal/models"
+)
+
+const idempotencyColumns = "id, user_id, idempotency_key, request_hash, status, header, body, created_at, expires_at"
+
+func scanIdempotencyRecord(row rowScanner) (models.IdempotencyRecord, error) {
+	var record models.IdempotencyRecord
+	var header string
+	err := row.Scan(
+		&record.ID, &record.UserID, &record.Key, &record.RequestHash, &record.Status,
+		&header, &record.Body, &record.CreatedAt, &record.ExpiresAt,
+	)
+	if err != nil {
+		return record, err
+	}
+	if err := json.Unmarshal([]byte(header), &record.Header); err != nil {
+		return record, fmt.Errorf("failed to decode stored response header: %w", err)
+	}
+	return record, nil
+}
+
+// getIdempotencyKey returns the record of one of the user's keys
+func getIdempotencyKey(q queryer, userID int, key string) (models.IdempotencyRecord, error) {
+	record, err := scanIdempotencyRecord(q.QueryRow(
+		"SELECT "+idempotencyColumns+" FROM idempotency_keys WHERE user_id = ? AND idempotency_key = ?",
+		userID, key,
+	))
+	if err == sql.ErrNoRows {
+		return record, ErrNotFound
+	}
+	return record, err
+}
+
+// ReserveIdempotencyKey claims a key for a request about to run, or returns
+// the record already stored under it with ErrConflict
+func (s *SQLStore) ReserveIdempotencyKey(record *models.IdempotencyRecord) (models.IdempotencyRecord, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return models.IdempotencyRecord{}, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(
+		"DELETE FROM idempotency_keys WHERE user_id = ? AND expires_at <= "+s.dialect.Now(), record.UserID,
+	); err != nil {
+		return models.IdempotencyRecord{}, fmt.Errorf("failed to remove expired idempotency keys: %w", err)
+	}
+
+	if existing, err := getIdempotencyKey(tx, record.UserID, record.Key); err == nil {
+		return existing, ErrConflict
+	} else if err != ErrNotFound {
+		return models.IdempotencyRecord{}, err
+	}
+
+	recordID, err := tx.Insert(`
+		INSERT INTO idempotency_keys (user_id, idempotency_key, request_hash, expires_at)
+		VALUES (?, ?, ?, ?)
+	`, record.UserID, record.Key, record.RequestHash, formatTime(record.ExpiresAt))
+	if err != nil {
+		// Another request may have claimed the key in the meantime
+		if existing, getErr := getIdempotencyKey(s.db, record.UserID, record.Key); getErr == nil {
+			return existing, ErrConflict
+		}
+		return models.IdempotencyRecord{}, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+
+	reserved, err := scanIdempotencyRecord(tx.QueryRow("SELECT "+idempotencyColumns+" FROM idempotency_keys WHERE id = ?", recordID))
+	if err != nil {
+		return models.IdempotencyRecord{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.IdempotencyRecord{}, err
+	}
+	*record = reserved
+	return reserved, nil
+}
+
+// CompleteIdempotencyKey stores the response to a reserved key's request
+func (s *SQLStore) CompleteIdempotencyKey(id, status int, header map[string][]string, body []byte) error {
+	encoded, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.db.Exec(
+		"UPDATE idempotency_keys SET status = ?, header = ?, body = ? WHERE id = ?",
+		status, string(encoded), body, id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+
+	if rowsAffected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey deletes a reserved key so the request can be retried
+func (s *SQLStore) ReleaseIdempotencyKey(id int) error {
+	if _, err := s.db.Exec("DELETE FROM idempotency_keys WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
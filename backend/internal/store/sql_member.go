@@ -0,0 +1,244 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+
+	"todo-list-app/internal/models"
+)
+
+// ListMembers returns the project's creator followed by its members
+func (s *SQLStore) ListMembers(projectID int) ([]models.ProjectMember, error) {
+	rows, err := s.db.Query(`
+		SELECT p.id, u.id, u.email, 'owner', p.created_at, 0
+		FROM projects p JOIN users u ON u.id = p.user_id
+		WHERE p.id = ?
+		UNION ALL
+		SELECT m.project_id, u.id, u.email, m.role, m.created_at, 1
+		FROM project_members m JOIN users u ON u.id = m.user_id
+		WHERE m.project_id = ?
+		ORDER BY 6, 5, 2
+	`, projectID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []models.ProjectMember{}
+	for rows.Next() {
+		var member models.ProjectMember
+		var isMember int
+		if err := rows.Scan(
+			&member.ProjectID, &member.UserID, &member.Email, &member.Role, &member.CreatedAt, &isMember,
+		); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+// SetMemberRole changes the role of a member
+func (s *SQLStore) SetMemberRole(projectID, userID int, role string) error {
+	result, err := s.db.Exec(
+		"UPDATE project_members SET role = ? WHERE project_id = ? AND user_id = ?", role, projectID, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update member: %w", err)
+	}
+
+	if rowsAffected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteMember removes a member from a project and unassigns them from its
+// todos, recording that in the todos' history as a change by actorID
+func (s *SQLStore) DeleteMember(projectID, userID, actorID int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("DELETE FROM project_members WHERE project_id = ? AND user_id = ?", projectID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove member: %w", err)
+	}
+
+	if rowsAffected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	before, err := todoStates(tx, "project_id = ? AND assignee_id IS NOT NULL", projectID)
+	if err != nil {
+		return err
+	}
+	unassigned, err := queryIDs(tx, "SELECT id FROM todos WHERE project_id = ? AND assignee_id = ?", projectID, userID)
+	if err != nil {
+		return err
+	}
+	if err := unassignStale(tx, "project_id = ?", projectID); err != nil {
+		return err
+	}
+	if err := syncTodos(tx, unassigned...); err != nil {
+		return err
+	}
+	if err := recordChanges(tx, actorID, models.RevisionUpdate, before); err != nil {
+		return err
+	}
+	if err := syncProjectTodos(tx, projectID, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// invitationSelect selects invitations together with their project's name
+const invitationSelect = `
+	SELECT i.id, i.project_id, p.name, i.email, i.role, i.invited_by, i.created_at
+	FROM project_invitations i JOIN projects p ON p.id = i.project_id`
+
+func scanInvitation(row rowScanner) (models.ProjectInvitation, error) {
+	var invitation models.ProjectInvitation
+	err := row.Scan(
+		&invitation.ID, &invitation.ProjectID, &invitation.ProjectName, &invitation.Email,
+		&invitation.Role, &invitation.InvitedBy, &invitation.CreatedAt,
+	)
+	return invitation, err
+}
+
+// listInvitations runs an invitation query
+func (s *SQLStore) listInvitations(query string, args ...interface{}) ([]models.ProjectInvitation, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []models.ProjectInvitation{}
+	for rows.Next() {
+		invitation, err := scanInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, invitation)
+	}
+	return invitations, rows.Err()
+}
+
+// ListProjectInvitations returns a project's open invitations
+func (s *SQLStore) ListProjectInvitations(projectID int) ([]models.ProjectInvitation, error) {
+	return s.listInvitations(invitationSelect+" WHERE i.project_id = ? ORDER BY i.created_at, i.id", projectID)
+}
+
+// ListUserInvitations returns the open invitations for an email address
+func (s *SQLStore) ListUserInvitations(email string) ([]models.ProjectInvitation, error) {
+	return s.listInvitations(invitationSelect+" WHERE i.email = ? ORDER BY i.created_at, i.id", email)
+}
+
+// GetInvitation loads an open invitation
+func (s *SQLStore) GetInvitation(id int) (models.ProjectInvitation, error) {
+	invitation, err := scanInvitation(s.db.QueryRow(invitationSelect+" WHERE i.id = ?", id))
+	if err == sql.ErrNoRows {
+		return invitation, ErrNotFound
+	}
+	return invitation, err
+}
+
+// CreateInvitation stores a new invitation
+func (s *SQLStore) CreateInvitation(invitation *models.ProjectInvitation) error {
+	var existingID int
+	err := s.db.QueryRow(
+		"SELECT id FROM project_invitations WHERE project_id = ? AND email = ?", invitation.ProjectID, invitation.Email,
+	).Scan(&existingID)
+	if err == nil {
+		return ErrConflict
+	} else if err != sql.ErrNoRows {
+		return err
+	}
+
+	invitationID, err := s.db.Insert(`
+		INSERT INTO project_invitations (project_id, email, role, invited_by)
+		VALUES (?, ?, ?, ?)
+	`, invitation.ProjectID, invitation.Email, invitation.Role, invitation.InvitedBy)
+	if err != nil {
+		return fmt.Errorf("failed to create invitation: %w", err)
+	}
+
+	created, err := s.GetInvitation(invitationID)
+	if err != nil {
+		return err
+	}
+	*invitation = created
+	return nil
+}
+
+// AcceptInvitation turns an invitation into a membership
+func (s *SQLStore) AcceptInvitation(id, userID int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var projectID int
+	var role string
+	err = tx.QueryRow("SELECT project_id, role FROM project_invitations WHERE id = ?", id).Scan(&projectID, &role)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	} else if err != nil {
+		return err
+	}
+
+	var existing int
+	err = tx.QueryRow(`
+		SELECT COUNT(*) FROM projects WHERE id = ? AND user_id = ?
+	`, projectID, userID).Scan(&existing)
+	if err != nil {
+		return err
+	}
+	if existing == 0 {
+		err = tx.QueryRow(`
+			SELECT COUNT(*) FROM project_members WHERE project_id = ? AND user_id = ?
+		`, projectID, userID).Scan(&existing)
+		if err != nil {
+			return err
+		}
+	}
+	if existing > 0 {
+		return ErrConflict
+	}
+
+	if _, err := tx.Exec(
+		"INSERT INTO project_members (project_id, user_id, role) VALUES (?, ?, ?)", projectID, userID, role,
+	); err != nil {
+		return fmt.Errorf("failed to add member: %w", err)
+	}
+	if err := syncProjectTodos(tx, projectID, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM project_invitations WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to remove invitation: %w", err)
+	}
+	return tx.Commit()
+}
+
+// DeleteInvitation revokes or declines an invitation
+func (s *SQLStore) DeleteInvitation(id int) error {
+	result, err := s.db.Exec("DELETE FROM project_invitations WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete invitation: %w", err)
+	}
+
+	if rowsAffected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"todo-list-app/internal/models"
+)
+
+// projectSelect selects the projects a user has a role on, with the role
+// and open todo counts. It takes the user ID three times; callers append
+// conditions and must then GROUP BY p.id, m.role.
+const projectSelect = `
+	SELECT p.id, p.user_id, CASE WHEN p.user_id = ? THEN 'owner' ELSE m.role END,
+		p.name, p.color, p.archived, p.sort_order, COUNT(t.id), p.created_at, p.updated_at
+	FROM projects p
+	LEFT JOIN project_members m ON m.project_id = p.id AND m.user_id = ?
+	LEFT JOIN todos t ON t.project_id = p.id AND t.completed = FALSE AND t.deleted_at IS NULL
+	WHERE (p.user_id = ? OR m.user_id IS NOT NULL)`
+
+func scanProject(row rowScanner) (models.Project, error) {
+	var project models.Project
+	err := row.Scan(
+		&project.ID, &project.UserID, &project.Role, &project.Name, &project.Color, &project.Archived,
+		&project.SortOrder, &project.TodoCount, &project.CreatedAt, &project.UpdatedAt,
+	)
+	return project, err
+}
+
+// ListProjects returns the projects the user has a role on, in sort order
+func (s *SQLStore) ListProjects(userID int, includeArchived bool) ([]models.Project, error) {
+	query := projectSelect
+	if !includeArchived {
+		query += " AND p.archived = FALSE"
+	}
+	query += " GROUP BY p.id, m.role ORDER BY p.sort_order, " + s.dialect.Fold("p.name") + ", p.id"
+
+	rows, err := s.db.Query(query, userID, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	projects := []models.Project{}
+	for rows.Next() {
+		project, err := scanProject(rows)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, project)
+	}
+	return projects, rows.Err()
+}
+
+// GetProject loads a project the user has a role on
+func (s *SQLStore) GetProject(id, userID int) (models.Project, error) {
+	project, err := scanProject(s.db.QueryRow(projectSelect+`
+		AND p.id = ?
+		GROUP BY p.id, m.role
+	`, userID, userID, userID, id))
+	if err == sql.ErrNoRows {
+		return project, ErrNotFound
+	}
+	return project, err
+}
+
+// CreateProject creates a new project
+func (s *SQLStore) CreateProject(project *models.Project) error {
+	projectID, err := s.db.Insert(`
+		INSERT INTO projects (user_id, name, color, archived, sort_order)
+		VALUES (?, ?, ?, ?, ?)
+	`, project.UserID, project.Name, project.Color, project.Archived, project.SortOrder)
+	if err != nil {
+		return fmt.Errorf("failed to create project: %w", err)
+	}
+
+	created, err := s.GetProject(projectID, project.UserID)
+	if err != nil {
+		return err
+	}
+	*project = created
+	return nil
+}
+
+// UpdateProject saves the editable fields of a project
+func (s *SQLStore) UpdateProject(project *models.Project) error {
+	result, err := s.db.Exec(`
+		UPDATE projects
+		SET name = ?, color = ?, archived = ?, sort_order = ?, updated_at = `+s.dialect.Now()+`
+		WHERE id = ?
+	`, project.Name, project.Color, project.Archived, project.SortOrder, project.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update project: %w", err)
+	}
+
+	if rowsAffected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	// Reload as seen by the requesting user, whose role is unchanged
+	role := project.Role
+	updated, err := s.GetProject(project.ID, project.UserID)
+	if err != nil {
+		return err
+	}
+	updated.Role = role
+	*project = updated
+	return nil
+}
+
+// DeleteProject deletes a project, moving its todos to the inbox and, if
+// requested, to the trash. Both are recorded in the todos' history as
+// changes by actorID.
+func (s *SQLStore) DeleteProject(id, actorID int, deleteTodos bool) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("DELETE FROM projects WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete project: %w", err)
+	}
+
+	if rowsAffected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	ids, err := queryIDs(tx, "SELECT id FROM todos WHERE project_id = ?", id)
+	if err != nil {
+		return err
+	}
+	before, err := todoStates(tx, "project_id = ?", id)
+	if err != nil {
+		return err
+	}
+	var trashed map[int]models.TodoState
+	if deleteTodos {
+		if trashed, err = todoStates(tx, "project_id = ? AND deleted_at IS NULL", id); err != nil {
+			return err
+		}
+	}
+
+	// SQLite does not enforce foreign keys, so handle sharing and the todos
+	// explicitly
+	if _, err := tx.Exec("DELETE FROM project_members WHERE project_id = ?", id); err != nil {
+		return fmt.Errorf("failed to remove members: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM project_invitations WHERE project_id = ?", id); err != nil {
+		return fmt.Errorf("failed to remove invitations: %w", err)
+	}
+	if deleteTodos {
+		// Deleted todos go to their creators' trash
+		if _, err := tx.Exec(
+			"UPDATE todos SET deleted_at = ?, version = version + 1 WHERE project_id = ? AND deleted_at IS NULL", formatTime(time.Now()), id,
+		); err != nil {
+			return fmt.Errorf("failed to move todos to the trash: %w", err)
+		}
+	}
+
+	// Only a todo's creator can see it in the inbox
+	if _, err := tx.Exec(`
+		UPDATE todos SET assignee_id = NULL, assigned_by = NULL, assigned_at = NULL
+		WHERE project_id = ? AND assignee_id <> user_id
+	`, id); err != nil {
+		return fmt.Errorf("failed to unassign todos: %w", err)
+	}
+	if _, err := tx.Exec(
+		"UPDATE todos SET project_id = NULL, version = version + 1, updated_at = "+s.dialect.Now()+" WHERE project_id = ?", id,
+	); err != nil {
+		return fmt.Errorf("failed to move todos to the inbox: %w", err)
+	}
+	if err := syncTodos(tx, ids...); err != nil {
+		return err
+	}
+
+	// Todos going to the trash are recorded as deleted, the others as
+	// moved to the inbox
+	for todoID := range trashed {
+		delete(before, todoID)
+	}
+	if err := recordChanges(tx, actorID, models.RevisionDelete, trashed); err != nil {
+		return err
+	}
+	if err := recordChanges(tx, actorID, models.RevisionUpdate, before); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,157 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"todo-list-app/internal/models"
+)
+
+// revisionSelect selects revisions together with the actor's email
+const revisionSelect = `
+	SELECT r.id, r.todo_id, r.revision, r.user_id, u.email, r.action, r.changes, r.state, COALESCE(r.version, 0), r.reverted_to, r.created_at
+	FROM todo_revisions r JOIN users u ON u.id = r.user_id`
+
+func scanRevision(row rowScanner) (models.TodoRevision, error) {
+	var revision models.TodoRevision
+	var changes, state string
+	err := row.Scan(
+		&revision.ID, &revision.TodoID, &revision.Revision, &revision.UserID, &revision.ActorEmail,
+		&revision.Action, &changes, &state, &revision.Version, &revision.RevertedTo, &revision.CreatedAt,
+	)
+	if err != nil {
+		return revision, err
+	}
+	if err := json.Unmarshal([]byte(changes), &revision.Changes); err != nil {
+		return revision, fmt.Errorf("invalid changes in revision %d: %w", revision.ID, err)
+	}
+	if err := json.Unmarshal([]byte(state), &revision.State); err != nil {
+		return revision, fmt.Errorf("invalid state in revision %d: %w", revision.ID, err)
+	}
+	return revision, nil
+}
+
+// ListRevisions returns a todo's history, oldest first
+func (s *SQLStore) ListRevisions(todoID int) ([]models.TodoRevision, error) {
+	rows, err := s.db.Query(revisionSelect+" WHERE r.todo_id = ? ORDER BY r.revision", todoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []models.TodoRevision{}
+	for rows.Next() {
+		revision, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, rows.Err()
+}
+
+// GetRevision loads one revision of a todo
+func (s *SQLStore) GetRevision(todoID, revision int) (models.TodoRevision, error) {
+	found, err := scanRevision(s.db.QueryRow(revisionSelect+" WHERE r.todo_id = ? AND r.revision = ?", todoID, revision))
+	if err == sql.ErrNoRows {
+		return found, ErrNotFound
+	}
+	return found, err
+}
+
+// addRevision appends a revision in the transaction that made the change,
+// numbering it after the todo's latest and taking the todo's version
+func addRevision(q queryer, revision *models.TodoRevision) error {
+	changes, err := json.Marshal(revision.Changes)
+	if err != nil {
+		return err
+	}
+	state, err := json.Marshal(revision.State)
+	if err != nil {
+		return err
+	}
+
+	var number int
+	if err := q.QueryRow(
+		"SELECT COALESCE(MAX(revision), 0) + 1 FROM todo_revisions WHERE todo_id = ?", revision.TodoID,
+	).Scan(&number); err != nil {
+		return err
+	}
+
+	if _, err := q.Insert(`
+		INSERT INTO todo_revisions (todo_id, revision, user_id, action, changes, state, version, reverted_to)
+		VALUES (?, ?, ?, ?, ?, ?, (SELECT version FROM todos WHERE id = ?), ?)
+	`, revision.TodoID, number, revision.UserID, revision.Action, string(changes), string(state),
+		revision.TodoID, revision.RevertedTo); err != nil {
+		return fmt.Errorf("failed to add revision: %w", err)
+	}
+	return nil
+}
+
+// revisionWriter returns a function adding revisions in q's transaction,
+// for recordRevisions
+func revisionWriter(q queryer) func(*models.TodoRevision) error {
+	return func(revision *models.TodoRevision) error {
+		return addRevision(q, revision)
+	}
+}
+
+// todoStates loads the tracked state of the todos matching where, by ID
+func todoStates(q queryer, where string, args ...interface{}) (map[int]models.TodoState, error) {
+	rows, err := q.Query("SELECT "+todoColumns+" FROM todos WHERE "+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var todos []models.Todo
+	for rows.Next() {
+		todo, err := scanTodo(rows)
+		if err != nil {
+			return nil, err
+		}
+		todos = append(todos, todo)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if err := loadTodoTags(q, todos); err != nil {
+		return nil, err
+	}
+	states := make(map[int]models.TodoState, len(todos))
+	for _, todo := range todos {
+		states[todo.ID] = StateOf(todo)
+	}
+	return states, nil
+}
+
+// subtreeStates returns the tracked state of a todo and of its subtasks,
+// at every level, leaving out those in the trash
+func subtreeStates(q queryer, id int) (map[int]models.TodoState, error) {
+	ids, err := subtreeIDs(q, id)
+	if err != nil || len(ids) == 0 {
+		return map[int]models.TodoState{}, err
+	}
+	return todoStates(q, "deleted_at IS NULL AND id IN ("+placeholders(len(ids))+")", ids...)
+}
+
+// recordChanges records action by actorID in the history of each todo in
+// before, diffed against its state now. Updates are only recorded where a
+// tracked field changed.
+func recordChanges(q queryer, actorID int, action string, before map[int]models.TodoState) error {
+	if len(before) == 0 {
+		return nil
+	}
+	ids := make([]interface{}, 0, len(before))
+	for id := range before {
+		ids = append(ids, id)
+	}
+	after, err := todoStates(q, "id IN ("+placeholders(len(ids))+")", ids...)
+	if err != nil {
+		return err
+	}
+	return recordRevisions(revisionWriter(q), actorID, 0, "", action, before, after, nil)
+}
@@ -0,0 +1,92 @@
+package store
+
+import (
+	"fmt"
+	"log"
+
+	"todo-list-app/internal/models"
+)
+
+// InsertTestData adds a test user (test@example.com, password
+// "password123") with a few todos for development, unless the user exists
+// already. The todos are written like any other, so they reach the search
+// index, their history and every user's change feed.
+func (s *SQLStore) InsertTestData() error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var count int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM users WHERE email = 'test@example.com'").Scan(&count); err != nil {
+		return fmt.Errorf("failed to check test data: %w", err)
+	}
+	if count > 0 {
+		log.Println("Test data already exists, skipping insertion")
+		return nil
+	}
+
+	userID, err := tx.Insert(`
+		INSERT INTO users (email, password_hash)
+		VALUES ('test@example.com', '$2a$10$570Q1w5Z9hL.WUb3Ch8Xwuycz0R9fFWXBVb9ebw.Os7FDTGmhtj1G')
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to insert test user: %w", err)
+	}
+
+	testTodos := []struct {
+		title       string
+		description string
+		priority    int
+		completed   bool
+		subtasks    []string
+	}{
+		{"Complete project setup", "Set up the initial project structure and configurations", 3, true, nil},
+		{"Implement authentication", "Create user registration and login functionality", 3, false, nil},
+		{"Build Todo CRUD", "Implement create, read, update, delete operations for todos", 2, false,
+			[]string{"Create todos", "List todos", "Update todos", "Delete todos"}},
+		{"Design UI/UX", "Create responsive and intuitive user interface", 2, false, nil},
+		{"Write tests", "Add unit and integration tests", 1, false, nil},
+	}
+
+	for _, seed := range testTodos {
+		change := TodoChange{
+			Todo: models.Todo{
+				UserID:      userID,
+				Title:       seed.title,
+				Description: seed.description,
+				Priority:    seed.priority,
+			},
+			Create: true,
+		}
+		if seed.completed {
+			change.Completed = &seed.completed
+		}
+		created, err := applyTodoChange(tx, s.dialect, userID, change)
+		if err != nil {
+			return fmt.Errorf("failed to insert test todo: %w", err)
+		}
+
+		for _, title := range seed.subtasks {
+			parentID := created.Todo.ID
+			if _, err := applyTodoChange(tx, s.dialect, userID, TodoChange{
+				Todo: models.Todo{
+					UserID:   userID,
+					ParentID: &parentID,
+					Title:    title,
+					Priority: seed.priority,
+				},
+				Create: true,
+			}); err != nil {
+				return fmt.Errorf("failed to insert test subtask: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Println("Test data inserted successfully")
+	return nil
+}
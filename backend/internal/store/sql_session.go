@@ -0,0 +1,115 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"todo-list-app/internal/models"
+)
+
+const sessionColumns = "id, user_id, user_agent, ip_address, created_at, last_seen_at, expires_at"
+
+func scanSession(row rowScanner) (models.Session, error) {
+	var session models.Session
+	err := row.Scan(
+		&session.ID, &session.UserID, &session.UserAgent, &session.IPAddress,
+		&session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt,
+	)
+	return session, err
+}
+
+// ListSessions returns the user's unexpired sessions, most recently seen first
+func (s *SQLStore) ListSessions(userID int, now time.Time) ([]models.Session, error) {
+	rows, err := s.db.Query(
+		"SELECT "+sessionColumns+" FROM sessions WHERE user_id = ? AND expires_at > ? ORDER BY last_seen_at DESC, id DESC",
+		userID, formatTime(now),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// CreateSession stores a session under the hash of its secret and removes
+// the user's expired sessions
+func (s *SQLStore) CreateSession(session *models.Session, hash string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(
+		"DELETE FROM sessions WHERE user_id = ? AND expires_at <= "+s.dialect.Now(), session.UserID,
+	); err != nil {
+		return fmt.Errorf("failed to remove expired sessions: %w", err)
+	}
+
+	sessionID, err := tx.Insert(`
+		INSERT INTO sessions (user_id, token_hash, user_agent, ip_address, expires_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, session.UserID, hash, session.UserAgent, session.IPAddress, formatTime(session.ExpiresAt))
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+
+	created, err := scanSession(tx.QueryRow("SELECT "+sessionColumns+" FROM sessions WHERE id = ?", sessionID))
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	*session = created
+	return nil
+}
+
+// GetSessionByHash returns the session whose secret has the given hash
+func (s *SQLStore) GetSessionByHash(hash string) (models.Session, error) {
+	session, err := scanSession(s.db.QueryRow("SELECT "+sessionColumns+" FROM sessions WHERE token_hash = ?", hash))
+	if err == sql.ErrNoRows {
+		return session, ErrNotFound
+	}
+	return session, err
+}
+
+// TouchSession records when a session was last seen
+func (s *SQLStore) TouchSession(id int, seenAt time.Time) error {
+	_, err := s.db.Exec("UPDATE sessions SET last_seen_at = ? WHERE id = ?", formatTime(seenAt), id)
+	return err
+}
+
+// DeleteSession ends one of the user's sessions
+func (s *SQLStore) DeleteSession(id, userID int) error {
+	result, err := s.db.Exec("DELETE FROM sessions WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+
+	if rowsAffected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteUserSessions ends all of the user's sessions
+func (s *SQLStore) DeleteUserSessions(userID int) error {
+	if _, err := s.db.Exec("DELETE FROM sessions WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("failed to delete sessions: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package store
+
+import (
+	"fmt"
+	"slices"
+
+	"todo-list-app/internal/models"
+)
+
+// syncTodos adds the todos to the change feed of every user who can see
+// them, together with their parents, whose subtask counts may have changed.
+// A user who was sent one of them before but can no longer see it gets a
+// tombstone instead. It runs after the change, in the same transaction.
+func syncTodos(q queryer, ids ...interface{}) error {
+	return syncFeeds(q, 0, ids)
+}
+
+// syncProjectTodos adds every todo of a project to one user's change feed,
+// after the user joined or left the project
+func syncProjectTodos(q queryer, projectID, userID int) error {
+	ids, err := queryIDs(q, "SELECT id FROM todos WHERE project_id = ?", projectID)
+	if err != nil {
+		return err
+	}
+	return syncFeeds(q, userID, ids)
+}
+
+// syncFeeds does the work of syncTodos for one user's feed, or for every
+// user's if userID is 0
+func syncFeeds(q queryer, userID int, ids []interface{}) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	parents, err := queryIDs(q,
+		"SELECT DISTINCT parent_id FROM todos WHERE parent_id IS NOT NULL AND id IN ("+placeholders(len(ids))+")", ids...)
+	if err != nil {
+		return err
+	}
+	ids = append(slices.Clone(ids), parents...)
+	in := "IN (" + placeholders(len(ids)) + ")"
+
+	// Who can see the todos now: the creator of an inbox todo, and the
+	// owner and members of a project
+	visible, err := queryTodoUsers(q, `
+		SELECT id, user_id FROM todos WHERE deleted_at IS NULL AND project_id IS NULL AND id `+in+`
+		UNION SELECT t.id, p.user_id FROM todos t JOIN projects p ON p.id = t.project_id
+			WHERE t.deleted_at IS NULL AND t.id `+in+`
+		UNION SELECT t.id, m.user_id FROM todos t JOIN project_members m ON m.project_id = t.project_id
+			WHERE t.deleted_at IS NULL AND t.id `+in,
+		slices.Concat(ids, ids, ids)...,
+	)
+	if err != nil {
+		return err
+	}
+	// Who was sent them before
+	known, err := queryTodoUsers(q, "SELECT todo_id, user_id FROM sync_changes WHERE deleted = ? AND todo_id "+in,
+		append([]interface{}{false}, ids...)...)
+	if err != nil {
+		return err
+	}
+
+	feeds := make(map[int]map[int]bool) // user ID -> todo ID -> deleted
+	for i, pairs := range [][][2]int{visible, known} {
+		for _, pair := range pairs {
+			todoID, feedUserID := pair[0], pair[1]
+			if userID != 0 && feedUserID != userID {
+				continue
+			}
+			if feeds[feedUserID] == nil {
+				feeds[feedUserID] = make(map[int]bool)
+			}
+			if _, seen := feeds[feedUserID][todoID]; !seen {
+				feeds[feedUserID][todoID] = i == 1
+			}
+		}
+	}
+
+	// Users are handled in a fixed order so that concurrent transactions
+	// lock their sequences in the same order
+	userIDs := make([]int, 0, len(feeds))
+	for feedUserID := range feeds {
+		userIDs = append(userIDs, feedUserID)
+	}
+	slices.Sort(userIDs)
+
+	for _, userID := range userIDs {
+		todoIDs := make([]int, 0, len(feeds[userID]))
+		for todoID := range feeds[userID] {
+			todoIDs = append(todoIDs, todoID)
+		}
+		slices.Sort(todoIDs)
+
+		// Reserve one sequence number per todo
+		if _, err := q.Exec(`
+			INSERT INTO sync_sequences (user_id, last_seq) VALUES (?, ?)
+			ON CONFLICT (user_id) DO UPDATE SET last_seq = sync_sequences.last_seq + excluded.last_seq
+		`, userID, len(todoIDs)); err != nil {
+			return fmt.Errorf("failed to advance sync sequence: %w", err)
+		}
+		var last int
+		if err := q.QueryRow("SELECT last_seq FROM sync_sequences WHERE user_id = ?", userID).Scan(&last); err != nil {
+			return err
+		}
+
+		for i, todoID := range todoIDs {
+			seq := last - len(todoIDs) + 1 + i
+			if _, err := q.Exec(`
+				INSERT INTO sync_changes (user_id, todo_id, seq, created_seq, deleted) VALUES (?, ?, ?, ?, ?)
+				ON CONFLICT (user_id, todo_id) DO UPDATE SET seq = excluded.seq, deleted = excluded.deleted,
+					created_seq = CASE WHEN sync_changes.deleted THEN excluded.created_seq ELSE sync_changes.created_seq END
+			`, userID, todoID, seq, seq, feeds[userID][todoID]); err != nil {
+				return fmt.Errorf("failed to record sync change: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// queryTodoUsers runs a query selecting pairs of todo and user IDs
+func queryTodoUsers(q queryer, query string, args ...interface{}) ([][2]int, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pairs [][2]int
+	for rows.Next() {
+		var pair [2]int
+		if err := rows.Scan(&pair[0], &pair[1]); err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, pair)
+	}
+	return pairs, rows.Err()
+}
+
+// ListSyncChanges returns the entries of the user's change feed after
+// sequence number since, in order
+func (s *SQLStore) ListSyncChanges(userID, since, limit int) ([]SyncChange, error) {
+	rows, err := s.db.Query(`
+		SELECT todo_id, seq, created_seq, deleted FROM sync_changes
+		WHERE user_id = ? AND seq > ? ORDER BY seq LIMIT ?
+	`, userID, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []SyncChange{}
+	var ids []interface{}
+	for rows.Next() {
+		var change SyncChange
+		if err := rows.Scan(&change.Todo.ID, &change.Seq, &change.CreatedSeq, &change.Deleted); err != nil {
+			return nil, err
+		}
+		if !change.Deleted {
+			ids = append(ids, change.Todo.ID)
+		}
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if len(ids) == 0 {
+		return changes, nil
+	}
+	clause, args := visibleClause("todos", userID)
+	todoRows, err := s.db.Query(
+		"SELECT "+todoColumns+" FROM todos WHERE "+clause+" AND id IN ("+placeholders(len(ids))+")", append(args, ids...)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer todoRows.Close()
+
+	var todos []models.Todo
+	for todoRows.Next() {
+		todo, err := scanTodo(todoRows)
+		if err != nil {
+			return nil, err
+		}
+		todos = append(todos, todo)
+	}
+	if err := todoRows.Err(); err != nil {
+		return nil, err
+	}
+	todoRows.Close()
+
+	if err := loadTodoDetails(s.db, todos); err != nil {
+		return nil, err
+	}
+	byID := make(map[int]models.Todo, len(todos))
+	for _, todo := range todos {
+		byID[todo.ID] = todo
+	}
+
+	// A todo deleted or hidden since the feed was read shows up as a
+	// tombstone; the feed already has a newer entry for it
+	for i, change := range changes {
+		if change.Deleted {
+			continue
+		}
+		if todo, ok := byID[change.Todo.ID]; ok {
+			changes[i].Todo = todo
+		} else {
+			changes[i].Deleted = true
+		}
+	}
+	return changes, nil
+}
@@ -0,0 +1,239 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+
+	"todo-list-app/internal/database"
+	"todo-list-app/internal/models"
+)
+
+// tagSelect selects tags with their todo counts, not counting the trash;
+// callers append WHERE and must end with GROUP BY t.id
+const tagSelect = `
+	SELECT t.id, t.user_id, t.name, COUNT(td.id), t.created_at
+	FROM tags t
+	LEFT JOIN todo_tags tt ON tt.tag_id = t.id
+	LEFT JOIN todos td ON td.id = tt.todo_id AND td.deleted_at IS NULL`
+
+func scanTag(row rowScanner) (models.Tag, error) {
+	var tag models.Tag
+	err := row.Scan(&tag.ID, &tag.UserID, &tag.Name, &tag.TodoCount, &tag.CreatedAt)
+	return tag, err
+}
+
+// ListTags returns the user's tags with the number of todos using each
+func (s *SQLStore) ListTags(userID int) ([]models.Tag, error) {
+	rows, err := s.db.Query(tagSelect+`
+		WHERE t.user_id = ?
+		GROUP BY t.id
+		ORDER BY `+s.dialect.Fold("t.name")+`
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []models.Tag{}
+	for rows.Next() {
+		tag, err := scanTag(rows)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// GetTag loads a single tag with its todo count
+func (s *SQLStore) GetTag(id, userID int) (models.Tag, error) {
+	tag, err := scanTag(s.db.QueryRow(tagSelect+`
+		WHERE t.id = ? AND t.user_id = ?
+		GROUP BY t.id
+	`, id, userID))
+	if err == sql.ErrNoRows {
+		return tag, ErrNotFound
+	}
+	return tag, err
+}
+
+// CreateTag creates a new tag
+func (s *SQLStore) CreateTag(userID int, name string) (models.Tag, error) {
+	if _, err := findTagID(s.db, s.dialect, userID, name); err == nil {
+		return models.Tag{}, ErrConflict
+	} else if err != sql.ErrNoRows {
+		return models.Tag{}, err
+	}
+
+	tagID, err := s.db.Insert("INSERT INTO tags (user_id, name) VALUES (?, ?)", userID, name)
+	if err != nil {
+		return models.Tag{}, fmt.Errorf("failed to create tag: %w", err)
+	}
+
+	return s.GetTag(tagID, userID)
+}
+
+// RenameTag renames a tag unless another of the user's tags has the name
+func (s *SQLStore) RenameTag(id, userID int, name string) (models.Tag, error) {
+	if existingID, err := findTagID(s.db, s.dialect, userID, name); err == nil && existingID != id {
+		return models.Tag{}, ErrConflict
+	} else if err != nil && err != sql.ErrNoRows {
+		return models.Tag{}, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return models.Tag{}, err
+	}
+	defer tx.Rollback()
+
+	before, err := taggedStates(tx, id)
+	if err != nil {
+		return models.Tag{}, err
+	}
+	result, err := tx.Exec("UPDATE tags SET name = ? WHERE id = ? AND user_id = ?", name, id, userID)
+	if err != nil {
+		return models.Tag{}, fmt.Errorf("failed to update tag: %w", err)
+	}
+
+	if rowsAffected, err := result.RowsAffected(); err != nil {
+		return models.Tag{}, err
+	} else if rowsAffected == 0 {
+		return models.Tag{}, ErrNotFound
+	}
+
+	if err := touchTaggedTodos(tx, id); err != nil {
+		return models.Tag{}, err
+	}
+	if err := recordChanges(tx, userID, models.RevisionUpdate, before); err != nil {
+		return models.Tag{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Tag{}, err
+	}
+
+	return s.GetTag(id, userID)
+}
+
+// MergeTags moves the source tag's todos onto the target tag and deletes it
+func (s *SQLStore) MergeTags(sourceID, targetID, userID int) (models.Tag, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return models.Tag{}, err
+	}
+	defer tx.Rollback()
+
+	// Both tags must belong to the user
+	var owned int
+	err = tx.QueryRow(
+		"SELECT COUNT(*) FROM tags WHERE id IN (?, ?) AND user_id = ?",
+		sourceID, targetID, userID,
+	).Scan(&owned)
+	if err != nil {
+		return models.Tag{}, err
+	}
+	if owned != 2 {
+		return models.Tag{}, ErrNotFound
+	}
+
+	before, err := taggedStates(tx, sourceID)
+	if err != nil {
+		return models.Tag{}, err
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO todo_tags (todo_id, tag_id)
+		SELECT todo_id, CAST(? AS INTEGER) FROM todo_tags WHERE tag_id = ?
+		ON CONFLICT (todo_id, tag_id) DO NOTHING
+	`, targetID, sourceID); err != nil {
+		return models.Tag{}, fmt.Errorf("failed to merge tags: %w", err)
+	}
+
+	if err := deleteTag(tx, sourceID); err != nil {
+		return models.Tag{}, err
+	}
+	if err := recordChanges(tx, userID, models.RevisionUpdate, before); err != nil {
+		return models.Tag{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Tag{}, err
+	}
+
+	return s.GetTag(targetID, userID)
+}
+
+// DeleteTag deletes a tag and unlinks it from every todo
+func (s *SQLStore) DeleteTag(id, userID int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var existingID int
+	err = tx.QueryRow("SELECT id FROM tags WHERE id = ? AND user_id = ?", id, userID).Scan(&existingID)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	} else if err != nil {
+		return err
+	}
+
+	before, err := taggedStates(tx, id)
+	if err != nil {
+		return err
+	}
+	if err := deleteTag(tx, id); err != nil {
+		return err
+	}
+	if err := recordChanges(tx, userID, models.RevisionUpdate, before); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// deleteTag removes a tag along with its todo links. SQLite does not enforce
+// foreign keys by default, so the links are removed explicitly.
+func deleteTag(q queryer, tagID int) error {
+	if err := touchTaggedTodos(q, tagID); err != nil {
+		return err
+	}
+	if _, err := q.Exec("DELETE FROM todo_tags WHERE tag_id = ?", tagID); err != nil {
+		return fmt.Errorf("failed to unlink tag: %w", err)
+	}
+	if _, err := q.Exec("DELETE FROM tags WHERE id = ?", tagID); err != nil {
+		return fmt.Errorf("failed to delete tag: %w", err)
+	}
+	return nil
+}
+
+// taggedStates returns the tracked state of the todos linked to a tag,
+// whose history a change to the tag adds to
+func taggedStates(q queryer, tagID int) (map[int]models.TodoState, error) {
+	return todoStates(q, "id IN (SELECT todo_id FROM todo_tags WHERE tag_id = ?)", tagID)
+}
+
+// touchTaggedTodos bumps the version of the todos linked to a tag, whose
+// tag names are changing
+func touchTaggedTodos(q queryer, tagID int) error {
+	ids, err := queryIDs(q, "SELECT todo_id FROM todo_tags WHERE tag_id = ?", tagID)
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+	if _, err := q.Exec(
+		"UPDATE todos SET version = version + 1 WHERE id IN ("+placeholders(len(ids))+")", ids...,
+	); err != nil {
+		return fmt.Errorf("failed to update tagged todos: %w", err)
+	}
+	return syncTodos(q, ids...)
+}
+
+// findTagID looks up a tag by name (case-insensitive) for a user
+func findTagID(q queryer, d database.Dialect, userID int, name string) (int, error) {
+	var tagID int
+	err := q.QueryRow(
+		"SELECT id FROM tags WHERE user_id = ? AND "+d.Fold("name")+" = "+d.Fold("?"),
+		userID, name,
+	).Scan(&tagID)
+	return tagID, err
+}
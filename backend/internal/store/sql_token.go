@@ -0,0 +1,93 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"todo-list-app/internal/models"
+)
+
+const tokenColumns = "id, user_id, name, prefix, scopes, expires_at, last_used_at, created_at"
+
+func scanToken(row rowScanner) (models.APIToken, error) {
+	var token models.APIToken
+	var scopes string
+	err := row.Scan(
+		&token.ID, &token.UserID, &token.Name, &token.Prefix, &scopes,
+		&token.ExpiresAt, &token.LastUsedAt, &token.CreatedAt,
+	)
+	token.Scopes = strings.Fields(scopes)
+	return token, err
+}
+
+// ListTokens returns the user's tokens, newest first
+func (s *SQLStore) ListTokens(userID int) ([]models.APIToken, error) {
+	rows, err := s.db.Query(
+		"SELECT "+tokenColumns+" FROM api_tokens WHERE user_id = ? ORDER BY created_at DESC, id DESC",
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []models.APIToken{}
+	for rows.Next() {
+		token, err := scanToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// CreateToken stores a token under the hash of its secret
+func (s *SQLStore) CreateToken(token *models.APIToken, hash string) error {
+	tokenID, err := s.db.Insert(`
+		INSERT INTO api_tokens (user_id, name, token_hash, prefix, scopes, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, token.UserID, token.Name, hash, token.Prefix, strings.Join(token.Scopes, " "), nullableTime(token.ExpiresAt))
+	if err != nil {
+		return fmt.Errorf("failed to create token: %w", err)
+	}
+
+	created, err := scanToken(s.db.QueryRow("SELECT "+tokenColumns+" FROM api_tokens WHERE id = ?", tokenID))
+	if err != nil {
+		return err
+	}
+	*token = created
+	return nil
+}
+
+// GetTokenByHash returns the token whose secret has the given hash
+func (s *SQLStore) GetTokenByHash(hash string) (models.APIToken, error) {
+	token, err := scanToken(s.db.QueryRow("SELECT "+tokenColumns+" FROM api_tokens WHERE token_hash = ?", hash))
+	if err == sql.ErrNoRows {
+		return token, ErrNotFound
+	}
+	return token, err
+}
+
+// TouchToken records when a token was last used
+func (s *SQLStore) TouchToken(id int, usedAt time.Time) error {
+	_, err := s.db.Exec("UPDATE api_tokens SET last_used_at = ? WHERE id = ?", formatTime(usedAt), id)
+	return err
+}
+
+// DeleteToken revokes one of the user's tokens
+func (s *SQLStore) DeleteToken(id, userID int) error {
+	result, err := s.db.Exec("DELETE FROM api_tokens WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete token: %w", err)
+	}
+
+	if rowsAffected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
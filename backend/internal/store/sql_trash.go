@@ -0,0 +1,241 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"todo-list-app/internal/models"
+)
+
+// trashClause builds the WHERE condition for the trash a user sees: the
+// deleted todos they could restore whose parent, if any, is not deleted
+func trashClause(userID int) (string, []interface{}) {
+	return `deleted_at IS NOT NULL
+		AND (parent_id IS NULL OR parent_id IN (SELECT id FROM todos WHERE deleted_at IS NULL))
+		AND ((project_id IS NULL AND user_id = ?) OR project_id IN (
+			SELECT id FROM projects WHERE user_id = ?
+			UNION SELECT project_id FROM project_members WHERE user_id = ? AND role IN ('editor', 'owner')))`,
+		[]interface{}{userID, userID, userID}
+}
+
+// ListTrash returns the trashed todos the user can restore, most recently
+// deleted first
+func (s *SQLStore) ListTrash(userID int) ([]models.Todo, error) {
+	clause, args := trashClause(userID)
+	rows, err := s.db.Query(
+		"SELECT "+todoColumns+" FROM todos WHERE "+clause+" ORDER BY deleted_at DESC, id DESC", args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	todos := []models.Todo{}
+	for rows.Next() {
+		todo, err := scanTodo(rows)
+		if err != nil {
+			return nil, err
+		}
+		todos = append(todos, todo)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if err := loadTodoDetails(s.db, todos); err != nil {
+		return nil, err
+	}
+	return todos, nil
+}
+
+// GetTrashedTodo loads a todo in the trash
+func (s *SQLStore) GetTrashedTodo(id int) (models.Todo, error) {
+	todo, err := scanTodo(s.db.QueryRow(
+		"SELECT "+todoColumns+" FROM todos WHERE id = ? AND deleted_at IS NOT NULL", id,
+	))
+	if err == sql.ErrNoRows {
+		return todo, ErrNotFound
+	} else if err != nil {
+		return todo, err
+	}
+
+	todos := []models.Todo{todo}
+	if err := loadTodoDetails(s.db, todos); err != nil {
+		return todo, err
+	}
+	return todos[0], nil
+}
+
+// RestoreTodo clears deleted_at on the todo and on the subtasks that were
+// deleted at the same moment, recording the restore in their history
+func (s *SQLStore) RestoreTodo(id, actorID int) (models.Todo, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return models.Todo{}, err
+	}
+	defer tx.Rollback()
+
+	var parentID sql.NullInt64
+	var deletedAt time.Time
+	err = tx.QueryRow(
+		"SELECT parent_id, deleted_at FROM todos WHERE id = ? AND deleted_at IS NOT NULL", id,
+	).Scan(&parentID, &deletedAt)
+	if err == sql.ErrNoRows {
+		return models.Todo{}, ErrNotFound
+	} else if err != nil {
+		return models.Todo{}, err
+	}
+
+	if parentID.Valid {
+		var parentDeleted bool
+		if err := tx.QueryRow(
+			"SELECT deleted_at IS NOT NULL FROM todos WHERE id = ?", parentID.Int64,
+		).Scan(&parentDeleted); err != nil {
+			return models.Todo{}, err
+		}
+		if parentDeleted {
+			return models.Todo{}, ErrConflict
+		}
+	}
+
+	ids, err := subtreeIDs(tx, id)
+	if err != nil {
+		return models.Todo{}, err
+	}
+	restored := "deleted_at = ? AND id IN (" + placeholders(len(ids)) + ")"
+	args := append([]interface{}{formatTime(deletedAt)}, ids...)
+	if ids, err = queryIDs(tx, "SELECT id FROM todos WHERE "+restored, args...); err != nil {
+		return models.Todo{}, err
+	}
+	if _, err := tx.Exec("UPDATE todos SET deleted_at = NULL, version = version + 1 WHERE "+restored, args...); err != nil {
+		return models.Todo{}, fmt.Errorf("failed to restore todo: %w", err)
+	}
+	if err := touchParents(tx, ids...); err != nil {
+		return models.Todo{}, err
+	}
+	if err := syncTodos(tx, ids...); err != nil {
+		return models.Todo{}, err
+	}
+	states, err := todoStates(tx, "id IN ("+placeholders(len(ids))+")", ids...)
+	if err != nil {
+		return models.Todo{}, err
+	}
+	if err := recordRevisions(revisionWriter(tx), actorID, id, models.RevisionRestore, models.RevisionRestore, states, states, nil); err != nil {
+		return models.Todo{}, err
+	}
+
+	todo, err := getTodo(tx, id)
+	if err != nil {
+		return models.Todo{}, err
+	}
+	return todo, tx.Commit()
+}
+
+// EmptyTrash permanently deletes the user's trash
+func (s *SQLStore) EmptyTrash(userID int) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	clause, args := trashClause(userID)
+	roots, err := queryIDs(tx, "SELECT id FROM todos WHERE "+clause, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	var ids []interface{}
+	for _, root := range roots {
+		subtree, err := subtreeIDs(tx, root.(int))
+		if err != nil {
+			return 0, err
+		}
+		ids = append(ids, subtree...)
+	}
+
+	blobKeys, err := purgeTodos(tx, ids)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	s.releaseBlobs(blobKeys)
+	return len(ids), nil
+}
+
+// PurgeTrash permanently deletes the todos trashed before the cutoff. A
+// subtask never goes to the trash after its parent, so whole subtrees are
+// purged together.
+func (s *SQLStore) PurgeTrash(before time.Time) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	ids, err := queryIDs(tx, "SELECT id FROM todos WHERE deleted_at < ?", formatTime(before))
+	if err != nil {
+		return 0, err
+	}
+
+	blobKeys, err := purgeTodos(tx, ids)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	s.releaseBlobs(blobKeys)
+	return len(ids), nil
+}
+
+// queryIDs runs a query selecting a single integer column
+func queryIDs(q queryer, query string, args ...interface{}) ([]interface{}, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []interface{}{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// purgeTodos permanently deletes todos with their tag links, comments,
+// history and attachments, returning the blob keys to release after commit. SQLite
+// does not enforce foreign keys, so everything is deleted explicitly.
+func purgeTodos(q queryer, ids []interface{}) ([]string, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	in := "IN (" + placeholders(len(ids)) + ")"
+
+	if _, err := q.Exec("DELETE FROM todo_tags WHERE todo_id "+in, ids...); err != nil {
+		return nil, fmt.Errorf("failed to unlink tags: %w", err)
+	}
+	if _, err := q.Exec("DELETE FROM comments WHERE todo_id "+in, ids...); err != nil {
+		return nil, fmt.Errorf("failed to delete comments: %w", err)
+	}
+	if _, err := q.Exec("DELETE FROM todo_revisions WHERE todo_id "+in, ids...); err != nil {
+		return nil, fmt.Errorf("failed to delete history: %w", err)
+	}
+	blobKeys, err := deleteAttachments(q, in, ids...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := q.Exec("DELETE FROM todos WHERE id "+in, ids...); err != nil {
+		return nil, fmt.Errorf("failed to delete todos: %w", err)
+	}
+	return blobKeys, nil
+}
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+
+	"todo-list-app/internal/models"
+)
+
+// GetUserByEmail loads a user, including the password hash, by email
+func (s *SQLStore) GetUserByEmail(email string) (models.User, error) {
+	var user models.User
+	err := s.db.QueryRow(
+		"SELECT id, email, password_hash, created_at FROM users WHERE email = ?",
+		email,
+	).Scan(&user.ID, &user.Email, &user.Password, &user.CreatedAt)
+	if err == sql.ErrNoRows {
+		return user, ErrNotFound
+	}
+	return user, err
+}
+
+// GetUserByID loads a user, including the password hash, by ID
+func (s *SQLStore) GetUserByID(id int) (models.User, error) {
+	var user models.User
+	err := s.db.QueryRow(
+		"SELECT id, email, password_hash, created_at FROM users WHERE id = ?",
+		id,
+	).Scan(&user.ID, &user.Email, &user.Password, &user.CreatedAt)
+	if err == sql.ErrNoRows {
+		return user, ErrNotFound
+	}
+	return user, err
+}
+
+// CreateUser creates a user with an already hashed password
+func (s *SQLStore) CreateUser(email, passwordHash string) (models.User, error) {
+	if _, err := s.GetUserByEmail(email); err == nil {
+		return models.User{}, ErrConflict
+	} else if err != ErrNotFound {
+		return models.User{}, err
+	}
+
+	userID, err := s.db.Insert(
+		"INSERT INTO users (email, password_hash) VALUES (?, ?)",
+		email, passwordHash,
+	)
+	if err != nil {
+		return models.User{}, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return s.GetUserByID(userID)
+}
+
+// UpdatePassword replaces a user's password hash and ends all of the
+// user's sessions
+func (s *SQLStore) UpdatePassword(id int, passwordHash string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE users SET password_hash = ? WHERE id = ?", passwordHash, id)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	if rowsAffected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	if _, err := tx.Exec("DELETE FROM sessions WHERE user_id = ?", id); err != nil {
+		return fmt.Errorf("failed to end sessions: %w", err)
+	}
+	return tx.Commit()
+}
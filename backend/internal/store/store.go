@@ -0,0 +1,424 @@
+// Package store defines the persistence interfaces used by the HTTP handlers
+// together with a SQL implementation (SQLite or Postgres) and an in-memory
+// implementation.
+package store
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
+	"todo-list-app/internal/models"
+)
+
+var (
+	// ErrNotFound is returned when the requested record does not exist or
+	// does not belong to the requesting user
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when a write conflicts with the stored state:
+	// a record would violate a uniqueness rule, a todo is no longer at the
+	// version the write was based on, or the state the write relies on is
+	// gone, like the rule of a todo whose next occurrence was already
+	// created or a parent that is still in the trash
+	ErrConflict = errors.New("conflict")
+	// ErrInvalidCursor is returned when a cursor does not fit the query's sort
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// TodoStore persists todos
+type TodoStore interface {
+	// ListTodos returns the todos matching the query, in query order
+	ListTodos(q TodoQuery) ([]models.Todo, error)
+	// GetTodo returns a todo by ID regardless of owner; todos in the trash
+	// are not found
+	GetTodo(id int) (models.Todo, error)
+	// ApplyTodoChanges applies a batch of changes in one transaction and
+	// returns a result for each. Every todo a change creates or changes,
+	// subtasks included, gets a revision made by actorID in the same
+	// transaction. A change fails with ErrNotFound or ErrConflict if its
+	// todo is gone or no longer at the version it was loaded at; versions
+	// bumped by earlier changes of the same batch, such as a parent's when
+	// its subtask is completed, do not count. Each todo may have at most
+	// one change in a batch. A failed
+	// change is undone and the rest still apply, unless atomic is set: then
+	// the first failure undoes the whole batch and the changes after it are
+	// not attempted.
+	ApplyTodoChanges(actorID int, changes []TodoChange, atomic bool) ([]TodoChangeResult, error)
+	// ListSubtasks returns a todo's direct subtasks, oldest first
+	ListSubtasks(parentID int) ([]models.Todo, error)
+	// GetAncestorIDs returns the IDs of a todo's parent, grandparent and so
+	// on up to the top-level todo
+	GetAncestorIDs(id int) ([]int, error)
+	// SubtreeHeight returns how many levels of subtasks a todo has below it
+	SubtreeHeight(id int) (int, error)
+	// SearchTodos runs a full-text search over the todos a user can see
+	SearchTodos(userID int, terms []SearchTerm, limit int) ([]models.TodoSearchResult, error)
+
+	// ListTrash returns the todos in the trash that the user can restore:
+	// their own inbox todos and those of projects where they are an
+	// editor or owner. Subtasks whose parent is in the trash too are left
+	// out. The most recently deleted come first.
+	ListTrash(userID int) ([]models.Todo, error)
+	// GetTrashedTodo returns a todo in the trash
+	GetTrashedTodo(id int) (models.Todo, error)
+	// RestoreTodo takes a todo out of the trash together with the subtasks
+	// deleted along with it, recording a restore by actorID in their
+	// history. It returns ErrConflict while the todo's parent is still in
+	// the trash.
+	RestoreTodo(id, actorID int) (models.Todo, error)
+	// EmptyTrash permanently deletes the todos ListTrash returns, with
+	// their subtasks, and reports how many todos were removed
+	EmptyTrash(userID int) (int, error)
+	// PurgeTrash permanently deletes every todo that went to the trash
+	// before the cutoff and reports how many were removed
+	PurgeTrash(before time.Time) (int, error)
+}
+
+// TagStore persists per-user tags
+type TagStore interface {
+	// ListTags returns the user's tags ordered by name, with todo counts
+	ListTags(userID int) ([]models.Tag, error)
+	// GetTag returns one of the user's tags with its todo count
+	GetTag(id, userID int) (models.Tag, error)
+	// CreateTag creates a tag, returning ErrConflict if the name is taken
+	CreateTag(userID int, name string) (models.Tag, error)
+	// RenameTag renames a tag, returning ErrConflict if another tag has the name
+	RenameTag(id, userID int, name string) (models.Tag, error)
+	// MergeTags moves every todo from the source tag to the target tag and
+	// deletes the source
+	MergeTags(sourceID, targetID, userID int) (models.Tag, error)
+	// DeleteTag deletes a tag and unlinks it from every todo
+	DeleteTag(id, userID int) error
+}
+
+// ProjectStore persists projects and who they are shared with. Projects
+// are returned with the requesting user's role; a project the user has no
+// role on is reported as ErrNotFound.
+type ProjectStore interface {
+	// ListProjects returns the projects the user owns or is a member of, in
+	// sort order with their open todo counts. Archived projects are left
+	// out unless asked for.
+	ListProjects(userID int, includeArchived bool) ([]models.Project, error)
+	// GetProject returns a project the user owns or is a member of
+	GetProject(id, userID int) (models.Project, error)
+	// CreateProject creates a project owned by project.UserID, filling in
+	// the ID and timestamps
+	CreateProject(project *models.Project) error
+	// UpdateProject saves the name, color, archived flag and sort order of
+	// a project and reloads it
+	UpdateProject(project *models.Project) error
+	// DeleteProject deletes a project with its members and invitations. Its
+	// todos move to their creators' inboxes, losing assignees other than
+	// the creator; when deleteTodos is set they go to the trash as well.
+	// The todos' history records this as a change by actorID.
+	DeleteProject(id, actorID int, deleteTodos bool) error
+
+	// ListMembers returns the project's creator followed by its members in
+	// the order they joined
+	ListMembers(projectID int) ([]models.ProjectMember, error)
+	// SetMemberRole changes the role of a member
+	SetMemberRole(projectID, userID int, role string) error
+	// DeleteMember removes a member from a project and unassigns them from
+	// its todos, which the todos' history records as a change by actorID
+	DeleteMember(projectID, userID, actorID int) error
+
+	// ListProjectInvitations returns a project's open invitations, oldest first
+	ListProjectInvitations(projectID int) ([]models.ProjectInvitation, error)
+	// ListUserInvitations returns the open invitations for an email
+	// address, oldest first
+	ListUserInvitations(email string) ([]models.ProjectInvitation, error)
+	// GetInvitation returns an open invitation
+	GetInvitation(id int) (models.ProjectInvitation, error)
+	// CreateInvitation stores an invitation, filling in the ID and creation
+	// time. It returns ErrConflict if the email is already invited.
+	CreateInvitation(invitation *models.ProjectInvitation) error
+	// AcceptInvitation makes the user a member with the invitation's role
+	// and removes the invitation. It returns ErrConflict if the user
+	// already has a role on the project.
+	AcceptInvitation(id, userID int) error
+	// DeleteInvitation revokes or declines an invitation
+	DeleteInvitation(id int) error
+}
+
+// CommentStore persists the comments on todos
+type CommentStore interface {
+	// ListComments returns a todo's comments, oldest first
+	ListComments(todoID int) ([]models.Comment, error)
+	// GetComment returns a comment by ID
+	GetComment(id int) (models.Comment, error)
+	// CreateComment stores a new comment, filling in the ID, author email
+	// and timestamps
+	CreateComment(comment *models.Comment) error
+	// UpdateComment replaces a comment's body, marks it edited and reloads it
+	UpdateComment(comment *models.Comment) error
+	// DeleteComment deletes a comment
+	DeleteComment(id int) error
+}
+
+// AttachmentStore persists files attached to todos. The content is kept
+// in a blob store; a blob is removed once no attachment refers to it,
+// including when attachments go away with their todo or project.
+type AttachmentStore interface {
+	// ListAttachments returns a todo's attachments, oldest first
+	ListAttachments(todoID int) ([]models.Attachment, error)
+	// GetAttachment returns an attachment by ID
+	GetAttachment(id int) (models.Attachment, error)
+	// CreateAttachment stores content in the blob store and records the
+	// attachment, filling in the ID, size, hash and creation time. An
+	// error reading content is returned as is.
+	CreateAttachment(attachment *models.Attachment, content io.Reader) error
+	// OpenAttachment returns the content of an attachment
+	OpenAttachment(attachment models.Attachment) (io.ReadSeekCloser, error)
+	// DeleteAttachment deletes an attachment
+	DeleteAttachment(id int) error
+}
+
+// RevisionStore reads the history of each todo. Revisions are added by the
+// writes that change a todo, in the same transaction, and go away when
+// their todo is purged from the trash.
+type RevisionStore interface {
+	// ListRevisions returns a todo's history, oldest first
+	ListRevisions(todoID int) ([]models.TodoRevision, error)
+	// GetRevision returns revision number revision of a todo
+	GetRevision(todoID, revision int) (models.TodoRevision, error)
+}
+
+// UserStore persists user accounts
+type UserStore interface {
+	// GetUserByEmail returns a user, including the password hash
+	GetUserByEmail(email string) (models.User, error)
+	// GetUserByID returns a user, including the password hash
+	GetUserByID(id int) (models.User, error)
+	// CreateUser creates a user, returning ErrConflict if the email is taken
+	CreateUser(email, passwordHash string) (models.User, error)
+	// UpdatePassword replaces a user's password hash and ends all of the
+	// user's sessions
+	UpdatePassword(id int, passwordHash string) error
+}
+
+// TokenStore persists personal access tokens. Tokens are looked up by a
+// hash of their secret; the secret itself is never stored.
+type TokenStore interface {
+	// ListTokens returns the user's tokens, newest first
+	ListTokens(userID int) ([]models.APIToken, error)
+	// CreateToken stores a token under the hash of its secret, filling in
+	// the ID and creation time
+	CreateToken(token *models.APIToken, hash string) error
+	// GetTokenByHash returns the token whose secret has the given hash
+	GetTokenByHash(hash string) (models.APIToken, error)
+	// TouchToken records when a token was last used
+	TouchToken(id int, usedAt time.Time) error
+	// DeleteToken revokes one of the user's tokens
+	DeleteToken(id, userID int) error
+}
+
+// SessionStore persists login sessions. Like tokens, sessions are looked up
+// by a hash of the secret kept in the cookie.
+type SessionStore interface {
+	// ListSessions returns the user's unexpired sessions, most recently
+	// seen first
+	ListSessions(userID int, now time.Time) ([]models.Session, error)
+	// CreateSession stores a session under the hash of its secret, filling
+	// in the ID and timestamps. The user's expired sessions are removed.
+	CreateSession(session *models.Session, hash string) error
+	// GetSessionByHash returns the session whose secret has the given hash
+	GetSessionByHash(hash string) (models.Session, error)
+	// TouchSession records when a session was last seen
+	TouchSession(id int, seenAt time.Time) error
+	// DeleteSession ends one of the user's sessions
+	DeleteSession(id, userID int) error
+	// DeleteUserSessions ends all of the user's sessions
+	DeleteUserSessions(userID int) error
+}
+
+// IdempotencyStore persists the responses of requests sent with an
+// Idempotency-Key so that a retry can be answered without running the
+// request again. Keys belong to the user who sent them.
+type IdempotencyStore interface {
+	// ReserveIdempotencyKey claims a key for a request about to run, filling
+	// in the ID and creation time. If the user already holds the unexpired
+	// key, the stored record is returned with ErrConflict. The user's
+	// expired keys are removed.
+	ReserveIdempotencyKey(record *models.IdempotencyRecord) (models.IdempotencyRecord, error)
+	// CompleteIdempotencyKey stores the response to a reserved key's request
+	CompleteIdempotencyKey(id, status int, header map[string][]string, body []byte) error
+	// ReleaseIdempotencyKey deletes a reserved key so the request can be
+	// retried
+	ReleaseIdempotencyKey(id int) error
+}
+
+// SyncStore keeps a change feed of todos for each user. Every change to a
+// todo the user can see, and every todo they can no longer see, takes the
+// next number in the user's own sequence; a todo appears in the feed only
+// with its latest change.
+type SyncStore interface {
+	// ListSyncChanges returns at most limit entries of the user's change
+	// feed after sequence number since, in order. Since 0 lists every todo
+	// the user can see, along with tombstones.
+	ListSyncChanges(userID, since, limit int) ([]SyncChange, error)
+}
+
+// Store bundles every store interface behind a single backend
+type Store interface {
+	TodoStore
+	TagStore
+	ProjectStore
+	CommentStore
+	AttachmentStore
+	RevisionStore
+	UserStore
+	TokenStore
+	SessionStore
+	IdempotencyStore
+	SyncStore
+}
+
+// TodoSort names a column todos can be ordered by
+type TodoSort string
+
+const (
+	SortPriority  TodoSort = "priority"
+	SortCreatedAt TodoSort = "created_at"
+	SortUpdatedAt TodoSort = "updated_at"
+	SortDueAt     TodoSort = "due_at"
+	SortTitle     TodoSort = "title"
+)
+
+// TodoQuery selects a page of the todos a user can see: their own inbox
+// and the todos of every project they own or are a member of
+type TodoQuery struct {
+	UserID int
+	// Project optionally restricts todos to one project; a project ID of 0
+	// selects the inbox
+	Project *int
+	// Assignee optionally restricts todos to those assigned to a user; a
+	// user ID of 0 selects unassigned todos
+	Assignee *int
+	// Completed optionally restricts todos to completed or open ones
+	Completed *bool
+	// Due optionally restricts todos by due date
+	Due *DueFilter
+	// Tags optionally restricts todos to those carrying any (or, with
+	// MatchAllTags, every one) of the named tags
+	Tags         []string
+	MatchAllTags bool
+	// Sort and Desc choose the order; ties are broken by ID in the same
+	// direction and todos without a due date always sort last by due_at
+	Sort TodoSort
+	Desc bool
+	// After resumes the listing after the todo the cursor was taken from
+	After *TodoCursor
+	// Limit caps the number of todos returned; zero means no limit
+	Limit int
+}
+
+// TodoChange is what a batch does to one todo. Todo is the todo as it was
+// loaded, carrying any new editable fields and tags.
+type TodoChange struct {
+	Todo models.Todo
+	// Create inserts Todo as a new todo with its tags, filling in the ID
+	// and timestamps; Completed and Next still apply to it
+	Create bool
+	// Update saves Todo's editable fields, including its assignment. Its
+	// tags are replaced unless Todo.Tags is nil and its project is applied
+	// to all of its subtasks, unassigning any subtask whose assignee cannot
+	// see the new project.
+	Update bool
+	// Completed sets the todo's completed flag
+	Completed *bool
+	// Cascade marks all of the todo's subtasks, at every level, completed
+	// along with it
+	Cascade bool
+	// Next is created as the next occurrence of a repeating todo that is
+	// being completed: the todo's rule is cleared and handed over to Next.
+	// Without a rule left to hand over no occurrence is created.
+	Next *models.Todo
+	// Delete moves the todo and its subtasks to the trash instead
+	Delete bool
+	// RevertedTo records the change in the todo's history as a revert to
+	// that revision
+	RevertedTo *int
+}
+
+// TodoChangeResult is the outcome of a TodoChange: the todo afterwards and
+// the occurrence spawned, if any, or the error that stopped it
+type TodoChangeResult struct {
+	Todo models.Todo
+	Next *models.Todo
+	Err  error
+}
+
+// SyncChange is an entry of a user's change feed. Seq is the entry's
+// number in the feed and CreatedSeq the number of the entry that first
+// showed the user the todo. A deleted entry is a tombstone: the todo was
+// deleted or the user can no longer see it, and only its ID is set.
+type SyncChange struct {
+	Todo       models.Todo
+	Seq        int
+	CreatedSeq int
+	Deleted    bool
+}
+
+// DueFilter selects todos by due date. Timed todos are matched against
+// [TimedFrom, TimedTo) and all-day todos against [DateFrom, DateTo); a zero
+// time leaves that end of the range open.
+type DueFilter struct {
+	TimedFrom time.Time
+	TimedTo   time.Time
+	DateFrom  time.Time
+	DateTo    time.Time
+	// OpenOnly excludes completed todos
+	OpenOnly bool
+}
+
+// TodoCursor is the keyset position of a todo within a sort order: its sort
+// key (nil for a NULL due date) and its ID
+type TodoCursor struct {
+	Value *string `json:"v"`
+	ID    int     `json:"id"`
+}
+
+// CursorFor returns the cursor that resumes a listing after the given todo
+func CursorFor(sort TodoSort, todo models.Todo) TodoCursor {
+	cursor := TodoCursor{ID: todo.ID}
+	var value string
+	switch sort {
+	case SortPriority:
+		value = strconv.Itoa(todo.Priority)
+	case SortCreatedAt:
+		value = formatTime(todo.CreatedAt)
+	case SortUpdatedAt:
+		value = formatTime(todo.UpdatedAt)
+	case SortDueAt:
+		if todo.DueAt == nil {
+			return cursor
+		}
+		value = formatTime(*todo.DueAt)
+	case SortTitle:
+		value = todo.Title
+	}
+	cursor.Value = &value
+	return cursor
+}
+
+// SearchTerm is a single word or quoted phrase of a search query. Words are
+// prefix-matched; phrases must match exactly.
+type SearchTerm struct {
+	Text   string
+	Phrase bool
+}
+
+// timeLayout matches the format SQLite uses for CURRENT_TIMESTAMP, so
+// values written from Go compare correctly against column defaults
+const timeLayout = "2006-01-02 15:04:05"
+
+// formatTime formats a time for storage and cursors
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
+
+// parseTime parses a time produced by formatTime
+func parseTime(s string) (time.Time, error) {
+	return time.ParseInLocation(timeLayout, s, time.UTC)
+}
@@ -0,0 +1,129 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"todo-list-app/internal/database"
+)
+
+// storeTests are run against every backend, each on an empty store
+var storeTests = []struct {
+	name string
+	run  func(t *testing.T, s Store)
+}{
+	{"TodoCRUD", testTodoCRUD},
+	{"Versions", testVersions},
+	{"Batches", testBatches},
+	{"ListTodos", testListTodos},
+	{"SyncFeed", testSyncFeed},
+	{"History", testHistory},
+	{"SubtreeHistory", testSubtreeHistory},
+	{"Tags", testTags},
+	{"Tokens", testTokens},
+	{"Users", testUsers},
+}
+
+// backends returns a constructor for an empty store of each backend.
+// Postgres is included when POSTGRES_URL names a database the tests may
+// create schemas in.
+func backends() map[string]func(t *testing.T) Store {
+	stores := map[string]func(t *testing.T) Store{
+		"memory": func(t *testing.T) Store { return NewMemoryStore() },
+		"sqlite": newSQLiteTestStore,
+	}
+	if url := os.Getenv("POSTGRES_URL"); url != "" {
+		stores["postgres"] = func(t *testing.T) Store { return newPostgresTestStore(t, url) }
+	}
+	return stores
+}
+
+// newSQLiteTestStore opens a store on a new SQLite database file
+func newSQLiteTestStore(t *testing.T) Store {
+	t.Helper()
+	db, err := database.InitDB("sqlite:" + filepath.Join(t.TempDir(), "todo.db"))
+	if err != nil && strings.Contains(err.Error(), "no such module: fts5") {
+		t.Skipf("SQLite store tests need the sqlite_fts5 build tag: %v", err)
+	} else if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return NewSQLStore(db)
+}
+
+var schemaCount int64
+
+// newPostgresTestStore opens a store on a new schema in the database at url,
+// which is dropped when the test ends
+func newPostgresTestStore(t *testing.T, url string) Store {
+	t.Helper()
+	admin, err := database.Open(url)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	schema := fmt.Sprintf("store_test_%d_%d", time.Now().UnixNano(), atomic.AddInt64(&schemaCount, 1))
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatalf("creating schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec("DROP SCHEMA " + schema + " CASCADE"); err != nil {
+			t.Errorf("dropping schema %s: %v", schema, err)
+		}
+	})
+
+	separator := "?"
+	if strings.Contains(url, "?") {
+		separator = "&"
+	}
+	db, err := database.InitDB(url + separator + "search_path=" + schema)
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return NewSQLStore(db)
+}
+
+func TestStores(t *testing.T) {
+	for name, open := range backends() {
+		t.Run(name, func(t *testing.T) {
+			for _, tt := range storeTests {
+				t.Run(tt.name, func(t *testing.T) { tt.run(t, open(t)) })
+			}
+		})
+	}
+}
+
+// mustCreateUser creates a user or fails the test
+func mustCreateUser(t *testing.T, s Store, email string) int {
+	t.Helper()
+	user, err := s.CreateUser(email, "hash")
+	if err != nil {
+		t.Fatalf("CreateUser(%q): %v", email, err)
+	}
+	return user.ID
+}
+
+func testUsers(t *testing.T, s Store) {
+	id := mustCreateUser(t, s, "user@example.com")
+	if _, err := s.CreateUser("user@example.com", "other"); err != ErrConflict {
+		t.Errorf("duplicate email: err = %v, want ErrConflict", err)
+	}
+
+	if err := s.UpdatePassword(id, "new hash"); err != nil {
+		t.Fatalf("UpdatePassword: %v", err)
+	}
+	user, err := s.GetUserByEmail("user@example.com")
+	if err != nil || user.ID != id || user.Password != "new hash" {
+		t.Errorf("GetUserByEmail = %+v, %v", user, err)
+	}
+	if _, err := s.GetUserByID(id + 100); err != ErrNotFound {
+		t.Errorf("unknown user: err = %v, want ErrNotFound", err)
+	}
+}
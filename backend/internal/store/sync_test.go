@@ -0,0 +1,61 @@
+package store
+
+import (
+	"testing"
+
+	"todo-list-app/internal/models"
+)
+
+// feedOf lists a user's change feed after since or fails the test
+func feedOf(t *testing.T, s Store, userID, since int) []SyncChange {
+	t.Helper()
+	changes, err := s.ListSyncChanges(userID, since, 100)
+	if err != nil {
+		t.Fatalf("ListSyncChanges(%d, %d): %v", userID, since, err)
+	}
+	return changes
+}
+
+func testSyncFeed(t *testing.T, s Store) {
+	owner := mustCreateUser(t, s, "owner@example.com")
+	other := mustCreateUser(t, s, "other@example.com")
+
+	parent := mustCreateTodo(t, s, models.Todo{UserID: owner, Title: "Trip"})
+	child := mustCreateTodo(t, s, models.Todo{UserID: owner, ParentID: &parent.ID, Title: "Book hotel"})
+	mustCreateTodo(t, s, models.Todo{UserID: other, Title: "Not mine"})
+
+	initial := feedOf(t, s, owner, 0)
+	if len(initial) != 2 {
+		t.Fatalf("initial feed = %+v", initial)
+	}
+	since := initial[len(initial)-1].Seq
+
+	// Each todo appears once, at its latest change
+	child.Title = "Book a hotel"
+	child = mustApply(t, s, owner, TodoChange{Todo: child, Update: true}).Todo
+	child.Priority = 2
+	mustApply(t, s, owner, TodoChange{Todo: child, Update: true})
+	changes := feedOf(t, s, owner, since)
+	if len(changes) != 2 || changes[len(changes)-1].Todo.ID != child.ID || changes[len(changes)-1].Todo.Priority != 2 {
+		t.Fatalf("feed after edits = %+v", changes)
+	}
+	since = changes[len(changes)-1].Seq
+
+	// Deleting a todo leaves tombstones for its whole subtree
+	parent, _ = s.GetTodo(parent.ID)
+	mustApply(t, s, owner, TodoChange{Todo: parent, Delete: true})
+	deleted := map[int]bool{}
+	for _, change := range feedOf(t, s, owner, since) {
+		if !change.Deleted {
+			t.Errorf("live entry after delete: %+v", change)
+		}
+		deleted[change.Todo.ID] = true
+	}
+	if len(deleted) != 2 || !deleted[parent.ID] || !deleted[child.ID] {
+		t.Errorf("tombstones = %v", deleted)
+	}
+
+	if changes := feedOf(t, s, other, 0); len(changes) != 1 {
+		t.Errorf("other user's feed = %+v", changes)
+	}
+}
@@ -0,0 +1,54 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+
+	"todo-list-app/internal/models"
+)
+
+func testTags(t *testing.T, s Store) {
+	owner := mustCreateUser(t, s, "owner@example.com")
+	todo := mustCreateTodo(t, s, models.Todo{UserID: owner, Title: "Buy milk", Tags: []string{"Home", "errands"}})
+
+	if _, err := s.CreateTag(owner, "home"); err != ErrConflict {
+		t.Errorf("duplicate tag: err = %v, want ErrConflict", err)
+	}
+	tags, err := s.ListTags(owner)
+	if err != nil || len(tags) != 2 {
+		t.Fatalf("ListTags = %+v, %v", tags, err)
+	}
+	errands, home := tags[0], tags[1]
+	if errands.Name != "errands" || errands.TodoCount != 1 {
+		t.Errorf("first tag = %+v", errands)
+	}
+
+	if _, err := s.RenameTag(errands.ID, owner, "HOME"); err != ErrConflict {
+		t.Errorf("rename to a taken name: err = %v, want ErrConflict", err)
+	}
+	if _, err := s.RenameTag(errands.ID, owner, "Shopping"); err != nil {
+		t.Fatalf("RenameTag: %v", err)
+	}
+	if got, _ := s.GetTodo(todo.ID); fmt.Sprint(got.Tags) != "[Home Shopping]" {
+		t.Errorf("tags after rename = %v", got.Tags)
+	}
+
+	merged, err := s.MergeTags(errands.ID, home.ID, owner)
+	if err != nil || merged.TodoCount != 1 {
+		t.Fatalf("MergeTags = %+v, %v", merged, err)
+	}
+	if err := s.DeleteTag(home.ID, owner); err != nil {
+		t.Fatalf("DeleteTag: %v", err)
+	}
+	if got, _ := s.GetTodo(todo.ID); len(got.Tags) != 0 {
+		t.Errorf("tags after delete = %v", got.Tags)
+	}
+	if _, err := s.GetTag(home.ID, owner); err != ErrNotFound {
+		t.Errorf("GetTag after delete: err = %v, want ErrNotFound", err)
+	}
+
+	// Renaming, merging and deleting each changed the todo's tags
+	if got := historyOf(t, s, todo.ID); got != "[create update update update]" {
+		t.Errorf("history = %s", got)
+	}
+}
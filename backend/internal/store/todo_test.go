@@ -0,0 +1,183 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+
+	"todo-list-app/internal/models"
+)
+
+// mustCreateTodo creates a todo as its owner or fails the test
+func mustCreateTodo(t *testing.T, s Store, todo models.Todo) models.Todo {
+	t.Helper()
+	if todo.Priority == 0 {
+		todo.Priority = 1
+	}
+	return mustApply(t, s, todo.UserID, TodoChange{Todo: todo, Create: true}).Todo
+}
+
+// applyChange applies a batch of one change and returns its result
+func applyChange(s Store, actorID int, change TodoChange) (TodoChangeResult, error) {
+	results, err := s.ApplyTodoChanges(actorID, []TodoChange{change}, true)
+	if err != nil {
+		return TodoChangeResult{}, err
+	}
+	return results[0], results[0].Err
+}
+
+// mustApply applies a change or fails the test
+func mustApply(t *testing.T, s Store, actorID int, change TodoChange) TodoChangeResult {
+	t.Helper()
+	result, err := applyChange(s, actorID, change)
+	if err != nil {
+		t.Fatalf("applying %+v: %v", change, err)
+	}
+	return result
+}
+
+func testTodoCRUD(t *testing.T, s Store) {
+	owner := mustCreateUser(t, s, "owner@example.com")
+	other := mustCreateUser(t, s, "other@example.com")
+
+	todo := mustCreateTodo(t, s, models.Todo{UserID: owner, Title: "Buy milk", Tags: []string{"Home", "errands"}})
+	if todo.ID == 0 || todo.CreatedAt.IsZero() {
+		t.Fatalf("created todo = %+v", todo)
+	}
+
+	got, err := s.GetTodo(todo.ID)
+	if err != nil {
+		t.Fatalf("GetTodo: %v", err)
+	}
+	if got.Title != "Buy milk" || got.Completed || fmt.Sprint(got.Tags) != "[errands Home]" {
+		t.Errorf("GetTodo = %+v", got)
+	}
+
+	// nil tags leave them alone, an empty list removes them
+	got.Title, got.Priority, got.Tags = "Buy oat milk", 3, nil
+	got = mustApply(t, s, owner, TodoChange{Todo: got, Update: true}).Todo
+	if got.Title != "Buy oat milk" || got.Priority != 3 || len(got.Tags) != 2 {
+		t.Errorf("after update = %+v", got)
+	}
+	got.Tags = []string{}
+	got = mustApply(t, s, owner, TodoChange{Todo: got, Update: true}).Todo
+	if len(got.Tags) != 0 {
+		t.Errorf("tags after clearing = %v", got.Tags)
+	}
+
+	completed := true
+	if got = mustApply(t, s, owner, TodoChange{Todo: got, Completed: &completed}).Todo; !got.Completed {
+		t.Errorf("after completing = %+v", got)
+	}
+
+	deleted := got
+	deleted.UserID = other
+	if _, err := applyChange(s, other, TodoChange{Todo: deleted, Delete: true}); err != ErrNotFound {
+		t.Errorf("delete by another user: err = %v, want ErrNotFound", err)
+	}
+	mustApply(t, s, owner, TodoChange{Todo: got, Delete: true})
+	if _, err := s.GetTodo(todo.ID); err != ErrNotFound {
+		t.Errorf("GetTodo after delete: err = %v, want ErrNotFound", err)
+	}
+	if _, err := applyChange(s, owner, TodoChange{Todo: got, Update: true}); err != ErrNotFound {
+		t.Errorf("update in the trash: err = %v, want ErrNotFound", err)
+	}
+}
+
+func testVersions(t *testing.T, s Store) {
+	owner := mustCreateUser(t, s, "owner@example.com")
+
+	parent := mustCreateTodo(t, s, models.Todo{UserID: owner, Title: "Trip"})
+	child := mustCreateTodo(t, s, models.Todo{UserID: owner, ParentID: &parent.ID, Title: "Book hotel"})
+	if parent.Version != 1 || child.Version != 1 {
+		t.Fatalf("new versions = %d, %d, want 1", parent.Version, child.Version)
+	}
+
+	// Changes to a todo's subtasks and comments show in its version
+	completed := true
+	child = mustApply(t, s, owner, TodoChange{Todo: child, Completed: &completed}).Todo
+	if err := s.CreateComment(&models.Comment{TodoID: parent.ID, UserID: owner, Body: "Book early"}); err != nil {
+		t.Fatalf("CreateComment: %v", err)
+	}
+	parent, err := s.GetTodo(parent.ID)
+	if err != nil {
+		t.Fatalf("GetTodo: %v", err)
+	}
+	if parent.Version != 4 {
+		t.Errorf("version after a new subtask, its completion and a comment = %d, want 4", parent.Version)
+	}
+
+	stale := parent
+	parent.Title = "Trip to Busan"
+	parent = mustApply(t, s, owner, TodoChange{Todo: parent, Update: true}).Todo
+	if parent.Version != 5 {
+		t.Errorf("version after update = %d, want 5", parent.Version)
+	}
+
+	// Every change made against the old version is refused
+	for name, change := range map[string]TodoChange{
+		"update":   {Todo: stale, Update: true},
+		"complete": {Todo: stale, Completed: &completed},
+		"cascade":  {Todo: stale, Completed: &completed, Cascade: true},
+		"delete":   {Todo: stale, Delete: true},
+	} {
+		if _, err := applyChange(s, owner, change); err != ErrConflict {
+			t.Errorf("stale %s: err = %v, want ErrConflict", name, err)
+		}
+	}
+
+	// Deleting the parent also changes its subtask
+	mustApply(t, s, owner, TodoChange{Todo: parent, Delete: true})
+	trashed, err := s.GetTrashedTodo(child.ID)
+	if err != nil {
+		t.Fatalf("GetTrashedTodo: %v", err)
+	}
+	if trashed.Version != 3 {
+		t.Errorf("subtask version after delete = %d, want 3", trashed.Version)
+	}
+}
+
+func testListTodos(t *testing.T, s Store) {
+	owner := mustCreateUser(t, s, "owner@example.com")
+	other := mustCreateUser(t, s, "other@example.com")
+
+	mustCreateTodo(t, s, models.Todo{UserID: owner, Title: "b", Priority: 2, Tags: []string{"home"}})
+	mustCreateTodo(t, s, models.Todo{UserID: owner, Title: "c", Priority: 1, Tags: []string{"home", "work"}})
+	mustCreateTodo(t, s, models.Todo{UserID: owner, Title: "a", Priority: 3})
+	mustCreateTodo(t, s, models.Todo{UserID: other, Title: "not mine"})
+
+	titles := func(q TodoQuery) string {
+		t.Helper()
+		todos, err := s.ListTodos(q)
+		if err != nil {
+			t.Fatalf("ListTodos(%+v): %v", q, err)
+		}
+		var titles []string
+		for _, todo := range todos {
+			titles = append(titles, todo.Title)
+		}
+		return fmt.Sprint(titles)
+	}
+
+	if got := titles(TodoQuery{UserID: owner, Sort: SortTitle}); got != "[a b c]" {
+		t.Errorf("by title = %s", got)
+	}
+	if got := titles(TodoQuery{UserID: owner, Sort: SortPriority, Desc: true}); got != "[a b c]" {
+		t.Errorf("by priority, descending = %s", got)
+	}
+	if got := titles(TodoQuery{UserID: owner, Sort: SortTitle, Tags: []string{"HOME"}}); got != "[b c]" {
+		t.Errorf("tagged home = %s", got)
+	}
+	if got := titles(TodoQuery{UserID: owner, Sort: SortTitle, Tags: []string{"home", "work"}, MatchAllTags: true}); got != "[c]" {
+		t.Errorf("tagged home and work = %s", got)
+	}
+
+	// Pages resume after the cursor of the last todo
+	first, err := s.ListTodos(TodoQuery{UserID: owner, Sort: SortTitle, Limit: 2})
+	if err != nil || len(first) != 2 {
+		t.Fatalf("first page = %+v, %v", first, err)
+	}
+	cursor := CursorFor(SortTitle, first[1])
+	if got := titles(TodoQuery{UserID: owner, Sort: SortTitle, After: &cursor}); got != "[c]" {
+		t.Errorf("second page = %s", got)
+	}
+}
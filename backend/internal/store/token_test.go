@@ -0,0 +1,49 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"todo-list-app/internal/models"
+)
+
+func testTokens(t *testing.T, s Store) {
+	owner := mustCreateUser(t, s, "owner@example.com")
+	other := mustCreateUser(t, s, "other@example.com")
+
+	token := models.APIToken{UserID: owner, Name: "cli", Prefix: "tl_abcd", Scopes: []string{"todos:read", "tags:write"}}
+	if err := s.CreateToken(&token, "hash-1"); err != nil {
+		t.Fatalf("CreateToken: %v", err)
+	}
+	if token.ID == 0 || token.CreatedAt.IsZero() {
+		t.Fatalf("created token = %+v", token)
+	}
+
+	got, err := s.GetTokenByHash("hash-1")
+	if err != nil || got.ID != token.ID || fmt.Sprint(got.Scopes) != "[todos:read tags:write]" || got.LastUsedAt != nil {
+		t.Errorf("GetTokenByHash = %+v, %v", got, err)
+	}
+	if _, err := s.GetTokenByHash("hash-2"); err != ErrNotFound {
+		t.Errorf("unknown hash: err = %v, want ErrNotFound", err)
+	}
+
+	usedAt := time.Date(2030, time.January, 1, 9, 0, 0, 0, time.UTC)
+	if err := s.TouchToken(token.ID, usedAt); err != nil {
+		t.Fatalf("TouchToken: %v", err)
+	}
+	tokens, err := s.ListTokens(owner)
+	if err != nil || len(tokens) != 1 || tokens[0].LastUsedAt == nil || !tokens[0].LastUsedAt.Equal(usedAt) {
+		t.Errorf("ListTokens = %+v, %v", tokens, err)
+	}
+
+	if err := s.DeleteToken(token.ID, other); err != ErrNotFound {
+		t.Errorf("delete by another user: err = %v, want ErrNotFound", err)
+	}
+	if err := s.DeleteToken(token.ID, owner); err != nil {
+		t.Fatalf("DeleteToken: %v", err)
+	}
+	if _, err := s.GetTokenByHash("hash-1"); err != ErrNotFound {
+		t.Errorf("revoked token: err = %v, want ErrNotFound", err)
+	}
+}
@@ -13,16 +13,27 @@ const useTodos = () => {
       setIsLoading(true)
       setError(null)
 
-      const response = await fetch(`${API_BASE_URL}/todos`, {
-        credentials: 'include',
-      })
-
-      if (!response.ok) {
-        throw new Error('Failed to fetch todos')
-      }
-
-      const data = await response.json()
-      setTodos(data || [])
+      // The list is paginated; follow next_cursor until every page is loaded
+      const allTodos = []
+      let cursor = null
+      do {
+        const params = new URLSearchParams({ limit: '200' })
+        if (cursor) params.set('cursor', cursor)
+
+        const response = await fetch(`${API_BASE_URL}/todos?${params}`, {
+          credentials: 'include',
+        })
+
+        if (!response.ok) {
+          throw new Error('Failed to fetch todos')
+        }
+
+        const data = await response.json()
+        allTodos.push(...(data.todos || []))
+        cursor = data.next_cursor
+      } while (cursor)
+
+      setTodos(allTodos)
     } catch (error) {
       setError(error.message)
       console.error('Failed to fetch todos:', error)
@@ -59,16 +70,19 @@ const useTodos = () => {
     }
   }
 
-  // Update an existing todo
+  // Update an existing todo; only the fields in todoData change. The edit
+  // is refused if someone else changed the todo since it was loaded.
   const updateTodo = async (id, todoData) => {
     try {
       setError(null)
 
+      const headers = { 'Content-Type': 'application/merge-patch+json' }
+      const current = todos.find(todo => todo.id === id)
+      if (current?.version) headers['If-Match'] = `"${current.version}"`
+
       const response = await fetch(`${API_BASE_URL}/todos/${id}`, {
-        method: 'PUT',
-        headers: {
-          'Content-Type': 'application/json',
-        },
+        method: 'PATCH',
+        headers,
         credentials: 'include',
         body: JSON.stringify(todoData),
       })
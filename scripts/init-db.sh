@@ -13,22 +13,25 @@ echo "Initializing database at $DB_PATH"
 # Create data directory if it doesn't exist
 mkdir -p "$DB_DIR"
 
+# Migrations run from the backend directory, so resolve the path first
+DB_PATH="$(cd "$DB_DIR" && pwd)/$(basename "$DB_PATH")"
+
 # Remove existing database for fresh start (development only)
 if [ "$RESET_DB" = "true" ]; then
     echo "Resetting database..."
     rm -f "$DB_PATH"
 fi
 
-# Run migrations
-echo "Running migrations..."
-sqlite3 "$DB_PATH" < backend/internal/database/migrations.sql
-
-# Seed test data only in development
+# Run migrations, seeding test data only in development
+INIT_FLAGS=""
 if [ "$SEED_DATA" = "true" ]; then
     echo "Seeding test data..."
-    sqlite3 "$DB_PATH" < backend/internal/database/seed.sql
+    INIT_FLAGS="-seed"
 fi
 
+echo "Running migrations..."
+(cd backend && go run -tags sqlite_fts5 ./cmd init-db -db "$DB_PATH" $INIT_FLAGS)
+
 echo "Database initialization complete!"
 echo "Database location: $DB_PATH"
 